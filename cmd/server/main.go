@@ -3,17 +3,22 @@ package main
 import (
 	"context"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"wallet-service/internal/cache"
 	"wallet-service/internal/config"
 	"wallet-service/internal/database"
+	"wallet-service/internal/events"
 	"wallet-service/internal/handler"
+	"wallet-service/internal/metrics"
 	"wallet-service/internal/repository"
 	"wallet-service/internal/service"
+	"wallet-service/internal/tenant"
 )
 
 func main() {
@@ -22,26 +27,181 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	db, err := database.Connect(cfg)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: cfg.SlogLevel()})))
+
+	cluster, err := database.Connect(cfg)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	defer db.Close()
+	defer cluster.Close()
+	// Кошельки, лимиты, корректировки и события работают с пулом pgx напрямую,
+	// остальные репозитории - через database/sql поверх того же пула
+	db := cluster.DB
+	metrics.PublishPools(func() any { return cluster.Stats() })
 
 	if err := database.RunMigrations(db); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
-	walletRepo := repository.NewWalletRepository(db)
-	walletService := service.NewWalletService(walletRepo, 3) // 3 retry attempts
-	router := handler.NewRouter(walletService)
+	expectedMigration, err := database.LatestMigrationVersion()
+	if err != nil {
+		log.Fatalf("Failed to read migrations: %v", err)
+	}
+
+	lockStrategy, err := repository.ParseLockStrategy(cfg.Database.LockStrategy)
+	if err != nil {
+		log.Fatalf("Invalid lock strategy: %v", err)
+	}
+	log.Printf("Using %s lock strategy", lockStrategy.Name())
+
+	tenants := tenant.NewRegistry(cfg.TenantModels()...)
+	if !tenants.KeysRequired() {
+		log.Println("No tenant API keys are configured, all requests use the default tenant")
+	}
+	if err := repository.EnsureFeeWallets(context.Background(), cluster.Primary, tenants.All()); err != nil {
+		log.Fatalf("Invalid fee wallet configuration: %v", err)
+	}
+	walletRepo := repository.NewWalletRepository(cluster.Primary, lockStrategy, tenants)
+	// Потоки и ожидание изменений перечитывают кошелек сразу после события,
+	// поэтому читают с основного сервера: реплика могла еще не получить изменение
+	eventWallets := walletRepo
+	if len(cluster.Replicas) > 0 {
+		walletRepo = repository.NewReplicaWalletRepository(walletRepo, cluster, lockStrategy, tenants)
+		log.Printf("Reading balances from %d replica(s), consistency token wait %v", len(cluster.Replicas), cfg.Database.ReplicaWait)
+	}
+	// Hub есть всегда: без слушателя он просто пуст
+	eventsHub := events.NewHub()
+	if cfg.Cache.Enabled {
+		walletCache := cache.NewWalletCache(cfg.Cache.Size, cfg.Cache.TTL)
+		eventsHub.Observe(walletCache)
+		walletRepo = repository.NewCachedWalletRepository(walletRepo, walletCache)
+		log.Printf("Wallet cache enabled: %d wallets, TTL %v", cfg.Cache.Size, cfg.Cache.TTL)
+	}
+	retryPolicy := service.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = cfg.Retry.MaxAttempts
+	retryPolicy.BaseDelay = cfg.Retry.BaseDelay
+	retryPolicy.MaxDelay = cfg.Retry.MaxDelay
+
+	walletService := service.NewWalletService(walletRepo, retryPolicy, tenants)
+	healthHandler := handler.NewHealthHandler(cluster, expectedMigration)
+
+	ledgerService := service.NewLedgerService(repository.NewLedgerRepository(db))
+
+	// Интерфейс остается nil при выключенном аудите - планировщик тогда его не пишет
+	var auditRecorder service.AuditRecorder
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
+	if cfg.Audit.Enabled {
+		auditRecorder = auditService
+	} else {
+		log.Println("Audit log is disabled")
+	}
+
+	// Ключ уже проверен при загрузке конфигурации
+	signingKey, err := cfg.Chain.PrivateKey()
+	if err != nil {
+		log.Fatalf("Invalid chain signing key: %v", err)
+	}
+	chainService := service.NewChainService(repository.NewChainRepository(db), signingKey)
+
+	routerOpts := handler.RouterOptions{Metrics: cfg.Features.Metrics}
+	routerOpts.Tenants = handler.NewTenantHandler(tenants)
+	if len(cluster.Replicas) > 0 {
+		routerOpts.Consistency = handler.NewConsistencyHandler(cluster)
+	}
+	routerOpts.History = handler.NewHistoryHandler(ledgerService)
+	if cfg.Audit.Enabled {
+		routerOpts.Audit = handler.NewAuditHandler(auditService, cfg.Audit.TrustProxy)
+	}
+	if cfg.Admin.Enabled() {
+		// Операторы уже проверены при загрузке конфигурации
+		operators, err := cfg.Admin.TokenHashes()
+		if err != nil {
+			log.Fatalf("Invalid admin operators: %v", err)
+		}
+		limitsService := service.NewLimitsService(repository.NewLimitsRepository(cluster.Primary), tenants)
+		adjustmentService := service.NewAdjustmentService(repository.NewAdjustmentRepository(cluster.Primary, lockStrategy, tenants), retryPolicy)
+		routerOpts.Admin = handler.NewAdminHandler(operators, limitsService, ledgerService, adjustmentService)
+		routerOpts.Chain = handler.NewChainHandler(chainService)
+	} else {
+		log.Println("Neither ADMIN_TOKEN nor admin operators are set, admin API is disabled")
+	}
+
+	scheduleService := service.NewScheduleService(repository.NewScheduleRepository(db), walletService, auditRecorder)
+	routerOpts.Schedules = handler.NewScheduleHandler(scheduleService)
+
+	if cfg.Events.Enabled {
+		eventService := service.NewEventService(eventWallets, repository.NewEventRepository(cluster.Primary), eventsHub)
+		routerOpts.Events = handler.NewEventsHandler(eventService, cfg.Events.HeartbeatInterval)
+	}
+
+	router := handler.NewRouter(walletService, healthHandler, routerOpts)
+
+	// Реплики забирают задания через SKIP LOCKED, поэтому планировщик можно
+	// включать на любом числе экземпляров
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	schedulerDone := make(chan struct{})
+	if cfg.Scheduler.Enabled {
+		go func() {
+			defer close(schedulerDone)
+			log.Printf("Scheduler started, polling every %v", cfg.Scheduler.Interval)
+			scheduleService.Run(schedulerCtx, cfg.Scheduler.Interval, cfg.Scheduler.BatchSize)
+		}()
+	} else {
+		close(schedulerDone)
+		log.Println("Scheduler is disabled on this instance")
+	}
+
+	// Снимки снимает одна реплика за раз (advisory-блокировка), остальные пропускают запуск
+	snapshotsCtx, stopSnapshots := context.WithCancel(context.Background())
+	snapshotsDone := make(chan struct{})
+	if cfg.Snapshots.Enabled {
+		go func() {
+			defer close(snapshotsDone)
+			log.Printf("Balance snapshots started, every %v with lag %v", cfg.Snapshots.Interval, cfg.Snapshots.Lag)
+			ledgerService.RunSnapshots(snapshotsCtx, cfg.Snapshots.Interval, cfg.Snapshots.Lag)
+		}()
+	} else {
+		close(snapshotsDone)
+		log.Println("Balance snapshots are disabled on this instance")
+	}
+
+	// Точки создает одна реплика за раз (advisory-блокировка)
+	checkpointsCtx, stopCheckpoints := context.WithCancel(context.Background())
+	checkpointsDone := make(chan struct{})
+	if signingKey != nil {
+		go func() {
+			defer close(checkpointsDone)
+			log.Printf("Chain checkpoints started, every %v", cfg.Chain.CheckpointInterval)
+			chainService.RunCheckpoints(checkpointsCtx, cfg.Chain.CheckpointInterval)
+		}()
+	} else {
+		close(checkpointsDone)
+		log.Println("CHAIN_SIGNING_KEY is not set, chain checkpoints are disabled")
+	}
+
+	// NOTIFY доходит до всех реплик, поэтому события слушает каждая, где включены
+	// потоки или кеш
+	eventsCtx, stopEvents := context.WithCancel(context.Background())
+	eventsDone := make(chan struct{})
+	if cfg.Events.Enabled || cfg.Cache.Enabled {
+		go func() {
+			defer close(eventsDone)
+			log.Println("Listening for balance events")
+			if err := events.Listen(eventsCtx, cfg.Database.ConnectionString(), eventsHub); err != nil {
+				log.Printf("Balance events stopped: %v", err)
+			}
+		}()
+	} else {
+		close(eventsDone)
+		log.Println("Balance events and wallet cache are disabled on this instance")
+	}
 
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
 		Handler:      router,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
 	}
 
 	go func() {
@@ -55,9 +215,27 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
+	// Сначала снимаем готовность и даем балансировщику время увести трафик
+	log.Printf("Draining traffic for %v...", cfg.HTTP.ShutdownDrainDelay)
+	healthHandler.SetShuttingDown()
+	time.Sleep(cfg.HTTP.ShutdownDrainDelay)
+
 	log.Println("Shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Незавершенная пачка откатится, задания подхватит другая реплика или следующий запуск
+	stopScheduler()
+	<-schedulerDone
+	stopSnapshots()
+	<-snapshotsDone
+	stopCheckpoints()
+	<-checkpointsDone
+	// Потоки событий не завершаются сами - закрываем их, иначе Shutdown ждал бы таймаута.
+	// Клиенты переподключатся к другой реплике с последней версией
+	stopEvents()
+	<-eventsDone
+	eventsHub.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
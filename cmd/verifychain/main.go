@@ -0,0 +1,128 @@
+// verifychain проверяет цепочки хешей операций и подписи контрольных точек
+// прямо по БД. Подключение берется из той же конфигурации, что у сервиса
+// (CONFIG_FILE и переменные окружения). Код выхода 1 - найдено нарушение
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"wallet-service/internal/config"
+	"wallet-service/internal/database"
+	"wallet-service/internal/repository"
+	"wallet-service/internal/service"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+)
+
+func main() {
+	walletFlag := flag.String("wallet", "", "проверить только этот кошелек; по умолчанию - все цепочки")
+	tenantFlag := flag.String("tenant", "", "проверить только цепочки этого тенанта; по умолчанию - всех тенантов из конфигурации")
+	publicKeyFlag := flag.String("public-key", "", "Ed25519-ключ проверки подписей в base64; по умолчанию выводится из CHAIN_SIGNING_KEY")
+	flag.Parse()
+
+	cfg, err := config.LoadArgs(nil)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	cluster, err := database.Connect(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer cluster.Close()
+	chainService := service.NewChainService(repository.NewChainRepository(cluster.DB), nil)
+
+	// Цепочки видны только своему тенанту, поэтому обходим тенантов по очереди.
+	// Кошелек из -wallet ищется у тенанта из -tenant или у тенанта по умолчанию
+	var tenants []string
+	switch {
+	case *tenantFlag != "":
+		tenants = []string{*tenantFlag}
+	case *walletFlag != "":
+		tenants = []string{tenant.DefaultID}
+	default:
+		for _, t := range cfg.TenantModels() {
+			tenants = append(tenants, t.ID)
+		}
+	}
+
+	ok := true
+	for _, tenantID := range tenants {
+		ctx := tenant.NewContext(context.Background(), tenantID)
+
+		var wallets []uuid.UUID
+		if *walletFlag != "" {
+			id, err := uuid.Parse(*walletFlag)
+			if err != nil {
+				log.Fatalf("Invalid -wallet: %v", err)
+			}
+			wallets = []uuid.UUID{id}
+		} else if wallets, err = chainService.Wallets(ctx); err != nil {
+			log.Fatalf("Failed to list chains of tenant %s: %v", tenantID, err)
+		}
+
+		for _, id := range wallets {
+			result, err := chainService.Verify(ctx, id)
+			if err != nil {
+				log.Fatalf("Failed to verify wallet %s: %v", id, err)
+			}
+			if result.Valid {
+				fmt.Printf("OK     %s/%s: %d links\n", tenantID, id, result.Checked)
+				continue
+			}
+			ok = false
+			b := result.BrokenAt
+			operation := "-"
+			if b.OperationID != nil {
+				operation = b.OperationID.String()
+			}
+			fmt.Printf("BROKEN %s/%s: seq %d, operation %s: %s\n", tenantID, id, b.Seq, operation, b.Reason)
+		}
+	}
+
+	publicKey, err := verificationKey(cfg, *publicKeyFlag)
+	if err != nil {
+		log.Fatalf("Invalid public key: %v", err)
+	}
+	if publicKey == nil {
+		fmt.Println("SKIP   checkpoints: no public key, pass -public-key or set CHAIN_SIGNING_KEY")
+	} else {
+		checked, err := chainService.VerifyCheckpoints(context.Background(), publicKey)
+		if err != nil {
+			ok = false
+			fmt.Printf("BROKEN checkpoints: %v (%d valid before it)\n", err, checked)
+		} else {
+			fmt.Printf("OK     checkpoints: %d\n", checked)
+		}
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// verificationKey - ключ из -public-key или выведенный из ключа подписи; nil, если нет ни того, ни другого
+func verificationKey(cfg *config.Config, flagValue string) (ed25519.PublicKey, error) {
+	if flagValue != "" {
+		key, err := base64.StdEncoding.DecodeString(flagValue)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("must be %d bytes, got %d", ed25519.PublicKeySize, len(key))
+		}
+		return key, nil
+	}
+
+	signingKey, err := cfg.Chain.PrivateKey()
+	if err != nil || signingKey == nil {
+		return nil, err
+	}
+	return signingKey.Public().(ed25519.PublicKey), nil
+}
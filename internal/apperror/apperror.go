@@ -0,0 +1,152 @@
+// Package apperror - доменные ошибки с машиночитаемыми кодами, общие для
+// repository, service и handler. Описание кодов - docs/errors.md
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type Code string
+
+const (
+	CodeValidationFailed     Code = "VALIDATION_FAILED"
+	CodeMalformedRequest     Code = "MALFORMED_REQUEST"
+	CodeMethodNotAllowed     Code = "METHOD_NOT_ALLOWED"
+	CodeWalletNotFound       Code = "WALLET_NOT_FOUND"
+	CodeInsufficientFunds    Code = "INSUFFICIENT_FUNDS"
+	CodeConcurrencyConflict  Code = "CONCURRENCY_CONFLICT"
+	CodePreconditionFailed   Code = "PRECONDITION_FAILED"
+	CodeIdempotencyKeyReused Code = "IDEMPOTENCY_KEY_REUSED"
+	CodeOperationNotFound    Code = "OPERATION_NOT_FOUND"
+	CodeAlreadyReversed      Code = "OPERATION_ALREADY_REVERSED"
+	CodeReversalExceeded     Code = "REVERSAL_AMOUNT_EXCEEDED"
+	CodeReversalNotAllowed   Code = "REVERSAL_NOT_ALLOWED"
+	CodeLimitExceeded        Code = "LIMIT_EXCEEDED"
+	CodeUnauthorized         Code = "UNAUTHORIZED"
+	CodeCreditLimitBelowDebt Code = "CREDIT_LIMIT_BELOW_DEBT"
+	CodeScheduleNotFound     Code = "SCHEDULED_OPERATION_NOT_FOUND"
+	CodeScheduleNotActive    Code = "SCHEDULED_OPERATION_NOT_ACTIVE"
+	CodeEntryNotFound        Code = "JOURNAL_ENTRY_NOT_FOUND"
+	CodeAdjustmentNotFound   Code = "ADJUSTMENT_NOT_FOUND"
+	CodeAdjustmentNotPending Code = "ADJUSTMENT_NOT_PENDING"
+	CodeSelfApproval         Code = "SELF_APPROVAL_FORBIDDEN"
+	CodeCheckpointNotFound   Code = "CHECKPOINT_NOT_FOUND"
+	CodeCurrencyMismatch     Code = "CURRENCY_MISMATCH"
+	CodeWalletFrozen         Code = "WALLET_FROZEN"
+	CodeFeeWalletInvalid     Code = "FEE_WALLET_MISCONFIGURED"
+	CodeInternal             Code = "INTERNAL_ERROR"
+)
+
+// Базовые ошибки. Сравнивать через errors.Is - совпадение определяется кодом,
+// поэтому ошибка с деталями или причиной все равно соответствует базовой
+var (
+	ErrValidationFailed     = New(CodeValidationFailed, http.StatusBadRequest, "Validation failed")
+	ErrMalformedRequest     = New(CodeMalformedRequest, http.StatusBadRequest, "Malformed request")
+	ErrMethodNotAllowed     = New(CodeMethodNotAllowed, http.StatusMethodNotAllowed, "Method not allowed")
+	ErrWalletNotFound       = New(CodeWalletNotFound, http.StatusNotFound, "Wallet not found")
+	ErrInsufficientFunds    = New(CodeInsufficientFunds, http.StatusBadRequest, "Insufficient funds")
+	ErrConcurrencyConflict  = New(CodeConcurrencyConflict, http.StatusConflict, "Operation conflict, please retry")
+	ErrPreconditionFailed   = New(CodePreconditionFailed, http.StatusPreconditionFailed, "Wallet has been modified")
+	ErrIdempotencyKeyReused = New(CodeIdempotencyKeyReused, http.StatusUnprocessableEntity, "Idempotency key was used for a different request")
+	ErrOperationNotFound    = New(CodeOperationNotFound, http.StatusNotFound, "Operation not found")
+	ErrAlreadyReversed      = New(CodeAlreadyReversed, http.StatusConflict, "Operation has already been fully reversed")
+	ErrReversalExceeded     = New(CodeReversalExceeded, http.StatusUnprocessableEntity, "Reversal amount exceeds the amount left to reverse")
+	ErrReversalNotAllowed   = New(CodeReversalNotAllowed, http.StatusUnprocessableEntity, "Operation cannot be reversed")
+	ErrLimitExceeded        = New(CodeLimitExceeded, http.StatusUnprocessableEntity, "Wallet limit exceeded")
+	ErrUnauthorized         = New(CodeUnauthorized, http.StatusUnauthorized, "Authentication required")
+	ErrCreditLimitBelowDebt = New(CodeCreditLimitBelowDebt, http.StatusUnprocessableEntity, "Credit limit is less than the current debt")
+	ErrScheduleNotFound     = New(CodeScheduleNotFound, http.StatusNotFound, "Scheduled operation not found")
+	ErrScheduleNotActive    = New(CodeScheduleNotActive, http.StatusConflict, "Scheduled operation is no longer active")
+	ErrEntryNotFound        = New(CodeEntryNotFound, http.StatusNotFound, "Journal entry not found")
+	ErrAdjustmentNotFound   = New(CodeAdjustmentNotFound, http.StatusNotFound, "Adjustment not found")
+	ErrAdjustmentNotPending = New(CodeAdjustmentNotPending, http.StatusConflict, "Adjustment has already been reviewed")
+	ErrSelfApproval         = New(CodeSelfApproval, http.StatusForbidden, "Adjustment must be approved by another operator")
+	ErrCheckpointNotFound   = New(CodeCheckpointNotFound, http.StatusNotFound, "No chain checkpoint has been published yet")
+	ErrCurrencyMismatch     = New(CodeCurrencyMismatch, http.StatusUnprocessableEntity, "Operation currency does not match the wallet currency")
+	ErrWalletFrozen         = New(CodeWalletFrozen, http.StatusConflict, "Wallet is frozen")
+	ErrFeeWalletInvalid     = New(CodeFeeWalletInvalid, http.StatusInternalServerError, "Fee wallet is misconfigured")
+	ErrInternal             = New(CodeInternal, http.StatusInternalServerError, "Internal server error")
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Code    Code
+	Status  int
+	Message string
+	// Detail - пояснение к конкретному случаю (message - общее для кода)
+	Detail  string
+	Details map[string]any
+	Fields  []FieldError
+	Err     error
+}
+
+func New(code Code, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+// Validation собирает ошибку VALIDATION_FAILED из ошибок по полям
+func Validation(fields ...FieldError) *Error {
+	e := *ErrValidationFailed
+	e.Fields = fields
+	return &e
+}
+
+func (e *Error) Error() string {
+	msg := strings.ToLower(e.Message)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	for _, f := range e.Fields {
+		msg += fmt.Sprintf("; %s: %s", f.Field, f.Message)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithDetail возвращает копию ошибки с пояснением
+func (e *Error) WithDetail(format string, args ...any) *Error {
+	c := *e
+	c.Detail = fmt.Sprintf(format, args...)
+	return &c
+}
+
+// WithDetails возвращает копию ошибки с дополнительными машиночитаемыми данными
+func (e *Error) WithDetails(details map[string]any) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// Wrap возвращает копию ошибки с причиной; причина не попадает в ответ клиенту
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// From приводит произвольную ошибку к *Error; неизвестные ошибки становятся INTERNAL_ERROR
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return ErrInternal.Wrap(err)
+}
@@ -0,0 +1,35 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError_IsMatchesByCode(t *testing.T) {
+	err := fmt.Errorf("withdraw: %w", ErrInsufficientFunds.WithDetail("balance is 10"))
+
+	assert.True(t, errors.Is(err, ErrInsufficientFunds))
+	assert.False(t, errors.Is(err, ErrWalletNotFound))
+}
+
+func TestFrom(t *testing.T) {
+	cause := errors.New("connection reset")
+
+	assert.Equal(t, CodeWalletNotFound, From(fmt.Errorf("get: %w", ErrWalletNotFound)).Code)
+
+	internal := From(cause)
+	assert.Equal(t, CodeInternal, internal.Code)
+	assert.ErrorIs(t, internal, cause)
+}
+
+func TestError_Problem(t *testing.T) {
+	problem := Validation(FieldError{Field: "amount", Message: "must be positive"}).Problem("/api/v1/wallet")
+
+	assert.Equal(t, ProblemTypeBase+"validation_failed", problem.Type)
+	assert.Equal(t, 400, problem.Status)
+	assert.Equal(t, CodeValidationFailed, problem.Code)
+	assert.Len(t, problem.Errors, 1)
+}
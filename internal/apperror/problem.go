@@ -0,0 +1,34 @@
+package apperror
+
+import "strings"
+
+// ProblemContentType - тип ответа с ошибкой по RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypeBase - документация кодов; type ответа ссылается на раздел конкретного кода
+const ProblemTypeBase = "https://github.com/forygg/wallet-service/blob/main/docs/errors.md#"
+
+// Problem - тело ответа application/problem+json
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     Code           `json:"code"`
+	Errors   []FieldError   `json:"errors,omitempty"`
+	Details  map[string]any `json:"details,omitempty"`
+}
+
+func (e *Error) Problem(instance string) Problem {
+	return Problem{
+		Type:     ProblemTypeBase + strings.ToLower(string(e.Code)),
+		Title:    e.Message,
+		Status:   e.Status,
+		Detail:   e.Detail,
+		Instance: instance,
+		Code:     e.Code,
+		Errors:   e.Fields,
+		Details:  e.Details,
+	}
+}
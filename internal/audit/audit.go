@@ -0,0 +1,59 @@
+// Package audit передает запись аудита текущего запроса через контекст.
+// Middleware создает запись, а сервисы и обработчики дополняют ее тем, что
+// известно только им: кошелек, созданная операция, код ошибки
+package audit
+
+import (
+	"context"
+
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+)
+
+type contextKey struct{}
+
+// NewContext возвращает контекст с записью, которую будут дополнять хуки
+func NewContext(ctx context.Context, rec *model.AuditRecord) context.Context {
+	return context.WithValue(ctx, contextKey{}, rec)
+}
+
+// FromContext возвращает запись аудита запроса или nil, если аудит не ведется
+func FromContext(ctx context.Context) *model.AuditRecord {
+	rec, _ := ctx.Value(contextKey{}).(*model.AuditRecord)
+	return rec
+}
+
+// SetWallet отмечает кошелек, который меняет запрос
+func SetWallet(ctx context.Context, id uuid.UUID) {
+	if rec := FromContext(ctx); rec != nil {
+		rec.WalletID = &id
+	}
+}
+
+// SetOperation отмечает операцию, созданную запросом
+func SetOperation(ctx context.Context, id uuid.UUID) {
+	if rec := FromContext(ctx); rec != nil {
+		rec.OperationID = &id
+	}
+}
+
+// SetError отмечает код ошибки из docs/errors.md, с которой завершился запрос
+func SetError(ctx context.Context, code string) {
+	if rec := FromContext(ctx); rec != nil {
+		rec.ErrorCode = code
+	}
+}
+
+// SetTenant отмечает тенанта, от имени которого выполняется запрос
+func SetTenant(ctx context.Context, id string) {
+	if rec := FromContext(ctx); rec != nil {
+		rec.TenantID = id
+	}
+}
+
+// SetActor отмечает, кто выполняет запрос: оператора или клиента, определенного по его ключу
+func SetActor(ctx context.Context, actor string) {
+	if rec := FromContext(ctx); rec != nil {
+		rec.Actor = actor
+	}
+}
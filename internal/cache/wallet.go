@@ -0,0 +1,264 @@
+// Package cache - LRU-кеш кошельков в памяти реплики. Записи устаревают по событиям
+// изменения кошелька (events.Hub), поэтому кеш согласован между репликами с задержкой
+// доставки NOTIFY; TTL ограничивает устаревание, если событие потерялось.
+// Устаревшая запись остается в кеше до TTL как нижняя граница версии: чтение с
+// отстающей реплики Postgres не вернет в кеш версию старше события. Границы для
+// некешированных кошельков хранятся отдельно и не вытесняют живые записи
+package cache
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"wallet-service/internal/metrics"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+)
+
+type key struct {
+	tenantID string
+	walletID uuid.UUID
+}
+
+type entry struct {
+	key     key
+	wallet  model.Wallet
+	expires time.Time
+	// stale - кошелек изменился; от записи осталась только версия wallet.Version,
+	// ниже которой кешировать нельзя
+	stale bool
+}
+
+// floor - нижняя граница версии кошелька, которого нет в кеше: событие пришло,
+// когда его никто не читал
+type floor struct {
+	key     key
+	version int
+	expires time.Time
+}
+
+// pendingLoad - чтения кошелька из базы, которые идут прямо сейчас. Событие во время
+// чтения поднимает minVersion, и прочитанная до коммита версия не попадет в кеш
+type pendingLoad struct {
+	count      int
+	minVersion int
+}
+
+// WalletCache хранит до size кошельков не дольше ttl
+type WalletCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	items   map[key]*list.Element
+	// order - от недавно использованных к давно
+	order   *list.List
+	pending map[key]*pendingLoad
+	// floors - до size границ версий некешированных кошельков, от новых к старым
+	floors     map[key]*list.Element
+	floorOrder *list.List
+}
+
+func NewWalletCache(size int, ttl time.Duration) *WalletCache {
+	return &WalletCache{
+		size:       size,
+		ttl:        ttl,
+		now:        time.Now,
+		items:      make(map[key]*list.Element),
+		order:      list.New(),
+		pending:    make(map[key]*pendingLoad),
+		floors:     make(map[key]*list.Element),
+		floorOrder: list.New(),
+	}
+}
+
+// GetOrLoad возвращает кошелек из кеша, а при промахе читает его через load и кладет в кеш
+func (c *WalletCache) GetOrLoad(tenantID string, walletID uuid.UUID, load func() (model.Wallet, error)) (model.Wallet, error) {
+	k := key{tenantID: tenantID, walletID: walletID}
+
+	c.mu.Lock()
+	if el, ok := c.items[k]; ok {
+		e := el.Value.(*entry)
+		switch {
+		case !c.now().Before(e.expires):
+			c.removeElement(el)
+		case !e.stale:
+			c.order.MoveToFront(el)
+			c.mu.Unlock()
+			metrics.CacheHits.Add(1)
+			return e.wallet, nil
+		}
+	}
+	p := c.pending[k]
+	if p == nil {
+		p = &pendingLoad{minVersion: c.floorOf(k)}
+		c.pending[k] = p
+	}
+	p.count++
+	c.mu.Unlock()
+	metrics.CacheMisses.Add(1)
+
+	wallet, err := load()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p.count--
+	if p.count == 0 {
+		delete(c.pending, k)
+	}
+	switch {
+	case err == nil && wallet.Version >= p.minVersion:
+		c.put(k, wallet)
+	case p.minVersion > 0 && p.minVersion != math.MaxInt:
+		// В кеш ничего не попало - граница, поднятая событием, не должна пропасть
+		c.setFloor(k, p.minVersion)
+	}
+	return wallet, err
+}
+
+// Invalidate сообщает, что кошелек изменился до версии version: более старая
+// запись устаревает, а идущие и будущие чтения не положат в кеш версию ниже.
+// Кошелек, которого нет в кеше и который сейчас не читается, получает только
+// границу версии в floors
+func (c *WalletCache) Invalidate(tenantID string, walletID uuid.UUID, version int) {
+	k := key{tenantID: tenantID, walletID: walletID}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p := c.pending[k]
+	if p != nil && p.minVersion < version {
+		p.minVersion = version
+	}
+	el, ok := c.items[k]
+	if !ok {
+		if p == nil {
+			c.setFloor(k, version)
+		}
+		return
+	}
+	if e := el.Value.(*entry); e.wallet.Version < version {
+		if !e.stale {
+			metrics.CacheInvalidations.Add(1)
+		}
+		e.wallet, e.stale = model.Wallet{Version: version}, true
+	}
+}
+
+// Notify - events.Observer: событие любой реплики делает запись кошелька устаревшей
+func (c *WalletCache) Notify(tenantID string, event model.BalanceEvent) {
+	c.Invalidate(tenantID, event.WalletID, event.Version)
+}
+
+// Reset - events.Observer: события могли потеряться, кеш очищается целиком
+func (c *WalletCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[key]*list.Element)
+	c.order.Init()
+	c.floors = make(map[key]*list.Element)
+	c.floorOrder.Init()
+	// Чтения, начатые до сброса, в кеш не попадут
+	for _, p := range c.pending {
+		p.minVersion = math.MaxInt
+	}
+}
+
+// Len - число кошельков в кеше без устаревших записей
+func (c *WalletCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		if !el.Value.(*entry).stale {
+			n++
+		}
+	}
+	return n
+}
+
+// put вызывается под c.mu
+func (c *WalletCache) put(k key, wallet model.Wallet) {
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.items[k]; ok {
+		e := el.Value.(*entry)
+		// Параллельное чтение могло положить более новую версию, а событие -
+		// поднять нижнюю границу
+		if e.wallet.Version <= wallet.Version {
+			e.wallet, e.expires, e.stale = wallet, expires, false
+		}
+		c.order.MoveToFront(el)
+		return
+	}
+	c.push(&entry{key: k, wallet: wallet, expires: expires})
+	// Дальше нижней границей служит сама запись
+	if el, ok := c.floors[k]; ok {
+		c.removeFloor(el)
+	}
+}
+
+// push вызывается под c.mu
+func (c *WalletCache) push(e *entry) {
+	c.items[e.key] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *WalletCache) removeElement(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*entry).key)
+}
+
+// floorOf - граница версии некешированного кошелька, 0 - границы нет. Вызывается под c.mu
+func (c *WalletCache) floorOf(k key) int {
+	el, ok := c.floors[k]
+	if !ok {
+		return 0
+	}
+	f := el.Value.(*floor)
+	if !c.now().Before(f.expires) {
+		c.removeFloor(el)
+		return 0
+	}
+	return f.version
+}
+
+// setFloor поднимает границу версии кошелька. Вызывается под c.mu
+func (c *WalletCache) setFloor(k key, version int) {
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.floors[k]; ok {
+		f := el.Value.(*floor)
+		if f.version < version {
+			f.version = version
+		}
+		f.expires = expires
+		c.floorOrder.MoveToFront(el)
+		return
+	}
+	c.floors[k] = c.floorOrder.PushFront(&floor{key: k, version: version, expires: expires})
+	for c.floorOrder.Len() > c.size {
+		c.removeFloor(c.floorOrder.Back())
+	}
+}
+
+func (c *WalletCache) removeFloor(el *list.Element) {
+	c.floorOrder.Remove(el)
+	delete(c.floors, el.Value.(*floor).key)
+}
+
+type bypassKey struct{}
+
+// WithoutCache - чтения с этим контекстом идут в базу мимо кеша
+// (запрос с Cache-Control: no-cache)
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Bypassed сообщает, что запрос просил не читать из кеша
+func Bypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
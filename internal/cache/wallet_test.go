@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loader считает обращения к базе и отдает кошелек текущей версии
+type loader struct {
+	calls   int
+	version int
+}
+
+func (l *loader) load(id uuid.UUID) func() (model.Wallet, error) {
+	return func() (model.Wallet, error) {
+		l.calls++
+		return model.Wallet{ID: id, Version: l.version}, nil
+	}
+}
+
+func TestWalletCache_ReadThroughAndInvalidate(t *testing.T) {
+	c := NewWalletCache(10, time.Minute)
+	id := uuid.New()
+	db := &loader{version: 1}
+
+	for i := 0; i < 3; i++ {
+		wallet, err := c.GetOrLoad("acme", id, db.load(id))
+		require.NoError(t, err)
+		assert.Equal(t, 1, wallet.Version)
+	}
+	assert.Equal(t, 1, db.calls)
+
+	// Тот же id у другого тенанта - другая запись
+	c.GetOrLoad("globex", id, db.load(id))
+	assert.Equal(t, 2, db.calls)
+
+	// Событие уже закешированной версии запись не трогает
+	c.Notify("acme", model.BalanceEvent{WalletID: id, Version: 1})
+	c.GetOrLoad("acme", id, db.load(id))
+	assert.Equal(t, 2, db.calls)
+
+	db.version = 2
+	c.Notify("acme", model.BalanceEvent{WalletID: id, Version: 2})
+	wallet, _ := c.GetOrLoad("acme", id, db.load(id))
+	assert.Equal(t, 2, wallet.Version)
+	assert.Equal(t, 3, db.calls)
+}
+
+func TestWalletCache_InvalidateDuringLoad(t *testing.T) {
+	c := NewWalletCache(10, time.Minute)
+	id := uuid.New()
+
+	// Чтение видело версию до коммита, а событие пришло, пока оно шло
+	wallet, err := c.GetOrLoad("acme", id, func() (model.Wallet, error) {
+		c.Invalidate("acme", id, 2)
+		return model.Wallet{ID: id, Version: 1}, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, wallet.Version)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestWalletCache_LaggingReadAfterInvalidate(t *testing.T) {
+	c := NewWalletCache(10, time.Minute)
+	id := uuid.New()
+	replica := &loader{version: 1}
+
+	// Событие пришло раньше, чем реплика воспроизвела изменение
+	c.Notify("acme", model.BalanceEvent{WalletID: id, Version: 2})
+	wallet, err := c.GetOrLoad("acme", id, replica.load(id))
+	require.NoError(t, err)
+	assert.Equal(t, 1, wallet.Version)
+	assert.Equal(t, 0, c.Len())
+
+	replica.version = 2
+	c.GetOrLoad("acme", id, replica.load(id))
+	c.GetOrLoad("acme", id, replica.load(id))
+	assert.Equal(t, 2, replica.calls)
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestWalletCache_EvictionAndTTL(t *testing.T) {
+	c := NewWalletCache(2, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	db := &loader{version: 1}
+	a, b, d := uuid.New(), uuid.New(), uuid.New()
+
+	c.GetOrLoad("acme", a, db.load(a))
+	c.GetOrLoad("acme", b, db.load(b))
+	c.GetOrLoad("acme", a, db.load(a))
+	// b - давно не читался и вытесняется
+	c.GetOrLoad("acme", d, db.load(d))
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, 3, db.calls)
+	c.GetOrLoad("acme", b, db.load(b))
+	assert.Equal(t, 4, db.calls)
+
+	now = now.Add(time.Minute)
+	c.GetOrLoad("acme", b, db.load(b))
+	assert.Equal(t, 5, db.calls)
+
+	c.Reset()
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestWalletCache_ErrorsAreNotCached(t *testing.T) {
+	c := NewWalletCache(10, time.Minute)
+	id := uuid.New()
+	notFound := errors.New("not found")
+
+	_, err := c.GetOrLoad("acme", id, func() (model.Wallet, error) { return model.Wallet{}, notFound })
+	assert.ErrorIs(t, err, notFound)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestWalletCache_InvalidateUncachedKeepsEntries(t *testing.T) {
+	c := NewWalletCache(2, time.Minute)
+	db := &loader{version: 1}
+	a, b := uuid.New(), uuid.New()
+	c.GetOrLoad("acme", a, db.load(a))
+	c.GetOrLoad("acme", b, db.load(b))
+
+	// События по кошелькам, которых нет в кеше, не вытесняют живые записи
+	for i := 0; i < 10; i++ {
+		c.Notify("acme", model.BalanceEvent{WalletID: uuid.New(), Version: 5})
+	}
+	c.GetOrLoad("acme", a, db.load(a))
+	c.GetOrLoad("acme", b, db.load(b))
+	assert.Equal(t, 2, db.calls)
+	assert.Equal(t, 2, c.Len())
+
+	// Граница версии некешированного кошелька все равно действует
+	d := uuid.New()
+	c.Notify("acme", model.BalanceEvent{WalletID: d, Version: 2})
+	c.GetOrLoad("acme", d, db.load(d))
+	c.GetOrLoad("acme", d, db.load(d))
+	assert.Equal(t, 4, db.calls)
+}
@@ -1,48 +1,516 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"net"
 	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
+// Config собирается из нескольких источников. Приоритет по возрастанию:
+// значения по умолчанию, файл (-config / CONFIG_FILE), переменные окружения, флаги
 type Config struct {
-	Port		string
-	Database	DatabaseConfig
+	Port      string          `yaml:"port"`
+	LogLevel  string          `yaml:"logLevel"`
+	HTTP      HTTPConfig      `yaml:"http"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Retry     RetryConfig     `yaml:"retry"`
+	Features  FeaturesConfig  `yaml:"features"`
+	Limits    LimitsConfig    `yaml:"limits"`
+	Admin     AdminConfig     `yaml:"admin"`
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+	Fees      FeesConfig      `yaml:"fees"`
+	Snapshots SnapshotsConfig `yaml:"snapshots"`
+	Audit     AuditConfig     `yaml:"audit"`
+	Chain     ChainConfig     `yaml:"chain"`
+	Events    EventsConfig    `yaml:"events"`
+	Cache     CacheConfig     `yaml:"cache"`
+	// Currencies - валюты кошельков тенантов, у которых свои не заданы; первая - по умолчанию
+	Currencies []string `yaml:"currencies"`
+	// Tenants - тенанты со своими ключами и настройками. Тенант default есть всегда
+	// и получает глобальные настройки; его можно описать здесь, чтобы задать ключи
+	Tenants []TenantConfig `yaml:"tenants"`
+}
+
+type HTTPConfig struct {
+	ReadTimeout     time.Duration `yaml:"readTimeout"`
+	WriteTimeout    time.Duration `yaml:"writeTimeout"`
+	IdleTimeout     time.Duration `yaml:"idleTimeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// ShutdownDrainDelay - сколько /readyz отдает 503 перед остановкой HTTP-сервера
+	ShutdownDrainDelay time.Duration `yaml:"shutdownDrainDelay"`
 }
 
 type DatabaseConfig struct {
-	Host		string
-	Port		string
-	User		string
-	Password	string
-	Name		string
-	SSLMode		string
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	// PasswordFile - файл с паролем (например, docker/k8s secret); имеет приоритет над Password
+	PasswordFile string `yaml:"passwordFile"`
+	Name         string `yaml:"name"`
+	SSLMode      string `yaml:"sslMode"`
+	// LockStrategy - pessimistic, optimistic, serializable или advisory
+	LockStrategy string `yaml:"lockStrategy"`
+
+	MaxOpenConns int `yaml:"maxOpenConns"`
+	// MaxIdleConns - сколько соединений пул держит открытыми и без нагрузки
+	MaxIdleConns    int           `yaml:"maxIdleConns"`
+	ConnMaxLifetime time.Duration `yaml:"connMaxLifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"connMaxIdleTime"`
+
+	// Replicas - адреса host:port реплик для чтения балансов; учетные данные и база
+	// те же, что у основного сервера. Пусто - все читается с основного
+	Replicas []string `yaml:"replicas"`
+	// ReplicaWait - сколько чтение с токеном согласованности ждет, пока реплика
+	// догонит запись, прежде чем уйти на основной сервер
+	ReplicaWait time.Duration `yaml:"replicaWait"`
+}
+
+// RetryConfig - параметры повторов операций при конфликтах конкурентного доступа
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"maxAttempts"`
+	BaseDelay   time.Duration `yaml:"baseDelay"`
+	MaxDelay    time.Duration `yaml:"maxDelay"`
+}
+
+// FeaturesConfig - переключатели необязательной функциональности
+type FeaturesConfig struct {
+	// Metrics публикует счетчики сервиса на /debug/vars
+	Metrics bool `yaml:"metrics"`
+}
+
+// LimitsConfig - лимиты по умолчанию для всех кошельков; 0 - без ограничения.
+// Лимиты отдельного кошелька меняются через админский API
+type LimitsConfig struct {
+	MaxOperationAmount      decimal.Decimal `yaml:"maxOperationAmount"`
+	DailyWithdrawalAmount   decimal.Decimal `yaml:"dailyWithdrawalAmount"`
+	DailyWithdrawalCount    int             `yaml:"dailyWithdrawalCount"`
+	MonthlyWithdrawalAmount decimal.Decimal `yaml:"monthlyWithdrawalAmount"`
+	MonthlyWithdrawalCount  int             `yaml:"monthlyWithdrawalCount"`
+}
+
+// AdminConfig - доступ к /api/v1/admin. Без токена и операторов админский API отключен
+type AdminConfig struct {
+	// Token - токен оператора admin
+	Token string `yaml:"token"`
+	// TokenFile - файл с токеном (docker/k8s secret); имеет приоритет над Token
+	TokenFile string `yaml:"tokenFile"`
+	// Operators - собственные токены операторов: id оператора -> SHA-256 токена в hex
+	// (echo -n "$TOKEN" | sha256sum). Оператор запроса определяется по токену, поэтому
+	// корректировку, предложенную одним оператором, одобряет только другой
+	Operators map[string]string `yaml:"operators"`
+}
+
+// AdminOperator - оператор, которому принадлежит Token
+const AdminOperator = "admin"
+
+// maxOperatorLength совпадает с размером колонок adjustments.proposed_by и audit_log.actor
+const maxOperatorLength = 64
+
+// Enabled сообщает, настроен ли хотя бы один оператор админского API
+func (c AdminConfig) Enabled() bool {
+	return c.Token != "" || len(c.Operators) > 0
+}
+
+// TokenHashes - операторы по SHA-256 их токенов, включая Token оператора admin
+func (c AdminConfig) TokenHashes() (map[[sha256.Size]byte]string, error) {
+	hashes := make(map[[sha256.Size]byte]string, len(c.Operators)+1)
+	if c.Token != "" {
+		hashes[sha256.Sum256([]byte(c.Token))] = AdminOperator
+	}
+
+	ids := make([]string, 0, len(c.Operators))
+	for id := range c.Operators {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if id == "" || len(id) > maxOperatorLength {
+			return nil, fmt.Errorf("operator id %q must be 1 to %d characters", id, maxOperatorLength)
+		}
+		if id == AdminOperator && c.Token != "" {
+			return nil, fmt.Errorf("operator %q is taken by the admin token", id)
+		}
+		decoded, err := hex.DecodeString(strings.TrimSpace(c.Operators[id]))
+		if err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("operator %q must have a hex SHA-256 token hash", id)
+		}
+		hash := [sha256.Size]byte(decoded)
+		if other, ok := hashes[hash]; ok {
+			return nil, fmt.Errorf("operators %q and %q share a token", other, id)
+		}
+		hashes[hash] = id
+	}
+	return hashes, nil
+}
+
+// SchedulerConfig - фоновое выполнение отложенных операций. API заданий работает
+// всегда; выключенный планировщик только перестает их запускать на этой реплике
+type SchedulerConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Interval - как часто проверять наступившие задания
+	Interval time.Duration `yaml:"interval"`
+	// BatchSize - сколько заданий забирать одной транзакцией
+	BatchSize int `yaml:"batchSize"`
+}
+
+// SnapshotsConfig - периодические снимки остатков счетов для запросов баланса
+// на момент времени. Без снимков ответы верны, но читают больше проводок
+type SnapshotsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Interval - как часто снимать остатки
+	Interval time.Duration `yaml:"interval"`
+	// Lag - насколько момент снимка отстает от текущего времени; должен превышать
+	// длительность самой долгой транзакции операции
+	Lag time.Duration `yaml:"lag"`
+}
+
+// AuditConfig - журнал аудита изменяющих запросов
+type AuditConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// TrustProxy - брать адрес клиента из последнего адреса X-Forwarded-For; включать
+	// только за своим балансировщиком, который дописывает этот заголовок
+	TrustProxy bool `yaml:"trustProxy"`
+}
+
+// ChainConfig - подписанные контрольные точки цепочки операций. Цепочка пишется
+// всегда; пустой ключ отключает только точки
+type ChainConfig struct {
+	// SigningKey - Ed25519 в base64: seed (32 байта) или закрытый ключ (64 байта)
+	SigningKey string `yaml:"signingKey"`
+	// SigningKeyFile - файл с ключом (docker/k8s secret); имеет приоритет над SigningKey
+	SigningKeyFile string `yaml:"signingKeyFile"`
+	// CheckpointInterval - как часто публиковать точку
+	CheckpointInterval time.Duration `yaml:"checkpointInterval"`
+}
+
+// EventsConfig - потоки изменений баланса (SSE и WebSocket). Уведомления
+// о коммитах пишутся всегда; выключенные потоки не слушают их на этой реплике
+type EventsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// HeartbeatInterval - период heartbeat в SSE и ping в WebSocket
+	HeartbeatInterval time.Duration `yaml:"heartbeatInterval"`
+}
+
+// CacheConfig - кеш кошельков в памяти реплики для запросов баланса. Устаревшие
+// записи удаляются по LISTEN/NOTIFY, TTL - страховка от потерянных уведомлений
+type CacheConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Size - сколько кошельков хранить
+	Size int           `yaml:"size"`
+	TTL  time.Duration `yaml:"ttl"`
+}
+
+// PrivateKey разбирает SigningKey; nil, если ключ не задан
+func (c ChainConfig) PrivateKey() (ed25519.PrivateKey, error) {
+	if c.SigningKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(c.SigningKey))
+	if err != nil {
+		return nil, fmt.Errorf("must be base64: %v", err)
+	}
+	switch len(key) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(key), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(key), nil
+	default:
+		return nil, fmt.Errorf("must be a %d-byte seed or a %d-byte private key, got %d bytes",
+			ed25519.SeedSize, ed25519.PrivateKeySize, len(key))
+	}
 }
 
+// FeesConfig - тарифная сетка комиссий. Пустой WalletID отключает комиссии.
+// Правила задаются только в файле конфигурации
+type FeesConfig struct {
+	// WalletID - кошелек, на который зачисляются комиссии
+	WalletID string          `yaml:"walletId"`
+	Rules    []FeeRuleConfig `yaml:"rules"`
+}
+
+// FeeRuleConfig - комиссия fixed + percent% от суммы, ограниченная min и max
+type FeeRuleConfig struct {
+	OperationType string `yaml:"operationType"`
+	// Tier - тариф кошелька; пустой - правило для всех тарифов
+	Tier    string           `yaml:"tier"`
+	Fixed   decimal.Decimal  `yaml:"fixed"`
+	Percent decimal.Decimal  `yaml:"percent"`
+	Min     *decimal.Decimal `yaml:"min"`
+	Max     *decimal.Decimal `yaml:"max"`
+}
+
+// Model переводит сетку в model.FeeSchedule. WalletID к этому моменту проверен validate
+func (f FeesConfig) Model() model.FeeSchedule {
+	var schedule model.FeeSchedule
+	if f.WalletID != "" {
+		schedule.WalletID = uuid.MustParse(f.WalletID)
+	}
+	for _, r := range f.Rules {
+		schedule.Rules = append(schedule.Rules, model.FeeRule{
+			OperationType: model.OperationType(r.OperationType),
+			Tier:          r.Tier,
+			Fixed:         r.Fixed,
+			Percent:       r.Percent,
+			Min:           r.Min,
+			Max:           r.Max,
+		})
+	}
+	return schedule
+}
+
+// TenantConfig - тенант: ключи клиентов, валюты, лимиты и комиссии.
+// Тенанты задаются только в файле конфигурации
+type TenantConfig struct {
+	ID string `yaml:"id"`
+	// APIKeys - ключи X-API-Key клиентов тенанта
+	APIKeys []string `yaml:"apiKeys"`
+	// APIKeysFile - файл с ключами по одному на строку (docker/k8s secret); дополняет APIKeys
+	APIKeysFile string `yaml:"apiKeysFile"`
+	// Currencies - валюты кошельков; пустой список - глобальный currencies
+	Currencies []string `yaml:"currencies"`
+	// Limits - лимиты по умолчанию; не заданы - глобальные limits
+	Limits *LimitsConfig `yaml:"limits"`
+	// Fees - тарифная сетка; не задана - без комиссий (у тенанта default - глобальная fees)
+	Fees *FeesConfig `yaml:"fees"`
+}
+
+// TenantModels - настройки всех тенантов, первым - тенант по умолчанию
+func (c *Config) TenantModels() []model.Tenant {
+	tenants := []model.Tenant{{
+		ID:         tenant.DefaultID,
+		Currencies: c.Currencies,
+		Limits:     c.Limits.Model(),
+		Fees:       c.Fees.Model(),
+	}}
+	for _, t := range c.Tenants {
+		m := model.Tenant{
+			ID:         t.ID,
+			Currencies: c.Currencies,
+			Limits:     c.Limits.Model(),
+			APIKeys:    t.APIKeys,
+		}
+		if len(t.Currencies) > 0 {
+			m.Currencies = t.Currencies
+		}
+		if t.Limits != nil {
+			m.Limits = t.Limits.Model()
+		}
+		if t.Fees != nil {
+			m.Fees = t.Fees.Model()
+		}
+		if t.ID == tenant.DefaultID {
+			if t.Fees == nil {
+				m.Fees = tenants[0].Fees
+			}
+			tenants[0] = m
+			continue
+		}
+		tenants = append(tenants, m)
+	}
+	return tenants
+}
+
+// Model переводит лимиты в model.Limits: нулевые значения становятся nil (без ограничения)
+func (l LimitsConfig) Model() model.Limits {
+	amount := func(d decimal.Decimal) *decimal.Decimal {
+		if d.IsZero() {
+			return nil
+		}
+		return &d
+	}
+	count := func(n int) *int {
+		if n == 0 {
+			return nil
+		}
+		return &n
+	}
+	return model.Limits{
+		MaxOperationAmount:      amount(l.MaxOperationAmount),
+		DailyWithdrawalAmount:   amount(l.DailyWithdrawalAmount),
+		DailyWithdrawalCount:    count(l.DailyWithdrawalCount),
+		MonthlyWithdrawalAmount: amount(l.MonthlyWithdrawalAmount),
+		MonthlyWithdrawalCount:  count(l.MonthlyWithdrawalCount),
+	}
+}
+
+func Default() *Config {
+	return &Config{
+		Port:     "8080",
+		LogLevel: "info",
+		HTTP: HTTPConfig{
+			ReadTimeout:        30 * time.Second,
+			WriteTimeout:       30 * time.Second,
+			IdleTimeout:        120 * time.Second,
+			ShutdownTimeout:    30 * time.Second,
+			ShutdownDrainDelay: 5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            "5432",
+			User:            "wallet_user",
+			Password:        "wallet_password",
+			Name:            "wallet_db",
+			SSLMode:         "disable",
+			LockStrategy:    "pessimistic",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 5 * time.Minute,
+			ConnMaxIdleTime: 2 * time.Minute,
+			ReplicaWait:     100 * time.Millisecond,
+		},
+		Retry: RetryConfig{
+			MaxAttempts: 3,
+			BaseDelay:   10 * time.Millisecond,
+			MaxDelay:    200 * time.Millisecond,
+		},
+		Features: FeaturesConfig{
+			Metrics: true,
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:   true,
+			Interval:  5 * time.Second,
+			BatchSize: 50,
+		},
+		Snapshots: SnapshotsConfig{
+			Enabled:  true,
+			Interval: time.Hour,
+			Lag:      5 * time.Minute,
+		},
+		Audit: AuditConfig{
+			Enabled: true,
+		},
+		Chain: ChainConfig{
+			CheckpointInterval: time.Hour,
+		},
+		Events: EventsConfig{
+			Enabled:           true,
+			HeartbeatInterval: 15 * time.Second,
+		},
+		Cache: CacheConfig{
+			Size: 10000,
+			TTL:  30 * time.Second,
+		},
+		Currencies: []string{"USD"},
+	}
+}
+
+// Load читает конфигурацию из аргументов командной строки процесса
 func Load() (*Config, error) {
-    cfg := &Config{
-        Port: getEnv("PORT", "8080"),
-        Database: DatabaseConfig{
-            Host:     getEnv("DB_HOST", "localhost"),
-            Port:     getEnv("DB_PORT", "5432"),
-            User:     getEnv("DB_USER", "wallet_user"),
-            Password: getEnv("DB_PASSWORD", "wallet_password"),
-            Name:     getEnv("DB_NAME", "wallet_db"),
-            SSLMode:  getEnv("DB_SSLMODE", "disable"),
-        },
-    }
-    
-    return cfg, nil
+	return LoadArgs(os.Args[1:])
+}
+
+// LoadArgs собирает конфигурацию из всех источников и валидирует ее.
+// Ошибки всех источников и валидации возвращаются разом
+func LoadArgs(args []string) (*Config, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := newFlagSet(settings)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to YAML or JSON config file (env CONFIG_FILE)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var problems []string
+	problems = append(problems, applyEnv(settings)...)
+	// Флаги уже разобраны, но применяем их только сейчас - поверх файла и окружения
+	problems = append(problems, applyFlags(fs, settings)...)
+
+	if err := resolveSecret(&cfg.Database.Password, cfg.Database.PasswordFile); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := resolveSecret(&cfg.Admin.Token, cfg.Admin.TokenFile); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := resolveSecret(&cfg.Chain.SigningKey, cfg.Chain.SigningKeyFile); err != nil {
+		problems = append(problems, err.Error())
+	}
+	for i := range cfg.Tenants {
+		if err := resolveKeys(&cfg.Tenants[i].APIKeys, cfg.Tenants[i].APIKeysFile); err != nil {
+			problems = append(problems, fmt.Sprintf("tenants[%d].apiKeysFile: %v", i, err))
+		}
+	}
+
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	return cfg, nil
 }
 
 func (d DatabaseConfig) ConnectionString() string {
-    return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-        d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode)
+	return d.connectionString(d.Host, d.Port)
+}
+
+// ReplicaConnectionString - строка подключения к реплике с адресом host:port
+func (d DatabaseConfig) ReplicaConnectionString(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, d.Port
+	}
+	return d.connectionString(host, port)
+}
+
+func (d DatabaseConfig) connectionString(host, port string) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		dsnValue(host), dsnValue(port), dsnValue(d.User), dsnValue(d.Password), dsnValue(d.Name), dsnValue(d.SSLMode))
+}
+
+// dsnValue заключает значение строки подключения в кавычки, как требует libpq: пароль
+// из файла может содержать пробел, кавычку или обратную косую черту, а без кавычек
+// "x sslmode=disable" стал бы двумя параметрами
+func dsnValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// resolveSecret подставляет значение секрета из файла, если путь задан
+func resolveSecret(value *string, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read secret file: %v", err)
+	}
+	*value = strings.TrimRight(string(data), "\r\n")
+	return nil
 }
 
-func getEnv(key, defaultValue string) string {
-    if value := os.Getenv(key); value != "" {
-        return value
-    }
-    return defaultValue
-}
\ No newline at end of file
+// resolveKeys добавляет ключи из файла, по одному на строку; пустые строки пропускаются
+func resolveKeys(keys *[]string, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read keys file: %v", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if key := strings.TrimSpace(line); key != "" {
+			*keys = append(*keys, key)
+		}
+	}
+	return nil
+}
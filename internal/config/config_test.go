@@ -0,0 +1,352 @@
+package config
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// clearEnv убирает из окружения теста все переменные конфигурации:
+// DB_HOST или PORT разработчика не должны влиять на результат
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	for _, s := range (&Config{}).settings() {
+		// Пустое значение applyEnv пропускает, как и отсутствующее
+		t.Setenv(s.env, "")
+	}
+}
+
+func TestLoadArgs_Defaults(t *testing.T) {
+	clearEnv(t)
+	cfg, err := LoadArgs(nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoadArgs_Precedence(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+port: "9000"
+database:
+  host: file-host
+  maxOpenConns: 50
+  maxIdleConns: 20
+retry:
+  maxAttempts: 5
+  baseDelay: 20ms
+http:
+  readTimeout: 10s
+`)
+
+	// Файл < окружение < флаги
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("RETRY_MAX_ATTEMPTS", "7")
+
+	cfg, err := LoadArgs([]string{"-retry-max-attempts", "9"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "9000", cfg.Port)
+	assert.Equal(t, "env-host", cfg.Database.Host)
+	assert.Equal(t, 50, cfg.Database.MaxOpenConns)
+	assert.Equal(t, 9, cfg.Retry.MaxAttempts)
+	assert.Equal(t, 20*time.Millisecond, cfg.Retry.BaseDelay)
+	assert.Equal(t, 10*time.Second, cfg.HTTP.ReadTimeout)
+	// Не заданные нигде значения остаются по умолчанию
+	assert.Equal(t, 30*time.Second, cfg.HTTP.WriteTimeout)
+}
+
+func TestLoadArgs_JSONFile(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.json", `{"logLevel": "debug", "database": {"lockStrategy": "advisory"}}`)
+
+	cfg, err := LoadArgs([]string{"-config", path})
+
+	require.NoError(t, err)
+	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.Equal(t, "advisory", cfg.Database.LockStrategy)
+}
+
+func TestLoadArgs_UnknownFileKey(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", "database:\n  maxOpenConnections: 10\n")
+
+	_, err := LoadArgs([]string{"-config", path})
+
+	assert.Error(t, err)
+}
+
+func TestLoadArgs_PasswordFile(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "db_password", "s3cret\n")
+	t.Setenv("DB_PASSWORD", "from-env")
+	t.Setenv("DB_PASSWORD_FILE", path)
+
+	cfg, err := LoadArgs(nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", cfg.Database.Password)
+}
+
+func TestDatabaseConfig_ConnectionStringQuotesValues(t *testing.T) {
+	for _, password := range []string{`pa ss`, `it's`, `back\slash`, `x sslmode=disable`, `\' host=evil`, ``} {
+		d := DatabaseConfig{Host: "db", Port: "5432", User: "wallet user", Password: password, Name: "wallet's", SSLMode: "require"}
+
+		parsed, err := pgconn.ParseConfig(d.ConnectionString())
+
+		require.NoError(t, err, password)
+		assert.Equal(t, password, parsed.Password)
+		assert.Equal(t, "wallet user", parsed.User)
+		assert.Equal(t, "wallet's", parsed.Database)
+		assert.Equal(t, "db", parsed.Host)
+		// sslmode из пароля не подменяет настроенный
+		assert.NotNil(t, parsed.TLSConfig, password)
+	}
+}
+
+func TestLoadArgs_Replicas(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_REPLICAS", "replica-1:5433, replica-2")
+
+	cfg, err := LoadArgs(nil)
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"replica-1:5433", "replica-2"}, cfg.Database.Replicas)
+	assert.Contains(t, cfg.Database.ReplicaConnectionString("replica-1:5433"), "host='replica-1' port='5433' ")
+	// Без порта берется порт основного сервера
+	assert.Contains(t, cfg.Database.ReplicaConnectionString("replica-2"), "host='replica-2' port='5432' ")
+
+	t.Setenv("DB_REPLICAS", "replica-1:0")
+	_, err = LoadArgs(nil)
+	assert.ErrorContains(t, err, "database.replicas")
+}
+
+func TestLoadArgs_AggregatesProblems(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DB_MAX_OPEN_CONNS", "many")
+	t.Setenv("DB_LOCK_STRATEGY", "pray")
+	t.Setenv("RETRY_MAX_ATTEMPTS", "0")
+
+	_, err := LoadArgs([]string{"-port", "70000", "-log-level", "loud"})
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Len(t, validationErr.Problems, 5)
+	assert.Contains(t, err.Error(), "env DB_MAX_OPEN_CONNS")
+	assert.Contains(t, err.Error(), "database.lockStrategy")
+	assert.Contains(t, err.Error(), "retry.maxAttempts")
+	assert.Contains(t, err.Error(), "port")
+	assert.Contains(t, err.Error(), "logLevel")
+}
+
+func TestLoadArgs_Limits(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+limits:
+  maxOperationAmount: 10000
+  dailyWithdrawalAmount: "2500.50"
+  dailyWithdrawalCount: 20
+`)
+	t.Setenv("LIMIT_MONTHLY_WITHDRAWAL_AMOUNT", "50000")
+
+	cfg, err := LoadArgs([]string{"-config", path})
+	require.NoError(t, err)
+
+	limits := cfg.Limits.Model()
+	require.NotNil(t, limits.MaxOperationAmount)
+	assert.Equal(t, "10000", limits.MaxOperationAmount.String())
+	assert.Equal(t, "2500.5", limits.DailyWithdrawalAmount.String())
+	assert.Equal(t, 20, *limits.DailyWithdrawalCount)
+	assert.Equal(t, "50000", limits.MonthlyWithdrawalAmount.String())
+	// 0 - без ограничения
+	assert.Nil(t, limits.MonthlyWithdrawalCount)
+}
+
+func TestLoadArgs_Fees(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+fees:
+  walletId: 7c9e6679-7425-40de-944b-e07fc1f90ae7
+  rules:
+    - operationType: WITHDRAW
+      fixed: 1
+      percent: "1.5"
+      min: 2
+      max: 50
+    - operationType: TRANSFER
+      percent: 150
+    - operationType: DEPOSIT
+      fixed: "0.005"
+`)
+
+	_, err := LoadArgs([]string{"-config", path})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fees.rules[1].operationType")
+	assert.Contains(t, err.Error(), "fees.rules[1].percent")
+	assert.Contains(t, err.Error(), "fees.rules[2].fixed must have at most 2 decimal places")
+	assert.NotContains(t, err.Error(), "fees.rules[0]")
+
+	t.Setenv("FEES_WALLET_ID", "not-a-uuid")
+	_, err = LoadArgs([]string{"-config", path})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fees.walletId")
+}
+
+func TestFeesConfig_Model(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+fees:
+  walletId: 7c9e6679-7425-40de-944b-e07fc1f90ae7
+  rules:
+    - operationType: WITHDRAW
+      tier: premium
+      percent: "0.5"
+      max: 50
+`)
+
+	cfg, err := LoadArgs([]string{"-config", path})
+	require.NoError(t, err)
+
+	fees := cfg.Fees.Model()
+	assert.Equal(t, "7c9e6679-7425-40de-944b-e07fc1f90ae7", fees.WalletID.String())
+	require.Len(t, fees.Rules, 1)
+	assert.Equal(t, "premium", fees.Rules[0].Tier)
+	assert.Equal(t, "0.5", fees.Rules[0].Percent.String())
+	assert.Nil(t, fees.Rules[0].Min)
+	assert.Equal(t, "50", fees.Rules[0].Max.String())
+}
+
+func TestLoadArgs_AdminOperators(t *testing.T) {
+	clearEnv(t)
+	alice := sha256.Sum256([]byte("alice-token"))
+	path := writeFile(t, "config.yaml", `
+admin:
+  token: root-token
+  operators:
+    alice: `+hex.EncodeToString(alice[:])+`
+`)
+
+	cfg, err := LoadArgs([]string{"-config", path})
+	require.NoError(t, err)
+	require.True(t, cfg.Admin.Enabled())
+	hashes, err := cfg.Admin.TokenHashes()
+	require.NoError(t, err)
+	assert.Equal(t, map[[sha256.Size]byte]string{
+		sha256.Sum256([]byte("root-token")): AdminOperator,
+		alice:                               "alice",
+	}, hashes)
+
+	path = writeFile(t, "config.yaml", `
+admin:
+  token: root-token
+  operators:
+    admin: `+hex.EncodeToString(alice[:])+`
+`)
+	_, err = LoadArgs([]string{"-config", path})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `operator "admin" is taken by the admin token`)
+
+	path = writeFile(t, "config.yaml", `
+admin:
+  operators:
+    bob: alice-token
+`)
+	_, err = LoadArgs([]string{"-config", path})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `operator "bob" must have a hex SHA-256 token hash`)
+}
+
+func TestLoadArgs_ChainSigningKey(t *testing.T) {
+	clearEnv(t)
+	seed := bytes.Repeat([]byte{7}, ed25519.SeedSize)
+	path := writeFile(t, "chain_key", base64.StdEncoding.EncodeToString(seed)+"\n")
+	t.Setenv("CHAIN_SIGNING_KEY_FILE", path)
+
+	cfg, err := LoadArgs(nil)
+	require.NoError(t, err)
+	key, err := cfg.Chain.PrivateKey()
+	require.NoError(t, err)
+	assert.Equal(t, ed25519.NewKeyFromSeed(seed), key)
+
+	t.Setenv("CHAIN_SIGNING_KEY_FILE", "")
+	t.Setenv("CHAIN_SIGNING_KEY", base64.StdEncoding.EncodeToString([]byte("short")))
+	_, err = LoadArgs(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "chain.signingKey")
+}
+
+func TestLoadArgs_Tenants(t *testing.T) {
+	clearEnv(t)
+	keyFile := writeFile(t, "acme.keys", "acme-key-0123456789\n")
+	path := writeFile(t, "config.yaml", `
+currencies: [USD, EUR]
+fees:
+  walletId: 7c9e6679-7425-40de-944b-e07fc1f90ae7
+tenants:
+  - id: acme
+    apiKeysFile: `+keyFile+`
+    currencies: [EUR]
+    limits:
+      maxOperationAmount: 500
+`)
+
+	_, err := LoadArgs([]string{"-config", path})
+	require.Error(t, err)
+	// У кошелька комиссий тенанта default две валюты
+	assert.Contains(t, err.Error(), "fees.walletId requires exactly one currency")
+
+	t.Setenv("CURRENCIES", "USD")
+	cfg, err := LoadArgs([]string{"-config", path})
+	require.NoError(t, err)
+
+	tenants := cfg.TenantModels()
+	require.Len(t, tenants, 2)
+	assert.Equal(t, "default", tenants[0].ID)
+	assert.Equal(t, []string{"USD"}, tenants[0].Currencies)
+	assert.Equal(t, "7c9e6679-7425-40de-944b-e07fc1f90ae7", tenants[0].Fees.WalletID.String())
+	assert.Equal(t, "acme", tenants[1].ID)
+	assert.Equal(t, []string{"EUR"}, tenants[1].Currencies)
+	assert.Equal(t, []string{"acme-key-0123456789"}, tenants[1].APIKeys)
+	assert.Equal(t, "500", tenants[1].Limits.MaxOperationAmount.String())
+	// Комиссии тенанта задаются только явно
+	assert.Empty(t, tenants[1].Fees.Rules)
+}
+
+func TestLoadArgs_TenantProblems(t *testing.T) {
+	clearEnv(t)
+	path := writeFile(t, "config.yaml", `
+currencies: [usd]
+tenants:
+  - id: Acme
+    apiKeys: [short]
+  - id: beta
+    apiKeys: [short]
+`)
+
+	_, err := LoadArgs([]string{"-config", path})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `currencies: "usd"`)
+	assert.Contains(t, err.Error(), `tenants[0].id: "Acme"`)
+	assert.Contains(t, err.Error(), "tenants[0].apiKeys: keys must be at least 16 characters")
+	assert.Contains(t, err.Error(), "tenants[1].apiKeys: key is already used by tenants[0]")
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// setting связывает поле конфигурации с переменной окружения и флагом.
+// Имя флага получается из имени переменной: DB_MAX_OPEN_CONNS -> -db-max-open-conns
+type setting struct {
+	env   string
+	usage string
+	set   func(string) error
+}
+
+func (s setting) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(s.env), "_", "-")
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		stringSetting("PORT", "HTTP port", &c.Port),
+		stringSetting("LOG_LEVEL", "log level: debug, info, warn, error", &c.LogLevel),
+
+		durationSetting("HTTP_READ_TIMEOUT", "HTTP read timeout", &c.HTTP.ReadTimeout),
+		durationSetting("HTTP_WRITE_TIMEOUT", "HTTP write timeout", &c.HTTP.WriteTimeout),
+		durationSetting("HTTP_IDLE_TIMEOUT", "HTTP keep-alive idle timeout", &c.HTTP.IdleTimeout),
+		durationSetting("SHUTDOWN_TIMEOUT", "graceful shutdown timeout", &c.HTTP.ShutdownTimeout),
+		durationSetting("SHUTDOWN_DRAIN_DELAY", "time /readyz fails before shutdown starts", &c.HTTP.ShutdownDrainDelay),
+
+		stringSetting("DB_HOST", "database host", &c.Database.Host),
+		stringSetting("DB_PORT", "database port", &c.Database.Port),
+		stringSetting("DB_USER", "database user", &c.Database.User),
+		stringSetting("DB_PASSWORD", "database password", &c.Database.Password),
+		stringSetting("DB_PASSWORD_FILE", "file with database password", &c.Database.PasswordFile),
+		stringSetting("DB_NAME", "database name", &c.Database.Name),
+		stringSetting("DB_SSLMODE", "database sslmode", &c.Database.SSLMode),
+		stringSetting("DB_LOCK_STRATEGY", "pessimistic, optimistic, serializable or advisory", &c.Database.LockStrategy),
+		intSetting("DB_MAX_OPEN_CONNS", "max open database connections", &c.Database.MaxOpenConns),
+		intSetting("DB_MAX_IDLE_CONNS", "max idle database connections", &c.Database.MaxIdleConns),
+		durationSetting("DB_CONN_MAX_LIFETIME", "max database connection lifetime", &c.Database.ConnMaxLifetime),
+		durationSetting("DB_CONN_MAX_IDLE_TIME", "max database connection idle time", &c.Database.ConnMaxIdleTime),
+		listSetting("DB_REPLICAS", "comma-separated host:port of read replicas", &c.Database.Replicas),
+		durationSetting("DB_REPLICA_WAIT", "how long a read with a consistency token waits for a replica", &c.Database.ReplicaWait),
+
+		intSetting("RETRY_MAX_ATTEMPTS", "max attempts per operation", &c.Retry.MaxAttempts),
+		durationSetting("RETRY_BASE_DELAY", "base retry backoff", &c.Retry.BaseDelay),
+		durationSetting("RETRY_MAX_DELAY", "max retry backoff", &c.Retry.MaxDelay),
+
+		boolSetting("FEATURE_METRICS", "expose /debug/vars", &c.Features.Metrics),
+
+		decimalSetting("LIMIT_MAX_OPERATION_AMOUNT", "default max amount of a single operation, 0 - unlimited", &c.Limits.MaxOperationAmount),
+		decimalSetting("LIMIT_DAILY_WITHDRAWAL_AMOUNT", "default daily withdrawal total, 0 - unlimited", &c.Limits.DailyWithdrawalAmount),
+		intSetting("LIMIT_DAILY_WITHDRAWAL_COUNT", "default daily withdrawal count, 0 - unlimited", &c.Limits.DailyWithdrawalCount),
+		decimalSetting("LIMIT_MONTHLY_WITHDRAWAL_AMOUNT", "default monthly withdrawal total, 0 - unlimited", &c.Limits.MonthlyWithdrawalAmount),
+		intSetting("LIMIT_MONTHLY_WITHDRAWAL_COUNT", "default monthly withdrawal count, 0 - unlimited", &c.Limits.MonthlyWithdrawalCount),
+
+		stringSetting("ADMIN_TOKEN", "X-Admin-Token of the admin operator", &c.Admin.Token),
+		stringSetting("ADMIN_TOKEN_FILE", "file with admin token", &c.Admin.TokenFile),
+
+		boolSetting("SCHEDULER_ENABLED", "run scheduled operations on this instance", &c.Scheduler.Enabled),
+		durationSetting("SCHEDULER_INTERVAL", "how often to poll for due scheduled operations", &c.Scheduler.Interval),
+		intSetting("SCHEDULER_BATCH_SIZE", "max scheduled operations claimed per transaction", &c.Scheduler.BatchSize),
+
+		stringSetting("FEES_WALLET_ID", "wallet credited with fees, empty - fees disabled", &c.Fees.WalletID),
+
+		boolSetting("SNAPSHOTS_ENABLED", "take balance snapshots on this instance", &c.Snapshots.Enabled),
+		durationSetting("SNAPSHOTS_INTERVAL", "how often to snapshot account balances", &c.Snapshots.Interval),
+		durationSetting("SNAPSHOTS_LAG", "how far snapshots lag behind now, must exceed the longest transaction", &c.Snapshots.Lag),
+
+		boolSetting("AUDIT_ENABLED", "write the audit log of state-changing requests", &c.Audit.Enabled),
+		boolSetting("AUDIT_TRUST_PROXY", "take the client address from X-Forwarded-For", &c.Audit.TrustProxy),
+
+		stringSetting("CHAIN_SIGNING_KEY", "base64 Ed25519 key signing chain checkpoints, empty - checkpoints disabled", &c.Chain.SigningKey),
+		stringSetting("CHAIN_SIGNING_KEY_FILE", "file with chain signing key", &c.Chain.SigningKeyFile),
+		durationSetting("CHAIN_CHECKPOINT_INTERVAL", "how often to publish a signed chain checkpoint", &c.Chain.CheckpointInterval),
+
+		boolSetting("EVENTS_ENABLED", "serve balance event streams on this instance", &c.Events.Enabled),
+		durationSetting("EVENTS_HEARTBEAT_INTERVAL", "heartbeat period of balance event streams", &c.Events.HeartbeatInterval),
+
+		boolSetting("CACHE_ENABLED", "cache wallets in memory for balance reads", &c.Cache.Enabled),
+		intSetting("CACHE_SIZE", "max wallets in the balance cache", &c.Cache.Size),
+		durationSetting("CACHE_TTL", "max age of a cached wallet", &c.Cache.TTL),
+
+		listSetting("CURRENCIES", "comma-separated wallet currencies, the first is the default", &c.Currencies),
+	}
+}
+
+func stringSetting(env, usage string, target *string) setting {
+	return setting{env: env, usage: usage, set: func(value string) error {
+		*target = value
+		return nil
+	}}
+}
+
+func intSetting(env, usage string, target *int) setting {
+	return setting{env: env, usage: usage, set: func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		*target = n
+		return nil
+	}}
+}
+
+func durationSetting(env, usage string, target *time.Duration) setting {
+	return setting{env: env, usage: usage, set: func(value string) error {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", value)
+		}
+		*target = d
+		return nil
+	}}
+}
+
+func decimalSetting(env, usage string, target *decimal.Decimal) setting {
+	return setting{env: env, usage: usage, set: func(value string) error {
+		d, err := decimal.NewFromString(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a decimal number", value)
+		}
+		*target = d
+		return nil
+	}}
+}
+
+// listSetting разбирает список через запятую; пробелы вокруг элементов отбрасываются
+func listSetting(env, usage string, target *[]string) setting {
+	return setting{env: env, usage: usage, set: func(value string) error {
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*target = list
+		return nil
+	}}
+}
+
+func boolSetting(env, usage string, target *bool) setting {
+	return setting{env: env, usage: usage, set: func(value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		*target = b
+		return nil
+	}}
+}
+
+// loadFile читает YAML или JSON (JSON - подмножество YAML). Неизвестные ключи - ошибка,
+// чтобы опечатка в конфиге не превращалась молча в значение по умолчанию
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func applyEnv(settings []setting) []string {
+	var problems []string
+	for _, s := range settings {
+		value, ok := os.LookupEnv(s.env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := s.set(value); err != nil {
+			problems = append(problems, fmt.Sprintf("env %s: %v", s.env, err))
+		}
+	}
+	return problems
+}
+
+// newFlagSet регистрирует флаги только для разбора и справки; значения
+// применяются позже в applyFlags, чтобы соблюсти приоритет источников
+func newFlagSet(settings []setting) *flag.FlagSet {
+	fs := flag.NewFlagSet("wallet-service", flag.ContinueOnError)
+	for _, s := range settings {
+		fs.String(s.flagName(), "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	return fs
+}
+
+func applyFlags(fs *flag.FlagSet, settings []setting) []string {
+	byName := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byName[s.flagName()] = s
+	}
+
+	var problems []string
+	fs.Visit(func(f *flag.Flag) {
+		s, ok := byName[f.Name]
+		if !ok {
+			return
+		}
+		if err := s.set(f.Value.String()); err != nil {
+			problems = append(problems, fmt.Sprintf("flag -%s: %v", f.Name, err))
+		}
+	})
+	return problems
+}
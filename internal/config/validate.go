@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ValidationError собирает все проблемы конфигурации, чтобы их можно было
+// исправить за один перезапуск, а не по одной
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Стратегии блокировок из repository.ParseLockStrategy
+var lockStrategies = []string{"pessimistic", "optimistic", "serializable", "advisory"}
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// tenantIDPattern - id тенанта помещается в колонки tenant_id VARCHAR(64)
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// minAPIKeyLength - более короткий ключ слишком легко подобрать
+const minAPIKeyLength = 16
+
+// SlogLevel переводит LogLevel в уровень log/slog
+func (c *Config) SlogLevel() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+func (c *Config) validate() []string {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(validPort(c.Port), "port: %q is not a valid TCP port", c.Port)
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.LogLevel)) == nil, "logLevel: %q must be one of debug, info, warn, error", c.LogLevel)
+
+	checkPositive := func(name string, d time.Duration) {
+		check(d > 0, "%s must be positive, got %v", name, d)
+	}
+	checkPositive("http.readTimeout", c.HTTP.ReadTimeout)
+	checkPositive("http.writeTimeout", c.HTTP.WriteTimeout)
+	checkPositive("http.idleTimeout", c.HTTP.IdleTimeout)
+	checkPositive("http.shutdownTimeout", c.HTTP.ShutdownTimeout)
+	check(c.HTTP.ShutdownDrainDelay >= 0, "http.shutdownDrainDelay must not be negative, got %v", c.HTTP.ShutdownDrainDelay)
+
+	db := c.Database
+	check(db.Host != "", "database.host is required")
+	check(validPort(db.Port), "database.port: %q is not a valid TCP port", db.Port)
+	check(db.User != "", "database.user is required")
+	check(db.Name != "", "database.name is required")
+	check(oneOf(db.SSLMode, sslModes), "database.sslMode: %q must be one of %s", db.SSLMode, strings.Join(sslModes, ", "))
+	check(oneOf(db.LockStrategy, lockStrategies), "database.lockStrategy: %q must be one of %s", db.LockStrategy, strings.Join(lockStrategies, ", "))
+	check(db.MaxOpenConns >= 1, "database.maxOpenConns must be at least 1, got %d", db.MaxOpenConns)
+	check(db.MaxIdleConns >= 0 && db.MaxIdleConns <= db.MaxOpenConns,
+		"database.maxIdleConns must be between 0 and maxOpenConns (%d), got %d", db.MaxOpenConns, db.MaxIdleConns)
+	checkPositive("database.connMaxLifetime", db.ConnMaxLifetime)
+	checkPositive("database.connMaxIdleTime", db.ConnMaxIdleTime)
+	for _, addr := range db.Replicas {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			host, port = addr, db.Port
+		}
+		check(host != "" && validPort(port), "database.replicas: %q is not a valid host:port", addr)
+	}
+	check(db.ReplicaWait >= 0, "database.replicaWait must not be negative, got %v", db.ReplicaWait)
+
+	check(c.Retry.MaxAttempts >= 1, "retry.maxAttempts must be at least 1, got %d", c.Retry.MaxAttempts)
+	check(c.Retry.BaseDelay >= 0, "retry.baseDelay must not be negative, got %v", c.Retry.BaseDelay)
+	check(c.Retry.MaxDelay >= c.Retry.BaseDelay, "retry.maxDelay (%v) must not be less than retry.baseDelay (%v)", c.Retry.MaxDelay, c.Retry.BaseDelay)
+
+	checkLimits := func(name string, l LimitsConfig) {
+		check(!l.MaxOperationAmount.IsNegative(), "%s.maxOperationAmount must not be negative, got %s", name, l.MaxOperationAmount)
+		check(!l.DailyWithdrawalAmount.IsNegative(), "%s.dailyWithdrawalAmount must not be negative, got %s", name, l.DailyWithdrawalAmount)
+		check(l.DailyWithdrawalCount >= 0, "%s.dailyWithdrawalCount must not be negative, got %d", name, l.DailyWithdrawalCount)
+		check(!l.MonthlyWithdrawalAmount.IsNegative(), "%s.monthlyWithdrawalAmount must not be negative, got %s", name, l.MonthlyWithdrawalAmount)
+		check(l.MonthlyWithdrawalCount >= 0, "%s.monthlyWithdrawalCount must not be negative, got %d", name, l.MonthlyWithdrawalCount)
+		check(model.ValidAmount(l.MaxOperationAmount), "%s.maxOperationAmount %s, got %s", name, model.AmountPrecisionMessage, l.MaxOperationAmount)
+		check(model.ValidAmount(l.DailyWithdrawalAmount), "%s.dailyWithdrawalAmount %s, got %s", name, model.AmountPrecisionMessage, l.DailyWithdrawalAmount)
+		check(model.ValidAmount(l.MonthlyWithdrawalAmount), "%s.monthlyWithdrawalAmount %s, got %s", name, model.AmountPrecisionMessage, l.MonthlyWithdrawalAmount)
+	}
+	checkLimits("limits", c.Limits)
+
+	checkPositive("scheduler.interval", c.Scheduler.Interval)
+	check(c.Scheduler.BatchSize >= 1, "scheduler.batchSize must be at least 1, got %d", c.Scheduler.BatchSize)
+
+	checkPositive("snapshots.interval", c.Snapshots.Interval)
+	checkPositive("snapshots.lag", c.Snapshots.Lag)
+
+	if _, err := c.Admin.TokenHashes(); err != nil {
+		check(false, "admin.operators: %v", err)
+	}
+
+	checkPositive("chain.checkpointInterval", c.Chain.CheckpointInterval)
+	if _, err := c.Chain.PrivateKey(); err != nil {
+		check(false, "chain.signingKey %v", err)
+	}
+
+	checkPositive("events.heartbeatInterval", c.Events.HeartbeatInterval)
+
+	check(c.Cache.Size >= 1, "cache.size must be at least 1, got %d", c.Cache.Size)
+	checkPositive("cache.ttl", c.Cache.TTL)
+
+	checkCurrencies := func(name string, currencies []string) {
+		seen := make(map[string]bool, len(currencies))
+		for _, currency := range currencies {
+			check(model.ValidCurrencyCode(currency), "%s: %q must be a three-letter ISO 4217 code", name, currency)
+			check(!seen[currency], "%s: %q is listed twice", name, currency)
+			seen[currency] = true
+		}
+	}
+	check(len(c.Currencies) > 0, "currencies must list at least one currency")
+	checkCurrencies("currencies", c.Currencies)
+
+	// Кошелек комиссий один на тенанта, а комиссия зачисляется в валюте кошелька операции
+	feeWallets := make(map[string]string)
+	checkFees := func(name string, f FeesConfig, currencies []string) {
+		if f.WalletID != "" {
+			_, err := uuid.Parse(f.WalletID)
+			check(err == nil, "%s.walletId: %q must be a valid UUID", name, f.WalletID)
+			check(len(currencies) == 1, "%s.walletId requires exactly one currency, fees are credited in the wallet currency", name)
+			if owner, ok := feeWallets[strings.ToLower(f.WalletID)]; ok {
+				check(false, "%s.walletId is already the fee wallet of %s", name, owner)
+			}
+			feeWallets[strings.ToLower(f.WalletID)] = name
+		}
+		check(f.WalletID != "" || len(f.Rules) == 0, "%s.walletId is required when %s.rules are set", name, name)
+		for i, r := range f.Rules {
+			name := fmt.Sprintf("%s.rules[%d]", name, i)
+			check(r.OperationType == string(model.OperationTypeDeposit) || r.OperationType == string(model.OperationTypeWithdraw),
+				"%s.operationType: %q must be DEPOSIT or WITHDRAW", name, r.OperationType)
+			check(!r.Fixed.IsNegative(), "%s.fixed must not be negative, got %s", name, r.Fixed)
+			check(!r.Percent.IsNegative() && r.Percent.LessThanOrEqual(decimal.NewFromInt(100)),
+				"%s.percent must be between 0 and 100, got %s", name, r.Percent)
+			check(r.Min == nil || !r.Min.IsNegative(), "%s.min must not be negative", name)
+			check(r.Min == nil || r.Max == nil || r.Min.LessThanOrEqual(*r.Max), "%s.min must not exceed max", name)
+			check(model.ValidAmount(r.Fixed), "%s.fixed %s, got %s", name, model.AmountPrecisionMessage, r.Fixed)
+			check(r.Min == nil || model.ValidAmount(*r.Min), "%s.min %s", name, model.AmountPrecisionMessage)
+			check(r.Max == nil || model.ValidAmount(*r.Max), "%s.max %s", name, model.AmountPrecisionMessage)
+		}
+	}
+
+	tenantIDs := make(map[string]bool, len(c.Tenants))
+	apiKeys := make(map[string]string)
+	defaultFees := true
+	for i, t := range c.Tenants {
+		name := fmt.Sprintf("tenants[%d]", i)
+		check(tenantIDPattern.MatchString(t.ID), "%s.id: %q must be 1-64 characters of a-z, 0-9, _ and -", name, t.ID)
+		check(!tenantIDs[t.ID], "%s.id: %q is listed twice", name, t.ID)
+		tenantIDs[t.ID] = true
+
+		for _, key := range t.APIKeys {
+			check(len(key) >= minAPIKeyLength, "%s.apiKeys: keys must be at least %d characters", name, minAPIKeyLength)
+			if owner, ok := apiKeys[key]; ok {
+				check(false, "%s.apiKeys: key is already used by %s", name, owner)
+			}
+			apiKeys[key] = name
+		}
+
+		currencies := c.Currencies
+		if len(t.Currencies) > 0 {
+			currencies = t.Currencies
+			checkCurrencies(name+".currencies", t.Currencies)
+		}
+		if t.Limits != nil {
+			checkLimits(name+".limits", *t.Limits)
+		}
+		if t.Fees != nil {
+			checkFees(name+".fees", *t.Fees, currencies)
+		}
+		if t.ID == tenant.DefaultID && t.Fees != nil {
+			defaultFees = false
+		}
+	}
+	// Глобальная сетка действует только у тенанта default
+	if defaultFees {
+		currencies := c.Currencies
+		for _, t := range c.Tenants {
+			if t.ID == tenant.DefaultID && len(t.Currencies) > 0 {
+				currencies = t.Currencies
+			}
+		}
+		checkFees("fees", c.Fees, currencies)
+	}
+
+	return problems
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"sync/atomic"
+	"time"
+
+	"wallet-service/internal/metrics"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// replicaPollInterval - как часто чтение с токеном проверяет, догнала ли реплика запись
+const replicaPollInterval = 5 * time.Millisecond
+
+// Cluster - пул основного сервера и пулы реплик для чтения. Кошельки работают
+// с пулами pgx напрямую, остальные репозитории и миграции - через DB
+type Cluster struct {
+	Primary  *pgxpool.Pool
+	Replicas []*pgxpool.Pool
+	// DB - database/sql поверх Primary: соединения общие с пулом
+	DB *sql.DB
+
+	replicaAddrs []string
+	replicaWait  time.Duration
+	next         atomic.Uint64
+}
+
+// Close закрывает все пулы
+func (c *Cluster) Close() {
+	if c.DB != nil {
+		c.DB.Close()
+	}
+	c.Primary.Close()
+	for _, replica := range c.Replicas {
+		replica.Close()
+	}
+}
+
+// PoolStats - снимок пула соединений
+type PoolStats struct {
+	Total    int32 `json:"total"`
+	Acquired int32 `json:"acquired"`
+	Idle     int32 `json:"idle"`
+	Max      int32 `json:"max"`
+	// EmptyAcquires - сколько раз запрос ждал соединения, потому что свободных не было
+	EmptyAcquires    int64 `json:"emptyAcquires"`
+	CanceledAcquires int64 `json:"canceledAcquires"`
+	Acquires         int64 `json:"acquires"`
+	// AcquireMs - суммарное время получения соединений
+	AcquireMs int64 `json:"acquireMs"`
+	NewConns  int64 `json:"newConns"`
+}
+
+func poolStats(pool *pgxpool.Pool) PoolStats {
+	stat := pool.Stat()
+	return PoolStats{
+		Total:            stat.TotalConns(),
+		Acquired:         stat.AcquiredConns(),
+		Idle:             stat.IdleConns(),
+		Max:              stat.MaxConns(),
+		EmptyAcquires:    stat.EmptyAcquireCount(),
+		CanceledAcquires: stat.CanceledAcquireCount(),
+		Acquires:         stat.AcquireCount(),
+		AcquireMs:        stat.AcquireDuration().Milliseconds(),
+		NewConns:         stat.NewConnsCount(),
+	}
+}
+
+// PrimaryStats - пул основного сервера
+func (c *Cluster) PrimaryStats() PoolStats {
+	return poolStats(c.Primary)
+}
+
+// Stats - пулы основного сервера ("primary") и реплик (по адресу)
+func (c *Cluster) Stats() map[string]PoolStats {
+	stats := map[string]PoolStats{"primary": poolStats(c.Primary)}
+	for i, replica := range c.Replicas {
+		stats[c.replicaAddrs[i]] = poolStats(replica)
+	}
+	return stats
+}
+
+// CommitToken - токен согласованности: позиция записи WAL основного сервера. Коммит
+// подтверждается после сброса его записи WAL, поэтому позиция не меньше LSN коммита
+// любой подтвержденной транзакции, и реплика, воспроизведшая WAL до токена, видит все
+// записи, о которых клиент уже получил ответ. Позиция вставки для этого не нужна:
+// она может указывать в еще не записанный WAL, до которого реплике придется ждать
+func (c *Cluster) CommitToken(ctx context.Context) (string, error) {
+	var lsn string
+	err := c.Primary.QueryRow(ctx, `SELECT pg_current_wal_lsn()::text`).Scan(&lsn)
+	return lsn, err
+}
+
+// Replica выбирает реплику для чтения по кругу. Если в ctx есть токен согласованности,
+// ждет до replicaWait, пока реплика воспроизведет WAL до него. nil - читать с основного
+// сервера: реплик нет, выбранная отстает или не отвечает
+func (c *Cluster) Replica(ctx context.Context) *pgxpool.Pool {
+	if len(c.Replicas) == 0 {
+		return nil
+	}
+	replica := c.Replicas[c.next.Add(1)%uint64(len(c.Replicas))]
+
+	token := ConsistencyToken(ctx)
+	if token == "" {
+		return replica
+	}
+	deadline := time.Now().Add(c.replicaWait)
+	for {
+		var caughtUp sql.NullBool
+		// NULL - сервер не в режиме реплики, ждать бессмысленно
+		err := replica.QueryRow(ctx, `SELECT pg_last_wal_replay_lsn() >= $1::pg_lsn`, token).Scan(&caughtUp)
+		if err != nil || !caughtUp.Valid {
+			break
+		}
+		if caughtUp.Bool {
+			return replica
+		}
+		if !time.Now().Add(replicaPollInterval).Before(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(replicaPollInterval):
+		}
+	}
+	metrics.ReplicaFallbacks.Add(1)
+	return nil
+}
+
+// tokenPattern - текстовая форма pg_lsn
+var tokenPattern = regexp.MustCompile(`^[0-9A-Fa-f]{1,8}/[0-9A-Fa-f]{1,8}$`)
+
+// ValidConsistencyToken проверяет, что токен от клиента - позиция WAL
+func ValidConsistencyToken(token string) bool {
+	return tokenPattern.MatchString(token)
+}
+
+type tokenKey struct{}
+
+// WithConsistencyToken - чтения с этим контекстом должны увидеть все записи до токена
+func WithConsistencyToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// ConsistencyToken - токен согласованности запроса или пустая строка
+func ConsistencyToken(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey{}).(string)
+	return token
+}
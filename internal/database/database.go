@@ -1,48 +1,208 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
-	"time"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"wallet-service/internal/config"
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
-func Connect(cfg *config.Config) (*sql.DB, error) {
-	db, err := sql.Open("postgres", cfg.Database.ConnectionString())
+// Connect открывает пулы pgx основного сервера и реплик из cfg.Database.Replicas.
+// Недоступная при старте реплика не мешает запуску: чтения с нее уходят на основной
+// сервер, пока пул не восстановит соединение
+func Connect(cfg *config.Config) (*Cluster, error) {
+	ctx := context.Background()
+
+	primary, err := open(ctx, cfg.Database, cfg.Database.ConnectionString())
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
+	}
+	if err := primary.Ping(ctx); err != nil {
+		primary.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	cluster := &Cluster{
+		Primary:     primary,
+		DB:          stdlib.OpenDBFromPool(primary),
+		replicaWait: cfg.Database.ReplicaWait,
+	}
+	for _, addr := range cfg.Database.Replicas {
+		replica, err := open(ctx, cfg.Database, cfg.Database.ReplicaConnectionString(addr))
+		if err != nil {
+			cluster.Close()
+			return nil, fmt.Errorf("replica %s: %w", addr, err)
+		}
+		if err := replica.Ping(ctx); err != nil {
+			log.Printf("Replica %s is unavailable, reads fall back to primary: %v", addr, err)
+		}
+		cluster.Replicas = append(cluster.Replicas, replica)
+		cluster.replicaAddrs = append(cluster.replicaAddrs, addr)
+	}
+
+	log.Printf("Successfully connected to database with connection pool, %d replica(s)", len(cluster.Replicas))
+	return cluster, nil
+}
+
+func open(ctx context.Context, cfg config.DatabaseConfig, connString string) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
 	}
 
 	// Настраиваем connection pool
-	db.SetMaxOpenConns(25)                 // Максимум соединений
-	db.SetMaxIdleConns(10)                 // Максимум idle соединений  
-	db.SetConnMaxLifetime(5 * time.Minute) // Максимальное время жизни соединения
-	db.SetConnMaxIdleTime(2 * time.Minute) // Максимальное время idle
+	poolConfig.MaxConns = int32(cfg.MaxOpenConns)    // Максимум соединений
+	poolConfig.MinConns = int32(cfg.MaxIdleConns)    // Сколько соединений держать открытыми
+	poolConfig.MaxConnLifetime = cfg.ConnMaxLifetime // Максимальное время жизни соединения
+	poolConfig.MaxConnIdleTime = cfg.ConnMaxIdleTime // Максимальное время idle (сверх MinConns)
+	// Горячие запросы репозитории выполняют именованными prepared statements.
+	// Остальные не готовятся на сервере: иначе динамические запросы списков
+	// вытесняли бы друг друга из кеша prepared statements соединения
+	poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheDescribe
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	return pool, nil
+}
+
+// MigrationsDir - каталог с миграциями вида 001_description.sql
+const MigrationsDir = "migrations"
 
-	log.Println("Successfully connected to database with connection pool")
-	return db, nil
+// migrationLockKey - ключ advisory-блокировки, чтобы реплики не накатывали миграции одновременно
+const migrationLockKey = 7265346
+
+type migration struct {
+	version int
+	path    string
 }
 
+// RunMigrations применяет еще не накатанные миграции по порядку номеров.
+// Примененные версии хранятся в schema_migrations
 func RunMigrations(db *sql.DB) error {
-	// Читаем миграцию из файла
-	migrationSQL, err := os.ReadFile("migrations/001_create_wallets.sql")
+	ctx := context.Background()
+
+	migrations, err := listMigrations()
 	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
+		return err
 	}
 
-	_, err = db.Exec(string(migrationSQL))
+	conn, err := db.Conn(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	err = conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current)
+	if err != nil {
+		return fmt.Errorf("failed to get migration version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return err
+		}
+		log.Printf("Applied migration %s", filepath.Base(m.path))
 	}
-	
+
 	log.Println("Migrations completed successfully")
 	return nil
-}
\ No newline at end of file
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	migrationSQL, err := os.ReadFile(m.path)
+	if err != nil {
+		return fmt.Errorf("failed to read migration file: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(migrationSQL)); err != nil {
+		return fmt.Errorf("failed to run migration %s: %w", filepath.Base(m.path), err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.version); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	return tx.Commit()
+}
+
+// LatestMigrationVersion возвращает номер последней миграции на диске -
+// версию схемы, которую ожидает этот бинарник
+func LatestMigrationVersion() (int, error) {
+	migrations, err := listMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].version, nil
+}
+
+// MigrationVersion возвращает последнюю примененную к базе миграцию
+func MigrationVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	return version, nil
+}
+
+func listMigrations() ([]migration, error) {
+	paths, err := filepath.Glob(filepath.Join(MigrationsDir, "*.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	migrations := make([]migration, 0, len(paths))
+	for _, path := range paths {
+		prefix, _, ok := strings.Cut(filepath.Base(path), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %s", path)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file name %s", path)
+		}
+		migrations = append(migrations, migration{version: version, path: path})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
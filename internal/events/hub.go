@@ -0,0 +1,152 @@
+// Package events доставляет изменения кошельков подписчикам. Репозиторий в транзакции
+// изменения шлет NOTIFY, Postgres доставляет его после коммита всем репликам,
+// а Listen каждой реплики раздает событие подписчикам и наблюдателям своего Hub
+package events
+
+import (
+	"sync"
+
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+)
+
+// Channel - канал LISTEN/NOTIFY событий балансов
+const Channel = "wallet_events"
+
+// Notification - полезная нагрузка NOTIFY
+type Notification struct {
+	TenantID string             `json:"tenant"`
+	Event    model.BalanceEvent `json:"event"`
+}
+
+// SubscriptionBuffer - сколько событий подписка держит, пока их не прочитали.
+// Переполнение означает, что клиент не успевает: подписка закрывается, и клиент
+// переподключается с последнего полученного события
+const SubscriptionBuffer = 64
+
+type topic struct {
+	tenantID string
+	walletID uuid.UUID
+}
+
+// Observer получает события всех кошельков реплики
+type Observer interface {
+	Notify(tenantID string, event model.BalanceEvent)
+	// Reset - часть событий могла потеряться
+	Reset()
+}
+
+// Hub раздает события подписчикам кошельков на этой реплике
+type Hub struct {
+	mu        sync.Mutex
+	subs      map[topic]map[*Subscription]struct{}
+	observers []Observer
+	closed    bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[topic]map[*Subscription]struct{})}
+}
+
+// Subscription - события одного кошелька. Канал Events закрывается при Close,
+// переполнении буфера, потере соединения с Postgres и остановке Hub
+type Subscription struct {
+	hub    *Hub
+	topic  topic
+	events chan model.BalanceEvent
+	once   sync.Once
+}
+
+func (s *Subscription) Events() <-chan model.BalanceEvent {
+	return s.events
+}
+
+// Close отписывает подписчика; повторный вызов ничего не делает
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Observe добавляет наблюдателя. Наблюдатели получают событие раньше подписчиков
+// и не должны блокироваться
+func (h *Hub) Observe(o Observer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.observers = append(h.observers, o)
+}
+
+// Subscribe подписывает на события кошелька тенанта. После Close хаба
+// возвращает уже закрытую подписку
+func (h *Hub) Subscribe(tenantID string, walletID uuid.UUID) *Subscription {
+	sub := &Subscription{
+		hub:    h,
+		topic:  topic{tenantID: tenantID, walletID: walletID},
+		events: make(chan model.BalanceEvent, SubscriptionBuffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		sub.once.Do(func() { close(sub.events) })
+		return sub
+	}
+	if h.subs[sub.topic] == nil {
+		h.subs[sub.topic] = make(map[*Subscription]struct{})
+	}
+	h.subs[sub.topic][sub] = struct{}{}
+	return sub
+}
+
+// Publish отдает событие подписчикам кошелька, не блокируясь на медленных
+func (h *Hub) Publish(tenantID string, event model.BalanceEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, o := range h.observers {
+		o.Notify(tenantID, event)
+	}
+	for sub := range h.subs[topic{tenantID: tenantID, walletID: event.WalletID}] {
+		select {
+		case sub.events <- event:
+		default:
+			h.remove(sub)
+		}
+	}
+}
+
+// Reset закрывает все подписки: события за время потери соединения с Postgres
+// не пришли, и клиенты должны дочитать их при переподключении
+func (h *Hub) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, o := range h.observers {
+		o.Reset()
+	}
+	for _, subs := range h.subs {
+		for sub := range subs {
+			h.remove(sub)
+		}
+	}
+}
+
+// Close закрывает все подписки и запрещает новые - потоки завершаются
+// до остановки HTTP-сервера
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+	h.Reset()
+}
+
+// remove вызывается под h.mu
+func (h *Hub) remove(sub *Subscription) {
+	subs := h.subs[sub.topic]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subs, sub.topic)
+	}
+	sub.once.Do(func() { close(sub.events) })
+}
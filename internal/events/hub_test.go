@@ -0,0 +1,93 @@
+package events
+
+import (
+	"testing"
+
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_PublishByTenantAndWallet(t *testing.T) {
+	hub := NewHub()
+	walletID := uuid.New()
+
+	sub := hub.Subscribe("acme", walletID)
+	defer sub.Close()
+	other := hub.Subscribe("globex", walletID)
+	defer other.Close()
+
+	hub.Publish("acme", model.BalanceEvent{WalletID: walletID, Version: 2})
+	hub.Publish("acme", model.BalanceEvent{WalletID: uuid.New(), Version: 7})
+
+	require.Len(t, sub.Events(), 1)
+	assert.Equal(t, 2, (<-sub.Events()).Version)
+	// Событие кошелька с тем же id у другого тенанта не доставляется
+	assert.Len(t, other.Events(), 0)
+}
+
+func TestHub_SlowSubscriberIsDropped(t *testing.T) {
+	hub := NewHub()
+	walletID := uuid.New()
+	sub := hub.Subscribe("acme", walletID)
+
+	for i := 1; i <= SubscriptionBuffer+1; i++ {
+		hub.Publish("acme", model.BalanceEvent{WalletID: walletID, Version: i})
+	}
+
+	received := 0
+	for range sub.Events() {
+		received++
+	}
+	assert.Equal(t, SubscriptionBuffer, received)
+	sub.Close()
+}
+
+func TestHub_ResetAndClose(t *testing.T) {
+	hub := NewHub()
+	walletID := uuid.New()
+
+	sub := hub.Subscribe("acme", walletID)
+	hub.Reset()
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+
+	// После Reset подписываться можно, после Close - нет
+	sub = hub.Subscribe("acme", walletID)
+	hub.Close()
+	_, ok = <-sub.Events()
+	assert.False(t, ok)
+
+	sub = hub.Subscribe("acme", walletID)
+	_, ok = <-sub.Events()
+	assert.False(t, ok)
+	sub.Close()
+}
+
+type recordingObserver struct {
+	events []model.BalanceEvent
+	resets int
+}
+
+func (o *recordingObserver) Notify(tenantID string, event model.BalanceEvent) {
+	o.events = append(o.events, event)
+}
+
+func (o *recordingObserver) Reset() {
+	o.resets++
+}
+
+func TestHub_Observers(t *testing.T) {
+	hub := NewHub()
+	observer := &recordingObserver{}
+	hub.Observe(observer)
+
+	// Наблюдатель получает события всех кошельков, даже без подписчиков
+	hub.Publish("acme", model.BalanceEvent{WalletID: uuid.New(), Version: 1})
+	hub.Publish("globex", model.BalanceEvent{WalletID: uuid.New(), Version: 3})
+	hub.Reset()
+
+	assert.Len(t, observer.events, 2)
+	assert.Equal(t, 1, observer.resets)
+}
@@ -0,0 +1,99 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	// pingInterval - проверка соединения, если уведомлений давно не было
+	pingInterval = 90 * time.Second
+)
+
+// Listen слушает канал событий в Postgres и публикует их в hub, пока не отменен ctx.
+// Соединение отдельное от пула и восстанавливается само; после восстановления
+// подписки сбрасываются (Hub.Reset)
+func Listen(ctx context.Context, connString string, hub *Hub) error {
+	config, err := pgx.ParseConfig(connString)
+	if err != nil {
+		return err
+	}
+
+	// Первое соединение должно получиться: иначе ошибку конфигурации видно сразу
+	conn, err := listen(ctx, config)
+	if err != nil {
+		return err
+	}
+	backoff := minReconnectInterval
+	for {
+		err := receive(ctx, conn, hub)
+		conn.Close(context.Background())
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Printf("events: lost connection: %v", err)
+
+		for conn = nil; conn == nil; {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
+			}
+			if conn, err = listen(ctx, config); err != nil {
+				log.Printf("events: failed to connect: %v", err)
+				backoff = min(2*backoff, maxReconnectInterval)
+			}
+		}
+		log.Println("events: reconnected")
+		backoff = minReconnectInterval
+		// Уведомления за время без соединения потерялись
+		hub.Reset()
+	}
+}
+
+func listen(ctx context.Context, config *pgx.ConnConfig) (*pgx.Conn, error) {
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{Channel}.Sanitize()); err != nil {
+		conn.Close(context.Background())
+		return nil, err
+	}
+	return conn, nil
+}
+
+// receive публикует уведомления, пока соединение живо
+func receive(ctx context.Context, conn *pgx.Conn, hub *Hub) error {
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, pingInterval)
+		n, err := conn.WaitForNotification(waitCtx)
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			// Таймаут ожидания соединение не закрывает - проверяем, живо ли оно
+			if err := conn.Ping(ctx); err != nil {
+				return err
+			}
+			continue
+		case err != nil:
+			return err
+		}
+
+		var notification Notification
+		if err := json.Unmarshal([]byte(n.Payload), &notification); err != nil {
+			log.Printf("events: malformed notification %q: %v", n.Payload, err)
+			continue
+		}
+		hub.Publish(notification.TenantID, notification.Event)
+	}
+}
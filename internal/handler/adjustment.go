@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maxCommentLength ограничивает то, что попадет в историю корректировок
+const maxCommentLength = 1000
+
+// ProposeAdjustment создает корректировку, которую должен одобрить другой оператор
+func (h *AdminHandler) ProposeAdjustment(w http.ResponseWriter, r *http.Request) {
+	// Кто предложил и кто одобрил корректировку, различаем по токенам операторов
+	operator := operatorFromContext(r.Context())
+
+	var req model.AdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, r, apperror.ErrMalformedRequest.WithDetail("request body is not valid JSON"))
+		return
+	}
+
+	if err := validateAdjustmentRequest(req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	adj, err := h.adjustments.Propose(r.Context(), operator, req)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/admin/adjustments/"+adj.ID.String())
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(adj)
+}
+
+// ListAdjustments - GET /api/v1/admin/adjustments?status=PENDING&walletId=...
+func (h *AdminHandler) ListAdjustments(w http.ResponseWriter, r *http.Request) {
+	var filter model.AdjustmentFilter
+	var fields []apperror.FieldError
+
+	if s := r.URL.Query().Get("walletId"); s != "" {
+		walletID, err := uuid.Parse(s)
+		if err != nil {
+			fields = append(fields, apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"})
+		}
+		filter.WalletID = &walletID
+	}
+
+	switch status := model.AdjustmentStatus(r.URL.Query().Get("status")); status {
+	case "", model.AdjustmentPending, model.AdjustmentApproved, model.AdjustmentRejected:
+		filter.Status = status
+	default:
+		fields = append(fields, apperror.FieldError{Field: "status", Message: "must be PENDING, APPROVED or REJECTED"})
+	}
+
+	if len(fields) > 0 {
+		respondWithProblem(w, r, apperror.Validation(fields...))
+		return
+	}
+
+	adjustments, err := h.adjustments.List(r.Context(), filter)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, adjustments)
+}
+
+func (h *AdminHandler) GetAdjustment(w http.ResponseWriter, r *http.Request) {
+	id, ok := adjustmentID(w, r)
+	if !ok {
+		return
+	}
+
+	adj, err := h.adjustments.Get(r.Context(), id)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, adj)
+}
+
+// ApproveAdjustment применяет корректировку к кошельку. Оператор должен отличаться от автора
+func (h *AdminHandler) ApproveAdjustment(w http.ResponseWriter, r *http.Request) {
+	review, ok := adjustmentReview(w, r)
+	if !ok {
+		return
+	}
+
+	adj, err := h.adjustments.Approve(r.Context(), review)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, adj)
+}
+
+// RejectAdjustment отклоняет корректировку; причина отказа обязательна
+func (h *AdminHandler) RejectAdjustment(w http.ResponseWriter, r *http.Request) {
+	review, ok := adjustmentReview(w, r)
+	if !ok {
+		return
+	}
+	if review.Comment == "" {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "comment", Message: "is required"}))
+		return
+	}
+
+	adj, err := h.adjustments.Reject(r.Context(), review)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, adj)
+}
+
+func adjustmentID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["adjustmentId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "adjustmentId", Message: "must be a valid UUID"}))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// adjustmentReview разбирает решение по корректировке: id, оператор и необязательное тело с комментарием
+func adjustmentReview(w http.ResponseWriter, r *http.Request) (model.AdjustmentReview, bool) {
+	id, ok := adjustmentID(w, r)
+	if !ok {
+		return model.AdjustmentReview{}, false
+	}
+	operator := operatorFromContext(r.Context())
+
+	var req model.AdjustmentReviewRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithProblem(w, r, apperror.ErrMalformedRequest.WithDetail("request body is not valid JSON"))
+			return model.AdjustmentReview{}, false
+		}
+	}
+	if len(req.Comment) > maxCommentLength {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "comment", Message: "must be at most 1000 characters"}))
+		return model.AdjustmentReview{}, false
+	}
+
+	return model.AdjustmentReview{AdjustmentID: id, Operator: operator, Comment: req.Comment}, true
+}
+
+func validateAdjustmentRequest(req model.AdjustmentRequest) error {
+	var fields []apperror.FieldError
+
+	if req.WalletID == uuid.Nil {
+		fields = append(fields, apperror.FieldError{Field: "walletId", Message: "is required"})
+	}
+
+	if req.Direction != model.AdjustmentCredit && req.Direction != model.AdjustmentDebit {
+		fields = append(fields, apperror.FieldError{Field: "direction", Message: "must be CREDIT or DEBIT"})
+	}
+
+	if !req.Amount.IsPositive() {
+		fields = append(fields, apperror.FieldError{Field: "amount", Message: "must be positive"})
+	} else if !model.ValidAmount(req.Amount) {
+		fields = append(fields, apperror.FieldError{Field: "amount", Message: model.AmountPrecisionMessage})
+	}
+
+	if !model.AdjustmentReasons[req.ReasonCode] {
+		fields = append(fields, apperror.FieldError{Field: "reasonCode", Message: "must be a known reason code"})
+	}
+
+	switch {
+	case req.Comment == "":
+		fields = append(fields, apperror.FieldError{Field: "comment", Message: "is required"})
+	case len(req.Comment) > maxCommentLength:
+		fields = append(fields, apperror.FieldError{Field: "comment", Message: "must be at most 1000 characters"})
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockAdjustmentService хранит корректировки в памяти и, как репозиторий,
+// не дает автору одобрить свою корректировку
+type MockAdjustmentService struct {
+	adjustments map[uuid.UUID]model.Adjustment
+}
+
+func newMockAdjustmentService() *MockAdjustmentService {
+	return &MockAdjustmentService{adjustments: map[uuid.UUID]model.Adjustment{}}
+}
+
+func (m *MockAdjustmentService) Propose(ctx context.Context, operator string, req model.AdjustmentRequest) (model.Adjustment, error) {
+	adj := model.Adjustment{
+		ID:         uuid.New(),
+		WalletID:   req.WalletID,
+		Direction:  req.Direction,
+		Amount:     req.Amount,
+		ReasonCode: req.ReasonCode,
+		Comment:    req.Comment,
+		Status:     model.AdjustmentPending,
+		ProposedBy: operator,
+	}
+	m.adjustments[adj.ID] = adj
+	return adj, nil
+}
+
+func (m *MockAdjustmentService) Get(ctx context.Context, id uuid.UUID) (model.Adjustment, error) {
+	adj, ok := m.adjustments[id]
+	if !ok {
+		return model.Adjustment{}, apperror.ErrAdjustmentNotFound
+	}
+	return adj, nil
+}
+
+func (m *MockAdjustmentService) List(ctx context.Context, filter model.AdjustmentFilter) ([]model.Adjustment, error) {
+	adjustments := []model.Adjustment{}
+	for _, adj := range m.adjustments {
+		if filter.Status == "" || adj.Status == filter.Status {
+			adjustments = append(adjustments, adj)
+		}
+	}
+	return adjustments, nil
+}
+
+func (m *MockAdjustmentService) Approve(ctx context.Context, review model.AdjustmentReview) (model.Adjustment, error) {
+	adj, err := m.Get(ctx, review.AdjustmentID)
+	if err != nil {
+		return model.Adjustment{}, err
+	}
+	if adj.ProposedBy == review.Operator {
+		return model.Adjustment{}, apperror.ErrSelfApproval
+	}
+	return m.review(adj, review, model.AdjustmentApproved)
+}
+
+func (m *MockAdjustmentService) Reject(ctx context.Context, review model.AdjustmentReview) (model.Adjustment, error) {
+	adj, err := m.Get(ctx, review.AdjustmentID)
+	if err != nil {
+		return model.Adjustment{}, err
+	}
+	return m.review(adj, review, model.AdjustmentRejected)
+}
+
+func (m *MockAdjustmentService) review(adj model.Adjustment, review model.AdjustmentReview, status model.AdjustmentStatus) (model.Adjustment, error) {
+	if adj.Status != model.AdjustmentPending {
+		return model.Adjustment{}, apperror.ErrAdjustmentNotPending
+	}
+	adj.Status = status
+	adj.ReviewedBy = review.Operator
+	adj.ReviewComment = review.Comment
+	m.adjustments[adj.ID] = adj
+	return adj, nil
+}
+
+// adminRequest - запрос с токеном оператора; пустой оператор - admin
+func adminRequest(method, path, operator string, body []byte) *http.Request {
+	if operator == "" {
+		operator = "admin"
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set(AdminTokenHeader, testOperatorTokens[operator])
+	return req
+}
+
+func TestAdminHandler_AdjustmentMakerChecker(t *testing.T) {
+	router := newAdminRouter(uuid.New())
+
+	body, _ := json.Marshal(model.AdjustmentRequest{
+		WalletID:   uuid.New(),
+		Direction:  model.AdjustmentCredit,
+		Amount:     decimal.NewFromInt(250),
+		ReasonCode: "ERROR_CORRECTION",
+		Comment:    "deposit lost during migration",
+	})
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("POST", "/api/v1/admin/adjustments", "alice", body))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var adj model.Adjustment
+	json.Unmarshal(rr.Body.Bytes(), &adj)
+	assert.Equal(t, model.AdjustmentPending, adj.Status)
+	assert.Equal(t, "alice", adj.ProposedBy)
+	path := "/api/v1/admin/adjustments/" + adj.ID.String()
+	assert.Equal(t, path, rr.Header().Get("Location"))
+
+	// Автор не может одобрить свою корректировку
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("POST", path+"/approve", "alice", nil))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("POST", path+"/approve", "bob", []byte(`{"comment":"checked against bank statement"}`)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	json.Unmarshal(rr.Body.Bytes(), &adj)
+	assert.Equal(t, model.AdjustmentApproved, adj.Status)
+	assert.Equal(t, "bob", adj.ReviewedBy)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("POST", path+"/reject", "carol", []byte(`{"comment":"too late"}`)))
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("GET", "/api/v1/admin/adjustments?status=APPROVED", "", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list []model.Adjustment
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Len(t, list, 1)
+}
+
+func TestAdminHandler_AdjustmentOperatorFromToken(t *testing.T) {
+	router := newAdminRouter(uuid.New())
+
+	body, _ := json.Marshal(model.AdjustmentRequest{
+		WalletID:   uuid.New(),
+		Direction:  model.AdjustmentDebit,
+		Amount:     decimal.NewFromInt(10),
+		ReasonCode: "ERROR_CORRECTION",
+		Comment:    "duplicate deposit",
+	})
+	// Заголовок с именем другого оператора ничего не меняет: оператора определяет токен
+	req := adminRequest("POST", "/api/v1/admin/adjustments", "alice", body)
+	req.Header.Set("X-Operator-ID", "bob")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var adj model.Adjustment
+	json.Unmarshal(rr.Body.Bytes(), &adj)
+	assert.Equal(t, "alice", adj.ProposedBy)
+
+	req = adminRequest("POST", "/api/v1/admin/adjustments/"+adj.ID.String()+"/approve", "alice", nil)
+	req.Header.Set("X-Operator-ID", "bob")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestAdminHandler_AdjustmentValidation(t *testing.T) {
+	router := newAdminRouter(uuid.New())
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("POST", "/api/v1/admin/adjustments", "alice",
+		[]byte(`{"direction":"SIDEWAYS","amount":"-5","reasonCode":"BECAUSE"}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var problem apperror.Problem
+	json.Unmarshal(rr.Body.Bytes(), &problem)
+	assert.Len(t, problem.Errors, 5)
+
+	// Отказ без причины
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("POST", "/api/v1/admin/adjustments/"+uuid.NewString()+"/reject", "bob", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("GET", "/api/v1/admin/adjustments?status=DONE", "", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/model"
+	"wallet-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// AdminTokenHeader - заголовок с токеном админского API
+const AdminTokenHeader = "X-Admin-Token"
+
+// maxTierLength совпадает с размером колонки wallets.tier
+const maxTierLength = 32
+
+// AdminHandler обслуживает /api/v1/admin. Все запросы требуют X-Admin-Token
+// оператора: по токену определяется, кто выполняет запрос
+type AdminHandler struct {
+	// operators - id оператора по SHA-256 его токена: сами токены в памяти не держим
+	operators   map[[sha256.Size]byte]string
+	limits      service.LimitsServiceInterface
+	ledger      service.LedgerServiceInterface
+	adjustments service.AdjustmentServiceInterface
+}
+
+func NewAdminHandler(operators map[[sha256.Size]byte]string, limits service.LimitsServiceInterface, ledger service.LedgerServiceInterface,
+	adjustments service.AdjustmentServiceInterface) *AdminHandler {
+	return &AdminHandler{
+		operators:   operators,
+		limits:      limits,
+		ledger:      ledger,
+		adjustments: adjustments,
+	}
+}
+
+type operatorKey struct{}
+
+// authenticate - middleware проверки токена. Оператор определяется по токену и
+// попадает в контекст и журнал аудита. Токены ищутся по хешу, поэтому время
+// ответа не зависит от того, сколько символов токена угадано
+func (h *AdminHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(AdminTokenHeader)
+		operator, ok := h.operators[sha256.Sum256([]byte(token))]
+		if token == "" || !ok {
+			respondWithProblem(w, r, apperror.ErrUnauthorized.WithDetail("valid %s header is required", AdminTokenHeader))
+			return
+		}
+		audit.SetActor(r.Context(), operator)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), operatorKey{}, operator)))
+	})
+}
+
+// operatorFromContext - оператор, токеном которого аутентифицирован запрос
+func operatorFromContext(ctx context.Context) string {
+	operator, _ := ctx.Value(operatorKey{}).(string)
+	return operator
+}
+
+func (h *AdminHandler) GetLimits(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	limits, err := h.limits.GetLimits(r.Context(), walletID)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, limits)
+}
+
+// SetLimits заменяет лимиты кошелька. Поле null или отсутствующее поле - глобальный лимит
+func (h *AdminHandler) SetLimits(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	var req model.Limits
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, r, apperror.ErrMalformedRequest.WithDetail("request body is not valid JSON"))
+		return
+	}
+
+	if err := validateLimits(req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	limits, err := h.limits.SetLimits(r.Context(), walletID, req)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, limits)
+}
+
+// SetCreditLimit задает овердрафт кошелька: баланс может уходить в минус до -creditLimit
+func (h *AdminHandler) SetCreditLimit(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	var req model.CreditLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, r, apperror.ErrMalformedRequest.WithDetail("request body is not valid JSON"))
+		return
+	}
+	if req.CreditLimit.IsNegative() {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "creditLimit", Message: "must not be negative"}))
+		return
+	}
+	if !model.ValidAmount(req.CreditLimit) {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "creditLimit", Message: model.AmountPrecisionMessage}))
+		return
+	}
+
+	wallet, err := h.limits.SetCreditLimit(r.Context(), walletID, req.CreditLimit)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(wallet.Version))
+	respondWithJSON(w, balanceResponse(wallet))
+}
+
+// SetTier назначает тариф кошелька. Тариф - произвольная метка, по которой
+// тарифная сетка выбирает правило комиссии
+func (h *AdminHandler) SetTier(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	var req model.TierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, r, apperror.ErrMalformedRequest.WithDetail("request body is not valid JSON"))
+		return
+	}
+	if req.Tier == "" || len(req.Tier) > maxTierLength {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "tier", Message: "must be 1 to 32 characters"}))
+		return
+	}
+
+	wallet, err := h.limits.SetTier(r.Context(), walletID, req.Tier)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(wallet.Version))
+	respondWithJSON(w, balanceResponse(wallet))
+}
+
+// SetStatus замораживает (FROZEN) или размораживает (ACTIVE) кошелек
+func (h *AdminHandler) SetStatus(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	var req model.StatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, r, apperror.ErrMalformedRequest.WithDetail("request body is not valid JSON"))
+		return
+	}
+	if req.Status != model.WalletActive && req.Status != model.WalletFrozen {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "status", Message: "must be ACTIVE or FROZEN"}))
+		return
+	}
+
+	wallet, err := h.limits.SetStatus(r.Context(), walletID, req.Status)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(wallet.Version))
+	respondWithJSON(w, balanceResponse(wallet))
+}
+
+// GetEntry возвращает проводку; id проводки операции совпадает с id операции
+func (h *AdminHandler) GetEntry(w http.ResponseWriter, r *http.Request) {
+	entryID, err := uuid.Parse(mux.Vars(r)["entryId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "entryId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	entry, err := h.ledger.GetEntry(r.Context(), entryID)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, entry)
+}
+
+func (h *AdminHandler) TrialBalance(w http.ResponseWriter, r *http.Request) {
+	report, err := h.ledger.TrialBalance(r.Context())
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, report)
+}
+
+func validateLimits(l model.Limits) error {
+	var fields []apperror.FieldError
+	checkAmount := func(name string, amount *decimal.Decimal) {
+		if amount != nil && amount.IsNegative() {
+			fields = append(fields, apperror.FieldError{Field: name, Message: "must not be negative"})
+		} else if amount != nil && !model.ValidAmount(*amount) {
+			fields = append(fields, apperror.FieldError{Field: name, Message: model.AmountPrecisionMessage})
+		}
+	}
+	checkCount := func(name string, count *int) {
+		if count != nil && *count < 0 {
+			fields = append(fields, apperror.FieldError{Field: name, Message: "must not be negative"})
+		}
+	}
+
+	checkAmount("maxOperationAmount", l.MaxOperationAmount)
+	checkAmount("dailyWithdrawalAmount", l.DailyWithdrawalAmount)
+	checkCount("dailyWithdrawalCount", l.DailyWithdrawalCount)
+	checkAmount("monthlyWithdrawalAmount", l.MonthlyWithdrawalAmount)
+	checkCount("monthlyWithdrawalCount", l.MonthlyWithdrawalCount)
+
+	if len(fields) > 0 {
+		return apperror.Validation(fields...)
+	}
+	return nil
+}
+
+func respondWithJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockLimitsService struct {
+	limits map[uuid.UUID]model.Limits
+}
+
+func (m *MockLimitsService) GetLimits(ctx context.Context, walletID uuid.UUID) (model.WalletLimits, error) {
+	overrides, ok := m.limits[walletID]
+	if !ok {
+		return model.WalletLimits{}, apperror.ErrWalletNotFound
+	}
+	return model.WalletLimits{WalletID: walletID, Effective: overrides, Overrides: overrides}, nil
+}
+
+func (m *MockLimitsService) SetLimits(ctx context.Context, walletID uuid.UUID, limits model.Limits) (model.WalletLimits, error) {
+	if _, ok := m.limits[walletID]; !ok {
+		return model.WalletLimits{}, apperror.ErrWalletNotFound
+	}
+	m.limits[walletID] = limits
+	return m.GetLimits(ctx, walletID)
+}
+
+// Все кошельки мока должны 300: лимит меньше 300 нарушает wallets_balance_check
+func (m *MockLimitsService) SetCreditLimit(ctx context.Context, walletID uuid.UUID, limit decimal.Decimal) (model.Wallet, error) {
+	if _, ok := m.limits[walletID]; !ok {
+		return model.Wallet{}, apperror.ErrWalletNotFound
+	}
+	if limit.LessThan(decimal.NewFromInt(300)) {
+		return model.Wallet{}, apperror.ErrCreditLimitBelowDebt
+	}
+	return model.Wallet{ID: walletID, Balance: decimal.NewFromInt(-300), CreditLimit: limit, Version: 7}, nil
+}
+
+func (m *MockLimitsService) SetTier(ctx context.Context, walletID uuid.UUID, tier string) (model.Wallet, error) {
+	if _, ok := m.limits[walletID]; !ok {
+		return model.Wallet{}, apperror.ErrWalletNotFound
+	}
+	return model.Wallet{ID: walletID, Tier: tier, Version: 8}, nil
+}
+
+func (m *MockLimitsService) SetStatus(ctx context.Context, walletID uuid.UUID, status model.WalletStatus) (model.Wallet, error) {
+	if _, ok := m.limits[walletID]; !ok {
+		return model.Wallet{}, apperror.ErrWalletNotFound
+	}
+	return model.Wallet{ID: walletID, Status: status, Version: 9}, nil
+}
+
+// MockLedgerService - кошелек на 100 после пополнения, кошелек комиссий на 1.
+// balances - балансы на любой момент времени
+type MockLedgerService struct {
+	balances map[uuid.UUID]decimal.Decimal
+}
+
+func (m *MockLedgerService) GetEntry(ctx context.Context, id uuid.UUID) (model.JournalEntry, error) {
+	return model.JournalEntry{}, apperror.ErrEntryNotFound
+}
+
+func (m *MockLedgerService) TrialBalance(ctx context.Context) (model.TrialBalance, error) {
+	walletBalance := decimal.NewFromInt(100)
+	return model.TrialBalance{
+		Accounts: []model.AccountBalance{
+			{Account: model.AccountCashIn, Balance: decimal.NewFromInt(-101)},
+			{Account: model.WalletAccount(uuid.New()), Balance: walletBalance, WalletBalance: &walletBalance},
+			{Account: model.WalletAccount(uuid.New()), Balance: decimal.NewFromInt(1)},
+		},
+		Balanced:   true,
+		Mismatches: []model.AccountBalance{},
+	}, nil
+}
+
+func (m *MockLedgerService) BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (decimal.Decimal, error) {
+	balance, ok := m.balances[walletID]
+	if !ok {
+		return decimal.Zero, apperror.ErrWalletNotFound
+	}
+	return balance, nil
+}
+
+func (m *MockLedgerService) BalancesAsOf(ctx context.Context, walletIDs []uuid.UUID, asOf time.Time) (map[uuid.UUID]decimal.Decimal, error) {
+	balances := make(map[uuid.UUID]decimal.Decimal)
+	for _, id := range walletIDs {
+		if balance, ok := m.balances[id]; ok {
+			balances[id] = balance
+		}
+	}
+	return balances, nil
+}
+
+// testOperatorTokens - токены операторов тестового админского API
+var testOperatorTokens = map[string]string{
+	"admin": "s3cret",
+	"alice": "alice-s3cret",
+	"bob":   "bob-s3cret",
+	"carol": "carol-s3cret",
+}
+
+func testOperators() map[[sha256.Size]byte]string {
+	operators := make(map[[sha256.Size]byte]string, len(testOperatorTokens))
+	for operator, token := range testOperatorTokens {
+		operators[sha256.Sum256([]byte(token))] = operator
+	}
+	return operators
+}
+
+func newAdminRouter(walletID uuid.UUID) http.Handler {
+	limits := &MockLimitsService{limits: map[uuid.UUID]model.Limits{walletID: {}}}
+	return NewRouter(&MockWalletService{}, nil, RouterOptions{Admin: NewAdminHandler(testOperators(), limits, &MockLedgerService{}, newMockAdjustmentService())})
+}
+
+func TestAdminHandler_RequiresToken(t *testing.T) {
+	walletID := uuid.New()
+	router := newAdminRouter(walletID)
+
+	for _, token := range []string{"", "wrong"} {
+		req := httptest.NewRequest("GET", "/api/v1/admin/wallets/"+walletID.String()+"/limits", nil)
+		if token != "" {
+			req.Header.Set(AdminTokenHeader, token)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		var problem apperror.Problem
+		json.Unmarshal(rr.Body.Bytes(), &problem)
+		assert.Equal(t, apperror.CodeUnauthorized, problem.Code)
+	}
+}
+
+func TestAdminHandler_SetAndGetLimits(t *testing.T) {
+	walletID := uuid.New()
+	router := newAdminRouter(walletID)
+	path := "/api/v1/admin/wallets/" + walletID.String() + "/limits"
+
+	req := httptest.NewRequest("PUT", path, bytes.NewReader([]byte(`{"dailyWithdrawalAmount":"1500.00","dailyWithdrawalCount":5}`)))
+	req.Header.Set(AdminTokenHeader, "s3cret")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	req = httptest.NewRequest("GET", path, nil)
+	req.Header.Set(AdminTokenHeader, "s3cret")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var limits model.WalletLimits
+	json.Unmarshal(rr.Body.Bytes(), &limits)
+	assert.True(t, decimal.NewFromInt(1500).Equal(*limits.Overrides.DailyWithdrawalAmount))
+	assert.Equal(t, 5, *limits.Overrides.DailyWithdrawalCount)
+	assert.Nil(t, limits.Overrides.MaxOperationAmount)
+}
+
+func TestAdminHandler_SetLimits_Validation(t *testing.T) {
+	walletID := uuid.New()
+	router := newAdminRouter(walletID)
+
+	req := httptest.NewRequest("PUT", "/api/v1/admin/wallets/"+walletID.String()+"/limits",
+		bytes.NewReader([]byte(`{"maxOperationAmount":"-1","monthlyWithdrawalCount":-2}`)))
+	req.Header.Set(AdminTokenHeader, "s3cret")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var problem apperror.Problem
+	json.Unmarshal(rr.Body.Bytes(), &problem)
+	assert.Len(t, problem.Errors, 2)
+}
+
+func TestAdminHandler_DisabledWithoutToken(t *testing.T) {
+	router := NewRouter(&MockWalletService{}, nil, RouterOptions{})
+
+	req := httptest.NewRequest("GET", "/api/v1/admin/wallets/"+uuid.NewString()+"/limits", nil)
+	req.Header.Set(AdminTokenHeader, "s3cret")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestAdminHandler_SetCreditLimit(t *testing.T) {
+	walletID := uuid.New()
+	router := newAdminRouter(walletID)
+	path := "/api/v1/admin/wallets/" + walletID.String() + "/credit-limit"
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"covers debt", `{"creditLimit":"1000"}`, http.StatusOK},
+		{"below debt", `{"creditLimit":"100"}`, http.StatusUnprocessableEntity},
+		{"negative", `{"creditLimit":"-1"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", path, bytes.NewReader([]byte(tt.body)))
+			req.Header.Set(AdminTokenHeader, "s3cret")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Code)
+		})
+	}
+
+	req := httptest.NewRequest("PUT", path, bytes.NewReader([]byte(`{"creditLimit":"1000"}`)))
+	req.Header.Set(AdminTokenHeader, "s3cret")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var balance model.BalanceResponse
+	json.Unmarshal(rr.Body.Bytes(), &balance)
+	assert.Equal(t, `"7"`, rr.Header().Get("ETag"))
+	assert.True(t, decimal.NewFromInt(700).Equal(balance.Available))
+}
+
+func TestAdminHandler_SetStatus(t *testing.T) {
+	walletID := uuid.New()
+	router := newAdminRouter(walletID)
+	path := "/api/v1/admin/wallets/" + walletID.String() + "/status"
+
+	for body, expected := range map[string]int{
+		`{"status":"FROZEN"}`: http.StatusOK,
+		`{"status":"ACTIVE"}`: http.StatusOK,
+		`{"status":"CLOSED"}`: http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("PUT", path, bytes.NewReader([]byte(body)))
+		req.Header.Set(AdminTokenHeader, "s3cret")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, expected, rr.Code, body)
+	}
+}
+
+func TestAdminHandler_Ledger(t *testing.T) {
+	router := newAdminRouter(uuid.New())
+
+	req := httptest.NewRequest("GET", "/api/v1/admin/ledger/trial-balance", nil)
+	req.Header.Set(AdminTokenHeader, "s3cret")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report model.TrialBalance
+	json.Unmarshal(rr.Body.Bytes(), &report)
+	assert.True(t, report.Balanced)
+	assert.Len(t, report.Accounts, 3)
+
+	req = httptest.NewRequest("GET", "/api/v1/admin/ledger/entries/"+uuid.NewString(), nil)
+	req.Header.Set(AdminTokenHeader, "s3cret")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/model"
+	"wallet-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// RequestIDHeader - id запроса. Клиентский id сохраняется, иначе генерируется новый;
+// в ответе он есть всегда, по нему запрос находится в журнале аудита
+const RequestIDHeader = "X-Request-ID"
+
+// anonymousActor - актор запроса, который не прошел аутентификацию
+const anonymousActor = "anonymous"
+
+const (
+	maxRequestIDLength = 128
+	// maxAuditBody - больше этого тело в сводку не попадает
+	maxAuditBody = 64 << 10
+	// maxSummaryString - длиннее строки в сводке обрезаются
+	maxSummaryString = 128
+)
+
+// AuditHandler пишет журнал аудита изменяющих запросов и отдает его админскому API
+type AuditHandler struct {
+	audit service.AuditServiceInterface
+	// trustProxy - брать адрес клиента из X-Forwarded-For, который дописал балансировщик
+	// перед сервисом: последний адрес цепочки
+	trustProxy bool
+}
+
+func NewAuditHandler(audit service.AuditServiceInterface, trustProxy bool) *AuditHandler {
+	return &AuditHandler{
+		audit:      audit,
+		trustProxy: trustProxy,
+	}
+}
+
+// record - middleware аудита. Запись создается до обработчика, сервисы дополняют
+// ее через пакет audit, а пишется она после ответа: операция к этому моменту уже
+// закоммичена, поэтому ошибка записи аудита только логируется
+func (h *AuditHandler) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+
+		if !isMutation(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		started := time.Now()
+		rec := &model.AuditRecord{
+			RequestID: requestID,
+			// Актора уточняет аутентификация (audit.SetActor), заголовкам клиента не доверяем
+			Actor:     anonymousActor,
+			ClientIP:  h.clientIP(r),
+			Method:    r.Method,
+			Endpoint:  routeTemplate(r),
+			Path:      r.URL.Path,
+			Summary:   summarizeBody(r),
+		}
+		if walletID, err := uuid.Parse(mux.Vars(r)["walletId"]); err == nil {
+			rec.WalletID = &walletID
+		}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(audit.NewContext(r.Context(), rec)))
+
+		rec.Status = sw.status
+		rec.Outcome = model.AuditSuccess
+		if sw.status >= http.StatusBadRequest {
+			rec.Outcome = model.AuditFailure
+		}
+		rec.LatencyMs = float64(time.Since(started).Microseconds()) / 1000
+
+		// Запрос мог быть отменен клиентом, а запись аудита нужна все равно
+		if err := h.audit.Record(context.WithoutCancel(r.Context()), *rec); err != nil {
+			log.Printf("failed to write audit record for %s %s (%s): %v", r.Method, r.URL.Path, requestID, err)
+		}
+	})
+}
+
+// List - страница журнала: GET /api/v1/admin/audit?actor=&walletId=&endpoint=&outcome=&from=&to=&after=&limit=
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	page, err := h.audit.List(r.Context(), filter)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, page)
+}
+
+// Export отдает все подходящие записи в формате JSON Lines, по записи на строку
+func (h *AuditHandler) Export(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+	filter.Limit = service.MaxAuditPageSize
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.jsonl"`)
+	enc := json.NewEncoder(w)
+	written := false
+	err = h.audit.Export(r.Context(), filter, func(rec model.AuditRecord) error {
+		written = true
+		return enc.Encode(rec)
+	})
+	if err != nil {
+		// Заголовки уже отправлены - ответить ошибкой можно, только если ничего не записано
+		if !written {
+			respondWithProblem(w, r, err)
+			return
+		}
+		log.Printf("audit export interrupted: %v", err)
+	}
+}
+
+func parseAuditFilter(r *http.Request) (model.AuditFilter, error) {
+	q := r.URL.Query()
+	filter := model.AuditFilter{
+		Actor:    q.Get("actor"),
+		Endpoint: q.Get("endpoint"),
+	}
+	var fields []apperror.FieldError
+
+	if s := q.Get("walletId"); s != "" {
+		walletID, err := uuid.Parse(s)
+		if err != nil {
+			fields = append(fields, apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"})
+		}
+		filter.WalletID = &walletID
+	}
+
+	switch outcome := model.AuditOutcome(q.Get("outcome")); outcome {
+	case "", model.AuditSuccess, model.AuditFailure:
+		filter.Outcome = outcome
+	default:
+		fields = append(fields, apperror.FieldError{Field: "outcome", Message: "must be SUCCESS or FAILURE"})
+	}
+
+	parseTime := func(name string) *time.Time {
+		s := q.Get(name)
+		if s == "" {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			fields = append(fields, apperror.FieldError{Field: name, Message: "must be an RFC 3339 timestamp"})
+			return nil
+		}
+		return &t
+	}
+	filter.From = parseTime("from")
+	filter.To = parseTime("to")
+
+	parseInt := func(name string) int64 {
+		s := q.Get(name)
+		if s == "" {
+			return 0
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			fields = append(fields, apperror.FieldError{Field: name, Message: "must be a non-negative integer"})
+		}
+		return n
+	}
+	filter.AfterID = parseInt("after")
+	filter.Limit = int(parseInt("limit"))
+
+	if len(fields) > 0 {
+		return model.AuditFilter{}, apperror.Validation(fields...)
+	}
+	return filter, nil
+}
+
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (h *AuditHandler) clientIP(r *http.Request) string {
+	if h.trustProxy {
+		// Доверяем только своему балансировщику: он дописывает адрес, с которого к нему
+		// пришли, в конец цепочки. Все, что левее, мог прислать сам клиент
+		if client := lastForwarded(r.Header.Values("X-Forwarded-For")); client != "" {
+			return client
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// lastForwarded - последний адрес X-Forwarded-For с учетом повторов заголовка;
+// пустая строка, если там не IP-адрес
+func lastForwarded(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	list := values[len(values)-1]
+	last := strings.TrimSpace(list[strings.LastIndex(list, ",")+1:])
+	if net.ParseIP(last) == nil {
+		return ""
+	}
+	return last
+}
+
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
+
+// summarizeBody читает тело для сводки и возвращает его обработчику нетронутым.
+// В сводку попадают поля JSON-объекта верхнего уровня: длинные строки обрезаются,
+// вложенные объекты и массивы сворачиваются до размера
+func summarizeBody(r *http.Request) map[string]any {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBody+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+	if err != nil || len(data) > maxAuditBody {
+		return nil
+	}
+
+	var body map[string]any
+	if json.Unmarshal(data, &body) != nil {
+		return nil
+	}
+
+	summary := make(map[string]any, len(body))
+	for key, value := range body {
+		switch v := value.(type) {
+		case string:
+			if len(v) > maxSummaryString {
+				v = v[:maxSummaryString] + "..."
+			}
+			summary[key] = v
+		case []any:
+			summary[key] = "[" + strconv.Itoa(len(v)) + " items]"
+		case map[string]any:
+			summary[key] = "{" + strconv.Itoa(len(v)) + " fields}"
+		default:
+			summary[key] = v
+		}
+	}
+	return summary
+}
+
+// statusWriter запоминает код ответа для журнала аудита
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
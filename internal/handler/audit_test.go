@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockAuditService struct {
+	records []model.AuditRecord
+}
+
+func (m *MockAuditService) Record(ctx context.Context, rec model.AuditRecord) error {
+	rec.ID = int64(len(m.records) + 1)
+	m.records = append(m.records, rec)
+	return nil
+}
+
+func (m *MockAuditService) List(ctx context.Context, filter model.AuditFilter) (model.AuditPage, error) {
+	page := model.AuditPage{Records: []model.AuditRecord{}}
+	for _, rec := range m.records {
+		if rec.ID > filter.AfterID && (filter.Outcome == "" || rec.Outcome == filter.Outcome) {
+			page.Records = append(page.Records, rec)
+		}
+	}
+	return page, nil
+}
+
+func (m *MockAuditService) Export(ctx context.Context, filter model.AuditFilter, fn func(model.AuditRecord) error) error {
+	page, _ := m.List(ctx, filter)
+	for _, rec := range page.Records {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newAuditRouter(auditService *MockAuditService) http.Handler {
+	limits := &MockLimitsService{limits: map[uuid.UUID]model.Limits{}}
+	return NewRouter(&MockWalletService{}, nil, RouterOptions{
+		Admin: NewAdminHandler(testOperators(), limits, &MockLedgerService{}, newMockAdjustmentService()),
+		Audit: NewAuditHandler(auditService, false),
+	})
+}
+
+func TestAuditHandler_RecordsMutations(t *testing.T) {
+	auditService := &MockAuditService{}
+	router := newAuditRouter(auditService)
+
+	body := `{"walletId":"123e4567-e89b-12d3-a456-426614174000","operationType":"WITHDRAW","amount":"5000"}`
+	req := httptest.NewRequest("POST", "/api/v1/wallet", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "audit-test")
+	req.Header.Set(RequestIDHeader, "req-42")
+	// Заголовку клиента не доверяем
+	req.Header.Set("X-Operator-ID", "alice")
+	req.RemoteAddr = "10.0.0.7:51234"
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	// Тело дошло до обработчика нетронутым
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "req-42", rr.Header().Get(RequestIDHeader))
+
+	if assert.Len(t, auditService.records, 1) {
+		rec := auditService.records[0]
+		assert.Equal(t, "req-42", rec.RequestID)
+		assert.Equal(t, "anonymous", rec.Actor)
+		assert.Equal(t, "10.0.0.7", rec.ClientIP)
+		assert.Equal(t, "/api/v1/wallet", rec.Endpoint)
+		assert.Equal(t, http.StatusBadRequest, rec.Status)
+		assert.Equal(t, model.AuditFailure, rec.Outcome)
+		assert.Equal(t, string(apperror.CodeInsufficientFunds), rec.ErrorCode)
+		assert.Equal(t, "5000", rec.Summary["amount"])
+	}
+
+	// Чтения в журнал не попадают, но id запроса получают
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/api/v1/wallets/123e4567-e89b-12d3-a456-426614174000", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get(RequestIDHeader))
+	assert.Len(t, auditService.records, 1)
+}
+
+func TestAuditHandler_RecordsRejectedAdminRequests(t *testing.T) {
+	auditService := &MockAuditService{}
+	router := newAuditRouter(auditService)
+
+	walletID := uuid.New()
+	req := httptest.NewRequest("PUT", "/api/v1/admin/wallets/"+walletID.String()+"/tier", strings.NewReader(`{"tier":"premium"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	if assert.Len(t, auditService.records, 1) {
+		rec := auditService.records[0]
+		assert.Equal(t, "anonymous", rec.Actor)
+		assert.Equal(t, "/api/v1/admin/wallets/{walletId}/tier", rec.Endpoint)
+		assert.Equal(t, walletID, *rec.WalletID)
+		assert.Equal(t, string(apperror.CodeUnauthorized), rec.ErrorCode)
+	}
+}
+
+func TestAuditHandler_RecordsOperator(t *testing.T) {
+	auditService := &MockAuditService{}
+	router := newAuditRouter(auditService)
+
+	req := httptest.NewRequest("PUT", "/api/v1/admin/wallets/"+uuid.NewString()+"/tier", strings.NewReader(`{"tier":"premium"}`))
+	req.Header.Set(AdminTokenHeader, testOperatorTokens["bob"])
+	req.Header.Set("X-Operator-ID", "alice")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if assert.Len(t, auditService.records, 1) {
+		assert.Equal(t, "bob", auditService.records[0].Actor)
+	}
+}
+
+func TestAuditHandler_RecordsClientKey(t *testing.T) {
+	auditService := &MockAuditService{}
+	tenants := tenant.NewRegistry(model.Tenant{ID: "acme", APIKeys: []string{"acme-key-0123456789"}})
+	router := NewRouter(&MockWalletService{}, nil, RouterOptions{
+		Tenants: NewTenantHandler(tenants),
+		Audit:   NewAuditHandler(auditService, false),
+	})
+
+	body := `{"walletId":"123e4567-e89b-12d3-a456-426614174000","operationType":"DEPOSIT","amount":"10"}`
+	req := httptest.NewRequest("POST", "/api/v1/wallet", strings.NewReader(body))
+	req.Header.Set(APIKeyHeader, "acme-key-0123456789")
+	req.Header.Set("X-Operator-ID", "alice")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if assert.Len(t, auditService.records, 1) {
+		rec := auditService.records[0]
+		assert.Equal(t, "acme", rec.TenantID)
+		assert.Equal(t, "acme:"+tenant.KeyFingerprint("acme-key-0123456789"), rec.Actor)
+		assert.NotContains(t, rec.Actor, "acme-key")
+	}
+}
+
+func TestAuditHandler_ClientIPBehindProxy(t *testing.T) {
+	tests := []struct {
+		name      string
+		forwarded []string
+		want      string
+	}{
+		{"no header", nil, "10.0.0.1"},
+		{"added by the balancer", []string{"203.0.113.7"}, "203.0.113.7"},
+		// Клиент прислал свой X-Forwarded-For, балансировщик дописал настоящий адрес
+		{"forged by the client", []string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{"forged in a separate header", []string{"198.51.100.1", "203.0.113.7"}, "203.0.113.7"},
+		{"not an address", []string{"198.51.100.1, unknown"}, "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditService := &MockAuditService{}
+			router := NewRouter(&MockWalletService{}, nil, RouterOptions{Audit: NewAuditHandler(auditService, true)})
+
+			body := `{"walletId":"123e4567-e89b-12d3-a456-426614174000","operationType":"DEPOSIT","amount":"10"}`
+			req := httptest.NewRequest("POST", "/api/v1/wallet", strings.NewReader(body))
+			req.RemoteAddr = "10.0.0.1:40000"
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if assert.Len(t, auditService.records, 1) {
+				assert.Equal(t, tt.want, auditService.records[0].ClientIP)
+			}
+		})
+	}
+}
+
+func TestAuditHandler_Export(t *testing.T) {
+	auditService := &MockAuditService{}
+	router := newAuditRouter(auditService)
+	for _, outcome := range []model.AuditOutcome{model.AuditSuccess, model.AuditFailure, model.AuditSuccess} {
+		auditService.Record(context.Background(), model.AuditRecord{Actor: "alice", Outcome: outcome})
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/admin/audit/export?outcome=SUCCESS", nil)
+	req.Header.Set(AdminTokenHeader, "s3cret")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+	var ids []int64
+	scanner := bufio.NewScanner(bytes.NewReader(rr.Body.Bytes()))
+	for scanner.Scan() {
+		var rec model.AuditRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+		ids = append(ids, rec.ID)
+	}
+	assert.Equal(t, []int64{1, 3}, ids)
+
+	req = httptest.NewRequest("GET", "/api/v1/admin/audit?from=yesterday&limit=-1", nil)
+	req.Header.Set(AdminTokenHeader, "s3cret")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
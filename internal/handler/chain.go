@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// ChainHandler отдает админскому API проверку цепочек операций и контрольные точки
+type ChainHandler struct {
+	chain service.ChainServiceInterface
+}
+
+func NewChainHandler(chain service.ChainServiceInterface) *ChainHandler {
+	return &ChainHandler{
+		chain: chain,
+	}
+}
+
+// Verify проходит цепочку кошелька. Нарушенная цепочка - не ошибка запроса:
+// ответ 200 с valid=false и первым нарушенным звеном
+func (h *ChainHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	result, err := h.chain.Verify(r.Context(), walletID)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, result)
+}
+
+func (h *ChainHandler) LatestCheckpoint(w http.ResponseWriter, r *http.Request) {
+	checkpoint, err := h.chain.LatestCheckpoint(r.Context())
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, model.ChainCheckpointResponse{ChainCheckpoint: checkpoint, PublicKey: h.chain.PublicKey()})
+}
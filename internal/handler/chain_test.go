@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockChainService struct {
+	results    map[uuid.UUID]model.ChainVerification
+	checkpoint *model.ChainCheckpoint
+	publicKey  ed25519.PublicKey
+}
+
+func (m *MockChainService) Verify(ctx context.Context, walletID uuid.UUID) (model.ChainVerification, error) {
+	result, ok := m.results[walletID]
+	if !ok {
+		return model.ChainVerification{}, apperror.ErrWalletNotFound
+	}
+	return result, nil
+}
+
+func (m *MockChainService) LatestCheckpoint(ctx context.Context) (model.ChainCheckpoint, error) {
+	if m.checkpoint == nil {
+		return model.ChainCheckpoint{}, apperror.ErrCheckpointNotFound
+	}
+	return *m.checkpoint, nil
+}
+
+func (m *MockChainService) PublicKey() ed25519.PublicKey {
+	return m.publicKey
+}
+
+func newChainRouter(chain *MockChainService) http.Handler {
+	limits := &MockLimitsService{limits: map[uuid.UUID]model.Limits{}}
+	return NewRouter(&MockWalletService{}, nil, RouterOptions{
+		Admin: NewAdminHandler(testOperators(), limits, &MockLedgerService{}, newMockAdjustmentService()),
+		Chain: NewChainHandler(chain),
+	})
+}
+
+func TestChainHandler_Verify(t *testing.T) {
+	walletID := uuid.New()
+	operationID := uuid.New()
+	chain := &MockChainService{results: map[uuid.UUID]model.ChainVerification{
+		walletID: {
+			WalletID: walletID,
+			Checked:  1,
+			BrokenAt: &model.ChainBreak{Seq: 2, OperationID: &operationID, Reason: "hash does not match the operation"},
+		},
+	}}
+	router := newChainRouter(chain)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("GET", "/api/v1/admin/chain/wallets/"+walletID.String()+"/verify", "", nil))
+
+	// Нарушенная цепочка - результат проверки, а не ошибка запроса
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var result model.ChainVerification
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(2), result.BrokenAt.Seq)
+	assert.Equal(t, operationID, *result.BrokenAt.OperationID)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("GET", "/api/v1/admin/chain/wallets/"+uuid.NewString()+"/verify", "", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("GET", "/api/v1/admin/chain/wallets/not-a-uuid/verify", "", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestChainHandler_LatestCheckpoint(t *testing.T) {
+	public, _, _ := ed25519.GenerateKey(nil)
+	chain := &MockChainService{publicKey: public}
+	router := newChainRouter(chain)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("GET", "/api/v1/admin/chain/checkpoints/latest", "", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), string(apperror.CodeCheckpointNotFound))
+
+	chain.checkpoint = &model.ChainCheckpoint{
+		ID:         7,
+		PrevDigest: model.GenesisHash,
+		Digest:     model.Hash{0xab, 0xcd},
+		Signature:  []byte{1, 2, 3},
+		Heads:      []model.ChainHead{},
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest("GET", "/api/v1/admin/chain/checkpoints/latest", "", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response model.ChainCheckpointResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, int64(7), response.ID)
+	assert.Equal(t, model.Hash{0xab, 0xcd}, response.Digest)
+	assert.Equal(t, []byte(public), response.PublicKey)
+	assert.Contains(t, rr.Body.String(), `"digest":"abcd"`)
+}
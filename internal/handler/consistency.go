@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/cache"
+	"wallet-service/internal/database"
+)
+
+// ConsistencyTokenHeader - токен согласованности: позиция WAL основного сервера после
+// изменения. Его возвращает успешный изменяющий запрос, а чтение с этим заголовком
+// видит изменение, даже если балансы читаются с реплик
+const ConsistencyTokenHeader = "X-Consistency-Token"
+
+// ConsistencyTokens выдает токен согласованности для уже закоммиченных изменений
+type ConsistencyTokens interface {
+	CommitToken(ctx context.Context) (string, error)
+}
+
+// ConsistencyHandler выдает токены в ответах на изменения и передает токен
+// чтения в репозиторий, который выбирает реплику
+type ConsistencyHandler struct {
+	tokens ConsistencyTokens
+}
+
+func NewConsistencyHandler(tokens ConsistencyTokens) *ConsistencyHandler {
+	return &ConsistencyHandler{tokens: tokens}
+}
+
+// middleware - изменяющим запросам добавляет токен в успешный ответ, читающим
+// кладет токен из заголовка в контекст
+func (h *ConsistencyHandler) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isMutation(r.Method) {
+			next.ServeHTTP(&tokenWriter{ResponseWriter: w, ctx: r.Context(), tokens: h.tokens}, r)
+			return
+		}
+
+		token := r.Header.Get(ConsistencyTokenHeader)
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !database.ValidConsistencyToken(token) {
+			respondWithProblem(w, r, apperror.Validation(apperror.FieldError{
+				Field:   ConsistencyTokenHeader,
+				Message: "must be a token from a write response",
+			}))
+			return
+		}
+		// Кеш не знает позиций WAL и может хранить версию старше токена
+		ctx := cache.WithoutCache(database.WithConsistencyToken(r.Context(), token))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// tokenWriter берет токен при записи заголовков ответа: к этому моменту изменение
+// уже закоммичено. Без токена ответ все равно отдается - чтение клиента тогда
+// может уйти на отставшую реплику
+type tokenWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	tokens      ConsistencyTokens
+	wroteHeader bool
+}
+
+func (w *tokenWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status < http.StatusBadRequest {
+			if token, err := w.tokens.CommitToken(w.ctx); err != nil {
+				log.Printf("failed to get consistency token: %v", err)
+			} else {
+				w.Header().Set(ConsistencyTokenHeader, token)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *tokenWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *tokenWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
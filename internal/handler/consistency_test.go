@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-service/internal/cache"
+	"wallet-service/internal/database"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+type staticTokens struct {
+	token string
+	err   error
+}
+
+func (s staticTokens) CommitToken(ctx context.Context) (string, error) {
+	return s.token, s.err
+}
+
+// tokenWalletService запоминает токен согласованности запроса
+type tokenWalletService struct {
+	MockWalletService
+	token    string
+	bypassed bool
+}
+
+func (m *tokenWalletService) GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error) {
+	m.token, m.bypassed = database.ConsistencyToken(ctx), cache.Bypassed(ctx)
+	return m.MockWalletService.GetWallet(ctx, id)
+}
+
+func operationRequest(walletID string, amount int64) *http.Request {
+	body, _ := json.Marshal(model.WalletOperationRequest{
+		WalletID:      uuid.MustParse(walletID),
+		OperationType: model.OperationTypeWithdraw,
+		Amount:        decimal.NewFromInt(amount),
+	})
+	return httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+}
+
+func TestConsistencyHandler_WriteReturnsToken(t *testing.T) {
+	router := NewRouter(&MockWalletService{}, nil, RouterOptions{Consistency: NewConsistencyHandler(staticTokens{token: "0/16B3748"})})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, operationRequest("123e4567-e89b-12d3-a456-426614174000", 100))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "0/16B3748", rr.Header().Get(ConsistencyTokenHeader))
+
+	// Неудачное изменение ничего не записало - токен не нужен
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, operationRequest("123e4567-e89b-12d3-a456-426614174000", 5000))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Empty(t, rr.Header().Get(ConsistencyTokenHeader))
+
+	// Без токена ответ все равно отдается
+	router = NewRouter(&MockWalletService{}, nil, RouterOptions{Consistency: NewConsistencyHandler(staticTokens{err: errors.New("primary is down")})})
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, operationRequest("123e4567-e89b-12d3-a456-426614174000", 100))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get(ConsistencyTokenHeader))
+}
+
+func TestConsistencyHandler_ReadWithToken(t *testing.T) {
+	service := &tokenWalletService{}
+	router := NewRouter(service, nil, RouterOptions{Consistency: NewConsistencyHandler(staticTokens{})})
+
+	req := httptest.NewRequest("GET", testWalletPath, nil)
+	req.Header.Set(ConsistencyTokenHeader, "1A/B374D848")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "1A/B374D848", service.token)
+	assert.True(t, service.bypassed)
+
+	req = httptest.NewRequest("GET", testWalletPath, nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, "", service.token)
+	assert.False(t, service.bypassed)
+
+	req = httptest.NewRequest("GET", testWalletPath, nil)
+	req.Header.Set(ConsistencyTokenHeader, "0/0; DROP TABLE wallets")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
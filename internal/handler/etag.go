@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// formatETag превращает версию кошелька в сильный ETag: "42"
+func formatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseETag разбирает одиночный ETag. Слабые теги (W/"42") допускаются
+// только если weakAllowed - для If-Match нужно строгое сравнение
+func parseETag(tag string, weakAllowed bool) (int, error) {
+	tag = strings.TrimSpace(tag)
+	if strings.HasPrefix(tag, "W/") {
+		if !weakAllowed {
+			return 0, fmt.Errorf("weak ETag is not allowed in If-Match")
+		}
+		tag = tag[2:]
+	}
+
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, fmt.Errorf("malformed ETag %q", tag)
+	}
+
+	version, err := strconv.Atoi(tag[1 : len(tag)-1])
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("malformed ETag %q", tag)
+	}
+	return version, nil
+}
+
+// errWeakIfMatch - слабый ETag в If-Match. If-Match сравнивается строго (RFC 9110),
+// поэтому слабый тег не совпадает ни с одной версией: это 412, а не ошибка запроса
+var errWeakIfMatch = errors.New("weak ETag never matches If-Match")
+
+// parseIfMatch возвращает ожидаемую клиентом версию кошелька.
+// "*" означает "любая версия существующего кошелька": version nil, anyVersion true
+func parseIfMatch(header string) (version *int, anyVersion bool, err error) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return nil, true, nil
+	}
+	if strings.Contains(header, ",") {
+		return nil, false, fmt.Errorf("If-Match must contain a single ETag")
+	}
+	if strings.HasPrefix(header, "W/") {
+		if _, err := parseETag(header, true); err != nil {
+			return nil, false, err
+		}
+		return nil, false, errWeakIfMatch
+	}
+
+	v, err := parseETag(header, false)
+	if err != nil {
+		return nil, false, err
+	}
+	return &v, false, nil
+}
+
+// etagMatches проверяет If-None-Match против текущей версии (слабое сравнение)
+func etagMatches(header string, version int) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if v, err := parseETag(tag, true); err == nil && v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// LastEventIDHeader - версия последнего полученного события; EventSource
+	// присылает его сам при переподключении
+	LastEventIDHeader = "Last-Event-ID"
+	// eventWriteTimeout - сколько ждать записи сообщения в WebSocket и ответа
+	// long-poll после конца ожидания
+	eventWriteTimeout = 10 * time.Second
+)
+
+// EventsHandler отдает изменения баланса кошелька по мере коммита операций:
+// Server-Sent Events, WebSocket и long-poll запроса баланса
+type EventsHandler struct {
+	events service.EventServiceInterface
+	// heartbeat - период пустых сообщений, которые держат соединение открытым
+	// через прокси и позволяют заметить отключившегося клиента
+	heartbeat time.Duration
+	upgrader  websocket.Upgrader
+}
+
+func NewEventsHandler(events service.EventServiceInterface, heartbeat time.Duration) *EventsHandler {
+	return &EventsHandler{
+		events:    events,
+		heartbeat: heartbeat,
+		upgrader: websocket.Upgrader{
+			// Доступ проверяется по API-ключу, а не по cookie, поэтому чужой
+			// сайт без ключа поток не откроет
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// Stream - поток событий в формате Server-Sent Events:
+// GET /api/v1/wallets/{walletId}/events. id события - версия кошелька
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	stream, lastEventID, ok := h.subscribe(w, r)
+	if !ok {
+		return
+	}
+	defer stream.Close()
+
+	// Поток живет дольше WriteTimeout сервера
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("events: failed to reset write deadline: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx иначе буферизует ответ
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event model.BalanceEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Version, event.Type, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	heartbeat := func() error {
+		if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+	h.pump(r, stream, lastEventID, send, heartbeat, nil)
+}
+
+// WebSocket - тот же поток событий JSON-сообщениями:
+// GET /api/v1/wallets/{walletId}/events/ws. Сервер шлет ping с периодом heartbeat
+// и закрывает соединение, если клиент не отвечает дольше двух периодов
+func (h *EventsHandler) WebSocket(w http.ResponseWriter, r *http.Request) {
+	stream, lastEventID, ok := h.subscribe(w, r)
+	if !ok {
+		return
+	}
+	defer stream.Close()
+
+	// При ошибке Upgrade сам отвечает клиенту
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Читаем только управляющие кадры: pong продлевает соединение, close завершает поток
+	closed := make(chan struct{})
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(event model.BalanceEvent) error {
+		conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		return conn.WriteJSON(event)
+	}
+	heartbeat := func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventWriteTimeout))
+	}
+
+	h.pump(r, stream, lastEventID, send, heartbeat, closed)
+
+	// Подписка закрыта сервером: клиент переподключится с последней версией
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "stream closed, reconnect with lastEventId")
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(eventWriteTimeout))
+}
+
+// pump отдает Backlog и события подписки, пропуская уже отданные версии, пока
+// клиент не отключится, подписка не закроется или запись не завершится ошибкой
+func (h *EventsHandler) pump(r *http.Request, stream *service.Stream, lastEventID *int, send func(model.BalanceEvent) error, heartbeat func() error, closed <-chan struct{}) {
+	last := 0
+	if lastEventID != nil {
+		last = *lastEventID
+	}
+	deliver := func(event model.BalanceEvent) error {
+		// Снимок заменяет собой все предыдущее, в том числе неверный lastEventId
+		if event.Type != model.EventSnapshot && event.Version <= last {
+			return nil
+		}
+		last = event.Version
+		return send(event)
+	}
+
+	for _, event := range stream.Backlog {
+		if err := deliver(event); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-closed:
+			return
+		case event, ok := <-stream.Events():
+			if !ok {
+				return
+			}
+			if err := deliver(event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := heartbeat(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// WaitForVersion - long-poll для клиентов без потоков:
+// GET /api/v1/wallets/{walletId}?waitForVersionAfter=N&timeout=30s. Отвечает как
+// запрос баланса, когда версия кошелька превысит N или истечет timeout
+func (h *EventsHandler) WaitForVersion(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	q := r.URL.Query()
+	var fields []apperror.FieldError
+	after, err := strconv.Atoi(q.Get("waitForVersionAfter"))
+	if err != nil || after < 0 {
+		fields = append(fields, apperror.FieldError{Field: "waitForVersionAfter", Message: "must be a non-negative integer"})
+	}
+	timeout := service.DefaultWaitTimeout
+	if s := q.Get("timeout"); s != "" {
+		timeout, err = time.ParseDuration(s)
+		if err != nil || timeout < 0 || timeout > service.MaxWaitTimeout {
+			fields = append(fields, apperror.FieldError{Field: "timeout", Message: "must be a duration from 0s to 60s"})
+		}
+	}
+	if len(fields) > 0 {
+		respondWithProblem(w, r, apperror.Validation(fields...))
+		return
+	}
+
+	// Ожидание может быть дольше WriteTimeout сервера
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Now().Add(timeout + eventWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("events: failed to extend write deadline: %v", err)
+	}
+
+	wallet, err := h.events.WaitForVersion(r.Context(), walletID, after, timeout)
+	if err != nil {
+		// Клиент не дождался - отвечать некому
+		if r.Context().Err() != nil {
+			return
+		}
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(wallet.Version))
+	respondWithJSON(w, balanceResponse(wallet))
+}
+
+// subscribe проверяет запрос и открывает поток; при ошибке отвечает клиенту сам
+func (h *EventsHandler) subscribe(w http.ResponseWriter, r *http.Request) (*service.Stream, *int, bool) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
+		return nil, nil, false
+	}
+
+	// Браузерный WebSocket не передает заголовки, поэтому есть и параметр запроса
+	var lastEventID *int
+	field, value := LastEventIDHeader, r.Header.Get(LastEventIDHeader)
+	if value == "" {
+		field, value = "lastEventId", r.URL.Query().Get("lastEventId")
+	}
+	if value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil || version < 0 {
+			respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: field, Message: "must be a non-negative integer"}))
+			return nil, nil, false
+		}
+		lastEventID = &version
+	}
+
+	stream, err := h.events.Subscribe(r.Context(), walletID, lastEventID)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return nil, nil, false
+	}
+	return stream, lastEventID, true
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/events"
+	"wallet-service/internal/model"
+	"wallet-service/internal/service"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// MockEventService подписывает на настоящий Hub и отдает заданный Backlog
+type MockEventService struct {
+	hub      *events.Hub
+	walletID uuid.UUID
+	backlog  []model.BalanceEvent
+	// lastEventID и tenantID - параметры последней подписки
+	lastEventID *int
+	tenantID    string
+	// waitAfter и waitTimeout - параметры последнего ожидания
+	waitAfter   int
+	waitTimeout time.Duration
+}
+
+func (m *MockEventService) Subscribe(ctx context.Context, walletID uuid.UUID, lastEventID *int) (*service.Stream, error) {
+	if walletID != m.walletID {
+		return nil, apperror.ErrWalletNotFound
+	}
+	m.lastEventID = lastEventID
+	m.tenantID = tenant.FromContext(ctx)
+	return &service.Stream{Subscription: m.hub.Subscribe(m.tenantID, walletID), Backlog: m.backlog}, nil
+}
+
+func (m *MockEventService) WaitForVersion(ctx context.Context, walletID uuid.UUID, after int, timeout time.Duration) (model.Wallet, error) {
+	if walletID != m.walletID {
+		return model.Wallet{}, apperror.ErrWalletNotFound
+	}
+	m.waitAfter, m.waitTimeout = after, timeout
+	return model.Wallet{ID: walletID, Balance: decimal.NewFromInt(100), Version: after + 1}, nil
+}
+
+func newEventsServer(t *testing.T, events *MockEventService) *httptest.Server {
+	server := httptest.NewServer(NewRouter(&MockWalletService{}, nil, RouterOptions{
+		Events:  NewEventsHandler(events, 50*time.Millisecond),
+		Tenants: NewTenantHandler(tenant.NewRegistry(model.Tenant{ID: "acme", APIKeys: []string{"acme-key"}})),
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func operationEvent(walletID uuid.UUID, version int) model.BalanceEvent {
+	return model.BalanceEvent{Type: model.EventOperation, WalletID: walletID, Balance: decimal.NewFromInt(int64(version)), Version: version}
+}
+
+// publishWhenSubscribed публикует события, когда поток подписался на Hub
+func publishWhenSubscribed(t *testing.T, mock *MockEventService, events ...model.BalanceEvent) {
+	require.Eventually(t, func() bool { return mock.tenantID != "" }, time.Second, 5*time.Millisecond)
+	for _, event := range events {
+		mock.hub.Publish(mock.tenantID, event)
+	}
+}
+
+func TestEventsHandler_Stream(t *testing.T) {
+	walletID := uuid.New()
+	mock := &MockEventService{hub: events.NewHub(), walletID: walletID, backlog: []model.BalanceEvent{operationEvent(walletID, 4)}}
+	server := newEventsServer(t, mock)
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/v1/wallets/"+walletID.String()+"/events?apiKey=acme-key", nil)
+	req.Header.Set(LastEventIDHeader, "3")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	require.NotNil(t, mock.lastEventID)
+	assert.Equal(t, 3, *mock.lastEventID)
+	assert.Equal(t, "acme", mock.tenantID)
+
+	// Версия 4 уже отдана из Backlog - повтор из подписки пропускается
+	publishWhenSubscribed(t, mock, operationEvent(walletID, 4), operationEvent(walletID, 5))
+
+	reader := bufio.NewReader(resp.Body)
+	var ids []string
+	heartbeat := false
+	for len(ids) < 2 || !heartbeat {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "id: ")))
+		case line == ": heartbeat\n":
+			heartbeat = true
+		}
+	}
+	assert.Equal(t, []string{"4", "5"}, ids)
+
+	// Закрытая сервером подписка завершает поток
+	mock.hub.Close()
+	_, err = reader.ReadString(0)
+	assert.Error(t, err)
+}
+
+func TestEventsHandler_StreamErrors(t *testing.T) {
+	walletID := uuid.New()
+	server := newEventsServer(t, &MockEventService{hub: events.NewHub(), walletID: walletID})
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/v1/wallets/" + walletID.String() + "/events", http.StatusUnauthorized},
+		{"/api/v1/wallets/" + uuid.NewString() + "/events?apiKey=acme-key", http.StatusNotFound},
+		{"/api/v1/wallets/" + walletID.String() + "/events?apiKey=acme-key&lastEventId=x", http.StatusBadRequest},
+		// Ключ в параметре принимается только для потоков событий
+		{"/api/v1/wallets/" + walletID.String() + "?apiKey=acme-key", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + tt.path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, tt.status, resp.StatusCode, tt.path)
+	}
+}
+
+func TestEventsHandler_WebSocket(t *testing.T) {
+	walletID := uuid.New()
+	snapshot := model.BalanceEvent{Type: model.EventSnapshot, WalletID: walletID, Balance: decimal.NewFromInt(100), Version: 2}
+	mock := &MockEventService{hub: events.NewHub(), walletID: walletID, backlog: []model.BalanceEvent{snapshot}}
+	server := newEventsServer(t, mock)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/wallets/" + walletID.String() + "/events/ws"
+	header := http.Header{APIKeyHeader: []string{"acme-key"}}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var event model.BalanceEvent
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, model.EventSnapshot, event.Type)
+	assert.Equal(t, 2, event.Version)
+
+	publishWhenSubscribed(t, mock, operationEvent(walletID, 3))
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, model.EventOperation, event.Type)
+	assert.Equal(t, 3, event.Version)
+
+	mock.hub.Close()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "%v", err)
+}
+
+func TestEventsHandler_WaitForVersion(t *testing.T) {
+	walletID := uuid.New()
+	mock := &MockEventService{hub: events.NewHub(), walletID: walletID}
+	router := NewRouter(&MockWalletService{}, nil, RouterOptions{Events: NewEventsHandler(mock, time.Second)})
+	path := "/api/v1/wallets/" + walletID.String()
+
+	req := httptest.NewRequest("GET", path+"?waitForVersionAfter=7&timeout=5s", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"8"`, rr.Header().Get("ETag"))
+	assert.Equal(t, 7, mock.waitAfter)
+	assert.Equal(t, 5*time.Second, mock.waitTimeout)
+
+	req = httptest.NewRequest("GET", path+"?waitForVersionAfter=7", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, service.DefaultWaitTimeout, mock.waitTimeout)
+
+	for _, query := range []string{"?waitForVersionAfter=x", "?waitForVersionAfter=7&timeout=2m", "?waitForVersionAfter=7&timeout=soon"} {
+		req := httptest.NewRequest("GET", path+query, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
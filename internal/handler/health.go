@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"wallet-service/internal/database"
+	"wallet-service/internal/model"
+)
+
+const (
+	healthStatusOK       = "ok"
+	healthStatusFailing  = "failing"
+	healthStatusReady    = "ready"
+	healthStatusNotReady = "not_ready"
+)
+
+// HealthHandler отдает /livez и /readyz. Готовность снимается вручную
+// перед остановкой сервера, чтобы балансировщик успел увести трафик
+type HealthHandler struct {
+	ping              func(ctx context.Context) error
+	migrationVersion  func(ctx context.Context) (int, error)
+	poolStats         func() database.PoolStats
+	expectedMigration int
+	timeout           time.Duration
+	shuttingDown      atomic.Bool
+}
+
+// NewHealthHandler проверяет основной сервер cluster; реплики на готовность не влияют
+func NewHealthHandler(cluster *database.Cluster, expectedMigration int) *HealthHandler {
+	return &HealthHandler{
+		ping: cluster.Primary.Ping,
+		migrationVersion: func(ctx context.Context) (int, error) {
+			return database.MigrationVersion(ctx, cluster.DB)
+		},
+		poolStats:         cluster.PrimaryStats,
+		expectedMigration: expectedMigration,
+		timeout:           2 * time.Second,
+	}
+}
+
+// SetShuttingDown переводит /readyz в 503 независимо от состояния зависимостей
+func (h *HealthHandler) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Livez отвечает, пока процесс жив и обслуживает HTTP; зависимости не проверяются
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": healthStatusOK})
+}
+
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	response := model.HealthResponse{
+		Status: healthStatusReady,
+		Checks: map[string]model.HealthCheck{},
+	}
+
+	if h.shuttingDown.Load() {
+		response.Checks["shutdown"] = model.HealthCheck{Status: healthStatusFailing, Error: "server is shutting down"}
+	}
+
+	start := time.Now()
+	if err := h.ping(ctx); err != nil {
+		response.Checks["database"] = model.HealthCheck{Status: healthStatusFailing, Error: err.Error()}
+	} else {
+		response.Checks["database"] = model.HealthCheck{
+			Status:  healthStatusOK,
+			Details: map[string]any{"latencyMs": time.Since(start).Milliseconds()},
+		}
+	}
+
+	response.Checks["migrations"] = h.checkMigrations(ctx)
+	response.Checks["pool"] = h.checkPool()
+
+	code := http.StatusOK
+	for _, check := range response.Checks {
+		if check.Status == healthStatusFailing {
+			response.Status = healthStatusNotReady
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context) model.HealthCheck {
+	current, err := h.migrationVersion(ctx)
+	if err != nil {
+		return model.HealthCheck{Status: healthStatusFailing, Error: err.Error()}
+	}
+
+	check := model.HealthCheck{
+		Status:  healthStatusOK,
+		Details: map[string]any{"current": current, "expected": h.expectedMigration},
+	}
+	if current < h.expectedMigration {
+		check.Status = healthStatusFailing
+		check.Error = fmt.Sprintf("schema version %d is behind expected %d", current, h.expectedMigration)
+	}
+	return check
+}
+
+// checkPool только сообщает о насыщении пула: при всплеске нагрузки
+// снимать реплику с балансировки было бы хуже, чем дать запросам подождать
+func (h *HealthHandler) checkPool() model.HealthCheck {
+	stats := h.poolStats()
+
+	details := map[string]any{
+		"open":      stats.Total,
+		"inUse":     stats.Acquired,
+		"idle":      stats.Idle,
+		"maxOpen":   stats.Max,
+		"waitCount": stats.EmptyAcquires,
+		"acquireMs": stats.AcquireMs,
+	}
+	if stats.Max > 0 {
+		saturation := float64(stats.Acquired) / float64(stats.Max)
+		details["saturation"] = saturation
+		details["saturated"] = stats.Acquired >= stats.Max
+	}
+
+	return model.HealthCheck{Status: healthStatusOK, Details: details}
+}
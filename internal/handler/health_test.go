@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"wallet-service/internal/database"
+	"wallet-service/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHealthHandler(pingErr error, migration int) *HealthHandler {
+	return &HealthHandler{
+		ping: func(ctx context.Context) error { return pingErr },
+		migrationVersion: func(ctx context.Context) (int, error) {
+			return migration, nil
+		},
+		poolStats: func() database.PoolStats {
+			return database.PoolStats{Max: 25, Total: 10, Acquired: 25}
+		},
+		expectedMigration: 2,
+		timeout:           time.Second,
+	}
+}
+
+func TestHealthHandler_Readyz(t *testing.T) {
+	tests := []struct {
+		name         string
+		pingErr      error
+		migration    int
+		shuttingDown bool
+		expected     int
+		failing      string
+	}{
+		{"ready", nil, 2, false, http.StatusOK, ""},
+		{"database down", errors.New("connection refused"), 2, false, http.StatusServiceUnavailable, "database"},
+		{"migrations behind", nil, 1, false, http.StatusServiceUnavailable, "migrations"},
+		{"shutting down", nil, 2, true, http.StatusServiceUnavailable, "shutdown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := newTestHealthHandler(tt.pingErr, tt.migration)
+			if tt.shuttingDown {
+				health.SetShuttingDown()
+			}
+
+			rr := httptest.NewRecorder()
+			health.Readyz(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+			assert.Equal(t, tt.expected, rr.Code)
+
+			var response model.HealthResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			if tt.failing != "" {
+				assert.Equal(t, healthStatusNotReady, response.Status)
+				assert.Equal(t, healthStatusFailing, response.Checks[tt.failing].Status)
+			} else {
+				assert.Equal(t, healthStatusReady, response.Status)
+			}
+
+			// Насыщение пула видно в ответе, но готовность не снимает
+			assert.Equal(t, true, response.Checks["pool"].Details["saturated"])
+		})
+	}
+}
+
+func TestHealthHandler_Livez(t *testing.T) {
+	health := newTestHealthHandler(errors.New("connection refused"), 0)
+	health.SetShuttingDown()
+
+	rr := httptest.NewRecorder()
+	health.Livez(rr, httptest.NewRequest("GET", "/livez", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maxBalancesAsOf - предел кошельков в одном запросе балансов на момент времени
+const maxBalancesAsOf = 1000
+
+// HistoryHandler отвечает балансами кошельков на момент времени по двойной записи
+type HistoryHandler struct {
+	ledger service.LedgerServiceInterface
+	now    func() time.Time
+}
+
+func NewHistoryHandler(ledger service.LedgerServiceInterface) *HistoryHandler {
+	return &HistoryHandler{
+		ledger: ledger,
+		now:    time.Now,
+	}
+}
+
+// GetBalanceAsOf - баланс кошелька на момент времени: GET /api/v1/wallets/{walletId}?asOf=<RFC3339>
+func (h *HistoryHandler) GetBalanceAsOf(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	asOf, err := time.Parse(time.RFC3339, r.URL.Query().Get("asOf"))
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "asOf", Message: "must be an RFC 3339 timestamp"}))
+		return
+	}
+
+	balance, err := h.ledger.BalanceAsOf(r.Context(), walletID, asOf)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, model.BalanceAsOfResponse{WalletID: walletID, Balance: balance, AsOf: asOf})
+}
+
+// GetBalancesAsOf - балансы нескольких кошельков на один момент одним запросом.
+// Без asOf берется текущее время
+func (h *HistoryHandler) GetBalancesAsOf(w http.ResponseWriter, r *http.Request) {
+	var req model.BalancesAsOfRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, r, apperror.ErrMalformedRequest.WithDetail("request body is not valid JSON"))
+		return
+	}
+
+	switch {
+	case len(req.WalletIDs) == 0:
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletIds", Message: "is required"}))
+		return
+	case len(req.WalletIDs) > maxBalancesAsOf:
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletIds", Message: "must contain at most 1000 wallets"}))
+		return
+	}
+
+	asOf := h.now()
+	if req.AsOf != nil {
+		asOf = *req.AsOf
+	}
+
+	balances, err := h.ledger.BalancesAsOf(r.Context(), req.WalletIDs, asOf)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	resp := model.BalancesAsOfResponse{
+		AsOf:     asOf,
+		Balances: make([]model.BalanceAsOfResponse, 0, len(balances)),
+		NotFound: []uuid.UUID{},
+	}
+	seen := make(map[uuid.UUID]bool, len(req.WalletIDs))
+	for _, id := range req.WalletIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		balance, ok := balances[id]
+		if !ok {
+			resp.NotFound = append(resp.NotFound, id)
+			continue
+		}
+		resp.Balances = append(resp.Balances, model.BalanceAsOfResponse{WalletID: id, Balance: balance, AsOf: asOf})
+	}
+
+	respondWithJSON(w, resp)
+}
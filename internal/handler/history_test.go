@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHistoryRouter(balances map[uuid.UUID]decimal.Decimal) http.Handler {
+	history := NewHistoryHandler(&MockLedgerService{balances: balances})
+	return NewRouter(&MockWalletService{}, nil, RouterOptions{History: history})
+}
+
+func TestHistoryHandler_GetBalanceAsOf(t *testing.T) {
+	walletID := uuid.New()
+	router := newHistoryRouter(map[uuid.UUID]decimal.Decimal{walletID: decimal.NewFromInt(40)})
+
+	req := httptest.NewRequest("GET", "/api/v1/wallets/"+walletID.String()+"?asOf=2026-01-01T00:00:00Z", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp model.BalanceAsOfResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, walletID, resp.WalletID)
+	assert.True(t, decimal.NewFromInt(40).Equal(resp.Balance))
+	assert.Equal(t, 2026, resp.AsOf.Year())
+
+	req = httptest.NewRequest("GET", "/api/v1/wallets/"+uuid.New().String()+"?asOf=2026-01-01T00:00:00Z", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestHistoryHandler_GetBalanceAsOf_InvalidTimestamp(t *testing.T) {
+	router := newHistoryRouter(nil)
+
+	req := httptest.NewRequest("GET", "/api/v1/wallets/"+uuid.New().String()+"?asOf=yesterday", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var problem apperror.Problem
+	json.Unmarshal(rr.Body.Bytes(), &problem)
+	assert.Equal(t, "asOf", problem.Errors[0].Field)
+}
+
+func TestHistoryHandler_GetBalancesAsOf(t *testing.T) {
+	known, missing := uuid.New(), uuid.New()
+	router := newHistoryRouter(map[uuid.UUID]decimal.Decimal{known: decimal.NewFromInt(15)})
+
+	body, _ := json.Marshal(model.BalancesAsOfRequest{WalletIDs: []uuid.UUID{known, missing, known}})
+	req := httptest.NewRequest("POST", "/api/v1/wallets/balances", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp model.BalancesAsOfResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Len(t, resp.Balances, 1)
+	assert.Equal(t, known, resp.Balances[0].WalletID)
+	assert.Equal(t, []uuid.UUID{missing}, resp.NotFound)
+	assert.False(t, resp.AsOf.IsZero())
+}
+
+func TestHistoryHandler_GetBalancesAsOf_Validation(t *testing.T) {
+	router := newHistoryRouter(nil)
+
+	ids := make([]uuid.UUID, maxBalancesAsOf+1)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	for _, req := range []model.BalancesAsOfRequest{{}, {WalletIDs: ids}} {
+		body, _ := json.Marshal(req)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("POST", "/api/v1/wallets/balances", bytes.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	}
+}
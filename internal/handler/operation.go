@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// IdempotencyKeyHeader - повтор запроса с тем же ключом возвращает результат первого выполнения
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength совпадает с размером колонки operations.idempotency_key
+const maxIdempotencyKeyLength = 255
+
+func (h *WalletHandler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	operationID, err := uuid.Parse(mux.Vars(r)["operationId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "operationId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	op, err := h.walletService.GetOperation(r.Context(), operationID)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, op)
+}
+
+// ReverseOperation сторнирует операцию. Тело необязательно: без amount сторнируется весь остаток
+func (h *WalletHandler) ReverseOperation(w http.ResponseWriter, r *http.Request) {
+	operationID, err := uuid.Parse(mux.Vars(r)["operationId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "operationId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	var req model.ReverseOperationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondWithProblem(w, r, apperror.ErrMalformedRequest.WithDetail("request body is not valid JSON"))
+		return
+	}
+
+	var fields []apperror.FieldError
+	if req.Amount != nil && !req.Amount.IsPositive() {
+		fields = append(fields, apperror.FieldError{Field: "amount", Message: "must be positive"})
+	} else if req.Amount != nil && !model.ValidAmount(*req.Amount) {
+		fields = append(fields, apperror.FieldError{Field: "amount", Message: model.AmountPrecisionMessage})
+	}
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		fields = append(fields, apperror.FieldError{Field: IdempotencyKeyHeader, Message: "must be at most 255 characters"})
+	}
+	if len(fields) > 0 {
+		respondWithProblem(w, r, apperror.Validation(fields...))
+		return
+	}
+
+	result, err := h.walletService.ReverseOperation(r.Context(), model.Reversal{
+		OperationID:    operationID,
+		Amount:         req.Amount,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithOperation(w, result)
+}
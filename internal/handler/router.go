@@ -1,23 +1,119 @@
 package handler
 
 import (
+	"expvar"
 	"net/http"
 
 	"wallet-service/internal/service"
 	"github.com/gorilla/mux"
 )
 
-func NewRouter(walletService *service.WalletService) http.Handler {
+// RouterOptions включает необязательные эндпоинты
+type RouterOptions struct {
+	Metrics bool
+	// Admin - обработчик /api/v1/admin; nil отключает админский API
+	Admin *AdminHandler
+	// Schedules - обработчик /api/v1/scheduled-operations; nil отключает отложенные операции
+	Schedules *ScheduleHandler
+	// History - балансы на момент времени (?asOf и /api/v1/wallets/balances); nil отключает их
+	History *HistoryHandler
+	// Audit - журнал аудита изменяющих запросов и /api/v1/admin/audit; nil отключает аудит
+	Audit *AuditHandler
+	// Chain - проверка цепочек операций в /api/v1/admin/chain; работает только вместе с Admin
+	Chain *ChainHandler
+	// Events - потоки изменений баланса (SSE и WebSocket) и ожидание изменения
+	// (?waitForVersionAfter); nil отключает их
+	Events *EventsHandler
+	// Consistency выдает токены согласованности в ответах на изменения и принимает их
+	// в чтениях; nil - реплик нет, и все читается с основного сервера
+	Consistency *ConsistencyHandler
+	// Tenants определяет тенанта запроса; nil - все запросы выполняются от имени тенанта по умолчанию
+	Tenants *TenantHandler
+}
+
+// NewRouter собирает HTTP API. healthHandler может быть nil - тогда /livez и /readyz не регистрируются
+func NewRouter(walletService service.WalletServiceInterface, healthHandler *HealthHandler, opts RouterOptions) http.Handler {
 	router := mux.NewRouter()
+	if opts.Audit != nil {
+		router.Use(opts.Audit.record)
+	}
+	// После аудита: запрос с неверным ключом тоже попадает в журнал
+	if opts.Tenants != nil {
+		router.Use(opts.Tenants.authenticate)
+	}
+	if opts.Consistency != nil {
+		router.Use(opts.Consistency.middleware)
+	}
 	walletHandler := NewWalletHandler(walletService)
 
 	router.HandleFunc("/api/v1/wallet", walletHandler.ProcessOperation).Methods("POST")
+	router.HandleFunc("/api/v1/wallets", walletHandler.ListWallets).Methods("GET")
+	// Маршруты истории регистрируются раньше GetBalance: mux выбирает первый совпавший
+	if opts.History != nil {
+		router.HandleFunc("/api/v1/wallets/balances", opts.History.GetBalancesAsOf).Methods("POST")
+		router.HandleFunc("/api/v1/wallets/{walletId}", opts.History.GetBalanceAsOf).Methods("GET").Queries("asOf", "{asOf}")
+	}
+	if opts.Events != nil {
+		router.HandleFunc("/api/v1/wallets/{walletId}", opts.Events.WaitForVersion).Methods("GET").Queries("waitForVersionAfter", "{after}")
+	}
 	router.HandleFunc("/api/v1/wallets/{walletId}", walletHandler.GetBalance).Methods("GET")
+	router.HandleFunc("/api/v1/wallets/{walletId}", walletHandler.UpdateMetadata).Methods("PATCH")
+	if opts.Events != nil {
+		router.HandleFunc("/api/v1/wallets/{walletId}/events", opts.Events.Stream).Methods("GET")
+		router.HandleFunc("/api/v1/wallets/{walletId}/events/ws", opts.Events.WebSocket).Methods("GET")
+	}
+	router.HandleFunc("/api/v1/operations/{operationId}", walletHandler.GetOperation).Methods("GET")
+	router.HandleFunc("/api/v1/operations/{operationId}/reverse", walletHandler.ReverseOperation).Methods("POST")
+
+	if opts.Schedules != nil {
+		router.HandleFunc("/api/v1/scheduled-operations", opts.Schedules.Create).Methods("POST")
+		router.HandleFunc("/api/v1/scheduled-operations", opts.Schedules.List).Methods("GET")
+		router.HandleFunc("/api/v1/scheduled-operations/{scheduleId}", opts.Schedules.Get).Methods("GET")
+		router.HandleFunc("/api/v1/scheduled-operations/{scheduleId}", opts.Schedules.Cancel).Methods("DELETE")
+		router.HandleFunc("/api/v1/scheduled-operations/{scheduleId}/runs", opts.Schedules.Runs).Methods("GET")
+	}
+
+	if opts.Admin != nil {
+		admin := router.PathPrefix("/api/v1/admin").Subrouter()
+		admin.Use(opts.Admin.authenticate)
+		if opts.Tenants != nil {
+			admin.Use(opts.Tenants.adminScope)
+		}
+		admin.HandleFunc("/wallets/{walletId}/limits", opts.Admin.GetLimits).Methods("GET")
+		admin.HandleFunc("/wallets/{walletId}/limits", opts.Admin.SetLimits).Methods("PUT")
+		admin.HandleFunc("/wallets/{walletId}/credit-limit", opts.Admin.SetCreditLimit).Methods("PUT")
+		admin.HandleFunc("/wallets/{walletId}/tier", opts.Admin.SetTier).Methods("PUT")
+		admin.HandleFunc("/wallets/{walletId}/status", opts.Admin.SetStatus).Methods("PUT")
+		admin.HandleFunc("/ledger/entries/{entryId}", opts.Admin.GetEntry).Methods("GET")
+		admin.HandleFunc("/ledger/trial-balance", opts.Admin.TrialBalance).Methods("GET")
+		admin.HandleFunc("/adjustments", opts.Admin.ProposeAdjustment).Methods("POST")
+		admin.HandleFunc("/adjustments", opts.Admin.ListAdjustments).Methods("GET")
+		admin.HandleFunc("/adjustments/{adjustmentId}", opts.Admin.GetAdjustment).Methods("GET")
+		admin.HandleFunc("/adjustments/{adjustmentId}/approve", opts.Admin.ApproveAdjustment).Methods("POST")
+		admin.HandleFunc("/adjustments/{adjustmentId}/reject", opts.Admin.RejectAdjustment).Methods("POST")
+		if opts.Chain != nil {
+			admin.HandleFunc("/chain/wallets/{walletId}/verify", opts.Chain.Verify).Methods("GET")
+			admin.HandleFunc("/chain/checkpoints/latest", opts.Chain.LatestCheckpoint).Methods("GET")
+		}
+		if opts.Audit != nil {
+			admin.HandleFunc("/audit", opts.Audit.List).Methods("GET")
+			admin.HandleFunc("/audit/export", opts.Audit.Export).Methods("GET")
+		}
+	}
 
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	}).Methods("GET")
+	if healthHandler != nil {
+		router.HandleFunc("/livez", healthHandler.Livez).Methods("GET")
+		router.HandleFunc("/readyz", healthHandler.Readyz).Methods("GET")
+	}
+
+	// Счетчики сервиса (попытки операций и т.д.) в формате expvar
+	if opts.Metrics {
+		router.Handle("/debug/vars", expvar.Handler()).Methods("GET")
+	}
 
 	return router
 }
\ No newline at end of file
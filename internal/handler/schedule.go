@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/service"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// ScheduleHandler обслуживает /api/v1/scheduled-operations
+type ScheduleHandler struct {
+	schedules service.ScheduleServiceInterface
+}
+
+func NewScheduleHandler(schedules service.ScheduleServiceInterface) *ScheduleHandler {
+	return &ScheduleHandler{
+		schedules: schedules,
+	}
+}
+
+func (h *ScheduleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req model.ScheduleOperationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, r, apperror.ErrMalformedRequest.WithDetail("request body is not valid JSON"))
+		return
+	}
+
+	if err := validateScheduleRequest(req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	job, err := h.schedules.Create(r.Context(), req)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/scheduled-operations/"+job.ID.String())
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(job)
+}
+
+// List возвращает задания кошелька: GET /api/v1/scheduled-operations?walletId=...
+func (h *ScheduleHandler) List(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(r.URL.Query().Get("walletId"))
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	jobs, err := h.schedules.List(r.Context(), walletID)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, jobs)
+}
+
+func (h *ScheduleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := scheduleID(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.schedules.Get(r.Context(), id)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, job)
+}
+
+// Cancel отменяет активное задание. Уже выполненные запуски не откатываются
+func (h *ScheduleHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, ok := scheduleID(w, r)
+	if !ok {
+		return
+	}
+
+	job, err := h.schedules.Cancel(r.Context(), id)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, job)
+}
+
+func (h *ScheduleHandler) Runs(w http.ResponseWriter, r *http.Request) {
+	id, ok := scheduleID(w, r)
+	if !ok {
+		return
+	}
+
+	runs, err := h.schedules.Runs(r.Context(), id)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	respondWithJSON(w, runs)
+}
+
+func scheduleID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["scheduleId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "scheduleId", Message: "must be a valid UUID"}))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// validateScheduleRequest проверяет поля операции; разбор cron-выражения - в сервисе
+func validateScheduleRequest(req model.ScheduleOperationRequest) error {
+	var fields []apperror.FieldError
+
+	if req.WalletID == uuid.Nil {
+		fields = append(fields, apperror.FieldError{Field: "walletId", Message: "is required"})
+	}
+
+	if req.OperationType != model.OperationTypeDeposit && req.OperationType != model.OperationTypeWithdraw {
+		fields = append(fields, apperror.FieldError{Field: "operationType", Message: "must be DEPOSIT or WITHDRAW"})
+	}
+
+	if req.Amount.LessThanOrEqual(decimal.Zero) {
+		fields = append(fields, apperror.FieldError{Field: "amount", Message: "must be positive"})
+	} else if !model.ValidAmount(req.Amount) {
+		fields = append(fields, apperror.FieldError{Field: "amount", Message: model.AmountPrecisionMessage})
+	}
+
+	if req.RunAt == nil && req.Schedule == "" {
+		fields = append(fields, apperror.FieldError{Field: "runAt", Message: "runAt or schedule is required"})
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type MockScheduleService struct {
+	jobs map[uuid.UUID]model.ScheduledOperation
+}
+
+func (m *MockScheduleService) Create(ctx context.Context, req model.ScheduleOperationRequest) (model.ScheduledOperation, error) {
+	job := model.ScheduledOperation{
+		ID:            uuid.New(),
+		WalletID:      req.WalletID,
+		OperationType: req.OperationType,
+		Amount:        req.Amount,
+		Schedule:      req.Schedule,
+		NextRunAt:     req.RunAt,
+		Status:        model.ScheduleStatusActive,
+	}
+	m.jobs[job.ID] = job
+	return job, nil
+}
+
+func (m *MockScheduleService) Get(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error) {
+	job, ok := m.jobs[id]
+	if !ok {
+		return model.ScheduledOperation{}, apperror.ErrScheduleNotFound
+	}
+	return job, nil
+}
+
+func (m *MockScheduleService) List(ctx context.Context, walletID uuid.UUID) ([]model.ScheduledOperation, error) {
+	jobs := []model.ScheduledOperation{}
+	for _, job := range m.jobs {
+		if job.WalletID == walletID {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (m *MockScheduleService) Cancel(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error) {
+	job, err := m.Get(ctx, id)
+	if err != nil {
+		return model.ScheduledOperation{}, err
+	}
+	if job.Status != model.ScheduleStatusActive {
+		return model.ScheduledOperation{}, apperror.ErrScheduleNotActive
+	}
+	job.Status = model.ScheduleStatusCancelled
+	job.NextRunAt = nil
+	m.jobs[id] = job
+	return job, nil
+}
+
+func (m *MockScheduleService) Runs(ctx context.Context, id uuid.UUID) ([]model.ScheduledRun, error) {
+	if _, err := m.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return []model.ScheduledRun{}, nil
+}
+
+func newScheduleRouter() http.Handler {
+	schedules := &MockScheduleService{jobs: map[uuid.UUID]model.ScheduledOperation{}}
+	return NewRouter(&MockWalletService{}, nil, RouterOptions{Schedules: NewScheduleHandler(schedules)})
+}
+
+func TestScheduleHandler_CreateListCancel(t *testing.T) {
+	router := newScheduleRouter()
+	walletID := uuid.New()
+
+	body := `{"walletId":"` + walletID.String() + `","operationType":"DEPOSIT","amount":"100.00","schedule":"0 9 * * *"}`
+	req := httptest.NewRequest("POST", "/api/v1/scheduled-operations", bytes.NewReader([]byte(body)))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var created model.ScheduledOperation
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	assert.Equal(t, "/api/v1/scheduled-operations/"+created.ID.String(), rr.Header().Get("Location"))
+
+	req = httptest.NewRequest("GET", "/api/v1/scheduled-operations?walletId="+walletID.String(), nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var jobs []model.ScheduledOperation
+	json.Unmarshal(rr.Body.Bytes(), &jobs)
+	assert.Len(t, jobs, 1)
+
+	path := "/api/v1/scheduled-operations/" + created.ID.String()
+	req = httptest.NewRequest("DELETE", path, nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var cancelled model.ScheduledOperation
+	json.Unmarshal(rr.Body.Bytes(), &cancelled)
+	assert.Equal(t, model.ScheduleStatusCancelled, cancelled.Status)
+
+	// Повторная отмена - задание уже не активно
+	req = httptest.NewRequest("DELETE", path, nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	var problem apperror.Problem
+	json.Unmarshal(rr.Body.Bytes(), &problem)
+	assert.Equal(t, apperror.CodeScheduleNotActive, problem.Code)
+}
+
+func TestScheduleHandler_CreateValidation(t *testing.T) {
+	router := newScheduleRouter()
+
+	req := httptest.NewRequest("POST", "/api/v1/scheduled-operations", bytes.NewReader([]byte(`{"operationType":"DEPOSIT","amount":"0"}`)))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var problem apperror.Problem
+	json.Unmarshal(rr.Body.Bytes(), &problem)
+	fields := map[string]bool{}
+	for _, e := range problem.Errors {
+		fields[e.Field] = true
+	}
+	assert.Equal(t, map[string]bool{"walletId": true, "amount": true, "runAt": true}, fields)
+}
+
+func TestScheduleHandler_GetNotFound(t *testing.T) {
+	router := newScheduleRouter()
+
+	req := httptest.NewRequest("GET", "/api/v1/scheduled-operations/"+uuid.New().String()+"/runs", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/tenant"
+)
+
+const (
+	// APIKeyHeader - ключ клиента, по которому определяется тенант запроса
+	APIKeyHeader = "X-API-Key"
+	// APIKeyParam - ключ в параметре запроса для потоков событий: EventSource
+	// и WebSocket в браузере не умеют передавать заголовки
+	APIKeyParam = "apiKey"
+	// TenantHeader - тенант, с данными которого работает запрос админского API
+	TenantHeader = "X-Tenant-ID"
+)
+
+const (
+	apiPrefix   = "/api/v1/"
+	adminPrefix = "/api/v1/admin/"
+)
+
+// TenantHandler определяет тенанта запроса и кладет его в контекст
+type TenantHandler struct {
+	tenants *tenant.Registry
+}
+
+func NewTenantHandler(tenants *tenant.Registry) *TenantHandler {
+	return &TenantHandler{tenants: tenants}
+}
+
+// authenticate - middleware клиентского API: тенант определяется по X-API-Key.
+// Пока ключей нет ни у одного тенанта, запрос без ключа выполняется от имени
+// тенанта по умолчанию. Админский API определяет тенанта сам (adminScope)
+func (h *TenantHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, apiPrefix) || strings.HasPrefix(r.URL.Path, adminPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		id := tenant.DefaultID
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" && isEventStream(r.URL.Path) {
+			key = r.URL.Query().Get(APIKeyParam)
+		}
+		if key != "" || h.tenants.KeysRequired() {
+			found, ok := h.tenants.Authenticate(key)
+			if key == "" || !ok {
+				respondWithProblem(w, r, apperror.ErrUnauthorized.WithDetail("valid %s header is required", APIKeyHeader))
+				return
+			}
+			id = found
+		}
+
+		audit.SetTenant(r.Context(), id)
+		audit.SetActor(r.Context(), clientActor(id, key))
+		next.ServeHTTP(w, r.WithContext(tenant.NewContext(r.Context(), id)))
+	})
+}
+
+// clientActor - актор клиентского запроса для журнала аудита: тенант и отпечаток
+// ключа, которым он подписан. Без ключа (ключей нет ни у одного тенанта) - только тенант
+func clientActor(id, key string) string {
+	if key == "" {
+		return id
+	}
+	return id + ":" + tenant.KeyFingerprint(key)
+}
+
+// adminScope - middleware админского API: оператор выбирает тенанта заголовком
+// X-Tenant-ID, без заголовка - тенант по умолчанию. Выполняется после проверки токена
+func (h *TenantHandler) adminScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(TenantHeader)
+		if id == "" {
+			id = tenant.DefaultID
+		}
+		if _, ok := h.tenants.Get(id); !ok {
+			respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: TenantHeader, Message: "unknown tenant"}))
+			return
+		}
+
+		audit.SetTenant(r.Context(), id)
+		next.ServeHTTP(w, r.WithContext(tenant.NewContext(r.Context(), id)))
+	})
+}
+
+func isEventStream(path string) bool {
+	return strings.HasSuffix(path, "/events") || strings.HasSuffix(path, "/events/ws")
+}
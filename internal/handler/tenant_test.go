@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// tenantWalletService запоминает тенанта, от имени которого пришел запрос
+type tenantWalletService struct {
+	MockWalletService
+	tenant string
+}
+
+func (m *tenantWalletService) GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error) {
+	m.tenant = tenant.FromContext(ctx)
+	return m.MockWalletService.GetWallet(ctx, id)
+}
+
+const testWalletPath = "/api/v1/wallets/123e4567-e89b-12d3-a456-426614174000"
+
+func TestTenantHandler_KeyIdentifiesTenant(t *testing.T) {
+	tenants := tenant.NewRegistry(
+		model.Tenant{ID: tenant.DefaultID, APIKeys: []string{"default-key-0123456"}},
+		model.Tenant{ID: "acme", APIKeys: []string{"acme-key-0123456789"}},
+	)
+	service := &tenantWalletService{}
+	router := NewRouter(service, nil, RouterOptions{Tenants: NewTenantHandler(tenants)})
+
+	req := httptest.NewRequest("GET", testWalletPath, nil)
+	req.Header.Set(APIKeyHeader, "acme-key-0123456789")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "acme", service.tenant)
+}
+
+func TestTenantHandler_RequiresKey(t *testing.T) {
+	tenants := tenant.NewRegistry(model.Tenant{ID: "acme", APIKeys: []string{"acme-key-0123456789"}})
+	router := NewRouter(&MockWalletService{}, nil, RouterOptions{Tenants: NewTenantHandler(tenants)})
+
+	for _, key := range []string{"", "wrong-key-0123456789"} {
+		req := httptest.NewRequest("GET", testWalletPath, nil)
+		if key != "" {
+			req.Header.Set(APIKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		var problem apperror.Problem
+		json.Unmarshal(rr.Body.Bytes(), &problem)
+		assert.Equal(t, apperror.CodeUnauthorized, problem.Code)
+	}
+}
+
+func TestTenantHandler_DefaultTenantWithoutKeys(t *testing.T) {
+	service := &tenantWalletService{}
+	router := NewRouter(service, nil, RouterOptions{Tenants: NewTenantHandler(tenant.NewRegistry())})
+
+	req := httptest.NewRequest("GET", testWalletPath, nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, tenant.DefaultID, service.tenant)
+}
+
+func TestTenantHandler_AdminUnknownTenant(t *testing.T) {
+	walletID := uuid.New()
+	limits := &MockLimitsService{limits: map[uuid.UUID]model.Limits{walletID: {}}}
+	router := NewRouter(&MockWalletService{}, nil, RouterOptions{
+		Admin:   NewAdminHandler(testOperators(), limits, &MockLedgerService{}, newMockAdjustmentService()),
+		Tenants: NewTenantHandler(tenant.NewRegistry(model.Tenant{ID: "acme", APIKeys: []string{"acme-key-0123456789"}})),
+	})
+	path := "/api/v1/admin/wallets/" + walletID.String() + "/limits"
+
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set(AdminTokenHeader, "s3cret")
+	req.Header.Set(TenantHeader, "unknown")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Админский API не требует X-API-Key
+	req = httptest.NewRequest("GET", path, nil)
+	req.Header.Set(AdminTokenHeader, "s3cret")
+	req.Header.Set(TenantHeader, "acme")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
@@ -2,9 +2,14 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
-	"fmt"
+	"strings"
 
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/cache"
 	"wallet-service/internal/model"
 	"wallet-service/internal/service"
 	"github.com/google/uuid"
@@ -24,44 +29,68 @@ func NewWalletHandler(walletService service.WalletServiceInterface) *WalletHandl
 
 func (h *WalletHandler) ProcessOperation(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		respondWithProblem(w, r, apperror.ErrMethodNotAllowed)
 		return
 	}
 
 	var req model.WalletOperationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request body")
+		respondWithProblem(w, r, apperror.ErrMalformedRequest.WithDetail("request body is not valid JSON"))
 		return
 	}
 
-	if err := validateOperationRequest(req); err != nil {
-		respondWithError(w, http.StatusBadRequest, err.Error())
+	mustExist := false
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		version, anyVersion, err := parseIfMatch(ifMatch)
+		if errors.Is(err, errWeakIfMatch) {
+			respondWithProblem(w, r, apperror.ErrPreconditionFailed.WithDetail("weak ETag never matches If-Match"))
+			return
+		}
+		if err != nil {
+			respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "If-Match", Message: err.Error()}))
+			return
+		}
+		if version != nil && req.ExpectedVersion != nil && *version != *req.ExpectedVersion {
+			respondWithProblem(w, r, apperror.Validation(apperror.FieldError{
+				Field:   "expectedVersion",
+				Message: "does not match If-Match header",
+			}))
+			return
+		}
+		if version != nil {
+			req.ExpectedVersion = version
+		}
+		mustExist = anyVersion
+	}
+
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if err := validateOperationRequest(req, key); err != nil {
+		respondWithProblem(w, r, err)
 		return
 	}
 
-	operation := model.WalletOperation(req)
+	operation := model.WalletOperation{
+		WalletID:        req.WalletID,
+		OperationType:   req.OperationType,
+		Amount:          req.Amount,
+		Currency:        req.Currency,
+		ExpectedVersion: req.ExpectedVersion,
+		MustExist:       mustExist,
+		IdempotencyKey:  key,
+	}
 
-	if err := h.walletService.ProcessOperation(r.Context(), operation); err != nil {
-		switch err {
-		case service.ErrWalletNotFound:
-			respondWithError(w, http.StatusNotFound, "Wallet not found")
-		case service.ErrInsufficientFunds:
-			respondWithError(w, http.StatusBadRequest, "Insufficient funds")
-		case service.ErrOptimisticLock:
-			respondWithError(w, http.StatusConflict, "Operation conflict, please retry")
-		default:
-			respondWithError(w, http.StatusInternalServerError, "Internal server error")
-		}
+	result, err := h.walletService.ProcessOperation(r.Context(), operation)
+	if err != nil {
+		respondWithProblem(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	respondWithOperation(w, result)
 }
 
 func (h *WalletHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		respondWithProblem(w, r, apperror.ErrMethodNotAllowed)
 		return
 	}
 
@@ -70,47 +99,115 @@ func (h *WalletHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 
 	walletID, err := uuid.Parse(walletIDStr)
 	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid wallet ID")
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
 		return
 	}
 
-	balance, err := h.walletService.GetBalance(r.Context(), walletID)
+	ctx := r.Context()
+	if noCache(r) {
+		ctx = cache.WithoutCache(ctx)
+	}
+	wallet, err := h.walletService.GetWallet(ctx, walletID)
 	if err != nil {
-		if err == service.ErrWalletNotFound {
-			respondWithError(w, http.StatusNotFound, "Wallet not found")
-		} else {
-			respondWithError(w, http.StatusInternalServerError, "Internal server error")
-		}
+		respondWithProblem(w, r, err)
 		return
 	}
 
-	response := model.BalanceResponse{
-		WalletID: walletID,
-		Balance:  balance,
+	w.Header().Set("ETag", formatETag(wallet.Version))
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, wallet.Version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	respondWithJSON(w, balanceResponse(wallet))
+}
+
+// noCache - клиент просит прочитать кошелек из базы: Cache-Control: no-cache
+func noCache(r *http.Request) bool {
+	for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+			return true
+		}
+	}
+	return false
+}
+
+func balanceResponse(wallet model.Wallet) model.BalanceResponse {
+	labels := wallet.Labels
+	if labels == nil {
+		labels = map[string]string{}
 	}
+	return model.BalanceResponse{
+		WalletID:    wallet.ID,
+		Balance:     wallet.Balance,
+		CreditLimit: wallet.CreditLimit,
+		Available:   wallet.Available(),
+		Tier:        wallet.Tier,
+		Currency:    wallet.Currency,
+		Status:      wallet.Status,
+		OwnerRef:    wallet.OwnerRef,
+		DisplayName: wallet.DisplayName,
+		Labels:      labels,
+		CreatedAt:   wallet.CreatedAt,
+		UpdatedAt:   wallet.UpdatedAt,
+		Version:     wallet.Version,
+	}
+}
 
+// respondWithOperation отдает примененную операцию и ETag новой версии кошелька
+func respondWithOperation(w http.ResponseWriter, op model.Operation) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	w.Header().Set("ETag", formatETag(op.WalletVersion))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(model.OperationResponse{Status: "success", Operation: op})
 }
 
-func validateOperationRequest(req model.WalletOperationRequest) error {
+// validateOperationRequest проверяет все поля сразу, чтобы клиент увидел все ошибки
+func validateOperationRequest(req model.WalletOperationRequest, idempotencyKey string) error {
+	var fields []apperror.FieldError
+
 	if req.WalletID == uuid.Nil {
-		return fmt.Errorf("walletId is required")
+		fields = append(fields, apperror.FieldError{Field: "walletId", Message: "is required"})
 	}
 
 	if req.OperationType != model.OperationTypeDeposit && req.OperationType != model.OperationTypeWithdraw {
-		return fmt.Errorf("operationType must be DEPOSIT or WITHDRAW")
+		fields = append(fields, apperror.FieldError{Field: "operationType", Message: "must be DEPOSIT or WITHDRAW"})
 	}
 
 	if req.Amount.LessThanOrEqual(decimal.Zero) {
-		return fmt.Errorf("amount must be positive")
+		fields = append(fields, apperror.FieldError{Field: "amount", Message: "must be positive"})
+	} else if !model.ValidAmount(req.Amount) {
+		fields = append(fields, apperror.FieldError{Field: "amount", Message: model.AmountPrecisionMessage})
+	}
+
+	if req.Currency != "" && !model.ValidCurrencyCode(req.Currency) {
+		fields = append(fields, apperror.FieldError{Field: "currency", Message: "must be a three-letter ISO 4217 code"})
 	}
 
+	if req.ExpectedVersion != nil && *req.ExpectedVersion < 1 {
+		fields = append(fields, apperror.FieldError{Field: "expectedVersion", Message: "must be positive"})
+	}
+
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		fields = append(fields, apperror.FieldError{Field: IdempotencyKeyHeader, Message: "must be at most 255 characters"})
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation(fields...)
+	}
 	return nil
 }
 
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(model.ErrorResponse{Error: message})
-}
\ No newline at end of file
+// respondWithProblem отвечает в формате RFC 7807. Ошибки без кода считаются
+// внутренними: клиенту уходит INTERNAL_ERROR, подробности - только в лог
+func respondWithProblem(w http.ResponseWriter, r *http.Request, err error) {
+	appErr := apperror.From(err)
+	audit.SetError(r.Context(), string(appErr.Code))
+	if appErr.Status >= http.StatusInternalServerError {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	}
+
+	w.Header().Set("Content-Type", apperror.ProblemContentType)
+	w.WriteHeader(appErr.Status)
+	json.NewEncoder(w).Encode(appErr.Problem(r.URL.Path))
+}
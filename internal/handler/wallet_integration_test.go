@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/cache"
 	"wallet-service/internal/model"
 	"wallet-service/internal/service"
 	"github.com/google/uuid"
@@ -17,7 +21,10 @@ import (
 )
 
 // Mock сервиса для интеграционных тестов
-type MockWalletService struct{}
+type MockWalletService struct {
+	// listFilter - фильтр последнего вызова ListWallets
+	listFilter model.WalletFilter
+}
 
 func (m *MockWalletService) GetBalance(ctx context.Context, id uuid.UUID) (decimal.Decimal, error) {
 	if id == uuid.MustParse("123e4567-e89b-12d3-a456-426614174000") {
@@ -26,14 +33,101 @@ func (m *MockWalletService) GetBalance(ctx context.Context, id uuid.UUID) (decim
 	return decimal.Zero, service.ErrWalletNotFound
 }
 
-func (m *MockWalletService) ProcessOperation(ctx context.Context, op model.WalletOperation) error {
+func (m *MockWalletService) GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error) {
+	if id == uuid.MustParse("123e4567-e89b-12d3-a456-426614174000") {
+		return model.Wallet{ID: id, Balance: decimal.NewFromInt(1000), Version: 3}, nil
+	}
+	return model.Wallet{}, service.ErrWalletNotFound
+}
+
+func (m *MockWalletService) ProcessOperation(ctx context.Context, op model.WalletOperation) (model.Operation, error) {
+	if op.ExpectedVersion != nil && *op.ExpectedVersion != 3 {
+		return model.Operation{}, service.ErrVersionMismatch
+	}
+	// If-Match: * выполняется только для существующего кошелька, как в репозитории
+	if op.MustExist && op.WalletID != uuid.MustParse("123e4567-e89b-12d3-a456-426614174000") {
+		return model.Operation{}, service.ErrVersionMismatch
+	}
 	if op.WalletID == uuid.MustParse("00000000-0000-0000-0000-000000000000") {
-		return service.ErrWalletNotFound
+		return model.Operation{}, service.ErrWalletNotFound
 	}
 	if op.OperationType == model.OperationTypeWithdraw && op.Amount.GreaterThan(decimal.NewFromInt(1000)) {
-		return service.ErrInsufficientFunds
+		return model.Operation{}, service.ErrInsufficientFunds
+	}
+	return model.Operation{
+		ID:            uuid.MustParse("9b2f4c3e-6c1a-4f7e-8d2b-1a3c5e7f9b0d"),
+		WalletID:      op.WalletID,
+		OperationType: op.OperationType,
+		Amount:        op.Amount,
+		WalletVersion: 4,
+	}, nil
+}
+
+// ListWallets отдает тестовый кошелек; при limit=1 есть следующая страница
+func (m *MockWalletService) ListWallets(ctx context.Context, filter model.WalletFilter) (model.WalletPage, error) {
+	m.listFilter = filter
+	wallet, _ := m.GetWallet(ctx, uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"))
+	page := model.WalletPage{Wallets: []model.Wallet{wallet}}
+	if filter.Limit == 1 {
+		page.Next = &model.WalletCursor{Sort: model.SortBalanceDesc, Value: "1000", ID: wallet.ID}
+	}
+	return page, nil
+}
+
+func (m *MockWalletService) UpdateMetadata(ctx context.Context, id uuid.UUID, meta model.WalletMetadata) (model.Wallet, error) {
+	wallet, err := m.GetWallet(ctx, id)
+	if err != nil {
+		return model.Wallet{}, err
+	}
+	if meta.OwnerRef != nil {
+		wallet.OwnerRef = *meta.OwnerRef
+	}
+	if meta.DisplayName != nil {
+		wallet.DisplayName = *meta.DisplayName
+	}
+	wallet.Labels = meta.Labels
+	wallet.Version++
+	return wallet, nil
+}
+
+// Операция на 100, из которых 40 уже сторнировано
+var testOperationID = uuid.MustParse("5f0c8a4e-2b7d-4e1f-9a6c-3d8b0e2f4a6c")
+
+func (m *MockWalletService) GetOperation(ctx context.Context, id uuid.UUID) (model.Operation, error) {
+	if id != testOperationID {
+		return model.Operation{}, apperror.ErrOperationNotFound
 	}
-	return nil
+	return model.Operation{
+		ID:             id,
+		WalletID:       uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+		OperationType:  model.OperationTypeDeposit,
+		Amount:         decimal.NewFromInt(100),
+		ReversedAmount: decimal.NewFromInt(40),
+		WalletVersion:  2,
+	}, nil
+}
+
+func (m *MockWalletService) ReverseOperation(ctx context.Context, rev model.Reversal) (model.Operation, error) {
+	original, err := m.GetOperation(ctx, rev.OperationID)
+	if err != nil {
+		return model.Operation{}, err
+	}
+	remaining := original.Amount.Sub(original.ReversedAmount)
+	amount := remaining
+	if rev.Amount != nil {
+		if rev.Amount.GreaterThan(remaining) {
+			return model.Operation{}, apperror.ErrReversalExceeded.WithDetails(map[string]any{"remaining": remaining})
+		}
+		amount = *rev.Amount
+	}
+	return model.Operation{
+		ID:            uuid.New(),
+		WalletID:      original.WalletID,
+		OperationType: original.OperationType.Opposite(),
+		Amount:        amount,
+		WalletVersion: 4,
+		ReversalOf:    &original.ID,
+	}, nil
 }
 
 func TestWalletHandler_ProcessOperation_Success(t *testing.T) {
@@ -57,10 +151,12 @@ func TestWalletHandler_ProcessOperation_Success(t *testing.T) {
 
 	// Проверяем результат
 	assert.Equal(t, http.StatusOK, rr.Code)
-	
-	var response map[string]string
+	assert.Equal(t, `"4"`, rr.Header().Get("ETag"))
+
+	var response model.OperationResponse
 	json.Unmarshal(rr.Body.Bytes(), &response)
-	assert.Equal(t, "success", response["status"])
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, uuid.MustParse("9b2f4c3e-6c1a-4f7e-8d2b-1a3c5e7f9b0d"), response.Operation.ID)
 }
 
 func TestWalletHandler_GetBalance_Success(t *testing.T) {
@@ -136,4 +232,280 @@ func TestWalletHandler_ProcessOperation_InvalidUUID(t *testing.T) {
 	router.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusBadRequest, rr.Code)
-}
\ No newline at end of file
+}
+
+func TestWalletHandler_GetBalance_ETag(t *testing.T) {
+	service := &MockWalletService{}
+	handler := NewWalletHandler(service)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/wallets/{walletId}", handler.GetBalance).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/api/v1/wallets/123e4567-e89b-12d3-a456-426614174000", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"3"`, rr.Header().Get("ETag"))
+
+	var response model.BalanceResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, 3, response.Version)
+	// Без овердрафта доступен весь баланс
+	assert.True(t, response.CreditLimit.IsZero())
+	assert.True(t, response.Available.Equal(response.Balance))
+}
+
+func TestWalletHandler_GetBalance_NotModified(t *testing.T) {
+	service := &MockWalletService{}
+	handler := NewWalletHandler(service)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/wallets/{walletId}", handler.GetBalance).Methods("GET")
+
+	// Версия не изменилась - тело не отдаем
+	req := httptest.NewRequest("GET", "/api/v1/wallets/123e4567-e89b-12d3-a456-426614174000", nil)
+	req.Header.Set("If-None-Match", `W/"3"`)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.Bytes())
+
+	// Устаревший ETag - отдаем актуальное состояние
+	req = httptest.NewRequest("GET", "/api/v1/wallets/123e4567-e89b-12d3-a456-426614174000", nil)
+	req.Header.Set("If-None-Match", `"2"`)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+// bypassWalletService запоминает, просил ли запрос читать мимо кеша
+type bypassWalletService struct {
+	MockWalletService
+	bypassed bool
+}
+
+func (m *bypassWalletService) GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error) {
+	m.bypassed = cache.Bypassed(ctx)
+	return m.MockWalletService.GetWallet(ctx, id)
+}
+
+func TestWalletHandler_GetBalance_NoCache(t *testing.T) {
+	service := &bypassWalletService{}
+	router := NewRouter(service, nil, RouterOptions{})
+
+	for header, bypassed := range map[string]bool{"": false, "max-age=0": false, "max-age=0, No-Cache": true} {
+		req := httptest.NewRequest("GET", "/api/v1/wallets/123e4567-e89b-12d3-a456-426614174000", nil)
+		req.Header.Set("Cache-Control", header)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, bypassed, service.bypassed, header)
+	}
+}
+
+func TestWalletHandler_ProcessOperation_IfMatch(t *testing.T) {
+	service := &MockWalletService{}
+	handler := NewWalletHandler(service)
+
+	existing := uuid.MustParse("123e4567-e89b-12d3-a456-426614174000")
+
+	tests := []struct {
+		name     string
+		walletID uuid.UUID
+		ifMatch  string
+		expected int
+	}{
+		{"current version", existing, `"3"`, http.StatusOK},
+		{"stale version", existing, `"2"`, http.StatusPreconditionFailed},
+		{"any version", existing, "*", http.StatusOK},
+		// "*" не создает кошелек пополнением
+		{"any version of a missing wallet", uuid.New(), "*", http.StatusPreconditionFailed},
+		// If-Match сравнивается строго: слабый тег не совпадает даже с текущей версией
+		{"weak etag", existing, `W/"3"`, http.StatusPreconditionFailed},
+		{"malformed weak etag", existing, `W/3`, http.StatusBadRequest},
+		{"malformed", existing, "3", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(model.WalletOperationRequest{
+				WalletID:      tt.walletID,
+				OperationType: model.OperationTypeDeposit,
+				Amount:        decimal.NewFromInt(100),
+			})
+			req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+			req.Header.Set("If-Match", tt.ifMatch)
+			rr := httptest.NewRecorder()
+			handler.ProcessOperation(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Code)
+		})
+	}
+}
+
+func TestWalletHandler_ProcessOperation_ExpectedVersionConflict(t *testing.T) {
+	service := &MockWalletService{}
+	handler := NewWalletHandler(service)
+
+	version := 3
+	reqBody := model.WalletOperationRequest{
+		WalletID:        uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+		OperationType:   model.OperationTypeDeposit,
+		Amount:          decimal.NewFromInt(100),
+		ExpectedVersion: &version,
+	}
+	body, _ := json.Marshal(reqBody)
+
+	// Заголовок и тело противоречат друг другу
+	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+	req.Header.Set("If-Match", `"4"`)
+	rr := httptest.NewRecorder()
+	handler.ProcessOperation(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestWalletHandler_ProcessOperation_ValidationProblem(t *testing.T) {
+	service := &MockWalletService{}
+	handler := NewWalletHandler(service)
+
+	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader([]byte(`{"operationType":"TRANSFER","amount":"-1"}`)))
+	rr := httptest.NewRecorder()
+	handler.ProcessOperation(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, apperror.ProblemContentType, rr.Header().Get("Content-Type"))
+
+	var problem apperror.Problem
+	json.Unmarshal(rr.Body.Bytes(), &problem)
+	assert.Equal(t, apperror.CodeValidationFailed, problem.Code)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "/api/v1/wallet", problem.Instance)
+
+	// Все ошибки по полям возвращаются разом
+	var fields []string
+	for _, f := range problem.Errors {
+		fields = append(fields, f.Field)
+	}
+	assert.Equal(t, []string{"walletId", "operationType", "amount"}, fields)
+}
+
+func TestWalletHandler_ProcessOperation_AmountPrecision(t *testing.T) {
+	service := &MockWalletService{}
+	handler := NewWalletHandler(service)
+
+	// Postgres округлил бы 10.005 до 10.01, а 10^18 не поместилось бы в колонку
+	for _, amount := range []string{"10.005", "1000000000000000000"} {
+		body := `{"walletId":"123e4567-e89b-12d3-a456-426614174000","operationType":"DEPOSIT","amount":"` + amount + `"}`
+		req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader([]byte(body)))
+		rr := httptest.NewRecorder()
+		handler.ProcessOperation(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, amount)
+		var problem apperror.Problem
+		json.Unmarshal(rr.Body.Bytes(), &problem)
+		if assert.Len(t, problem.Errors, 1) {
+			assert.Equal(t, "amount", problem.Errors[0].Field)
+			assert.Equal(t, model.AmountPrecisionMessage, problem.Errors[0].Message)
+		}
+	}
+}
+
+func TestWalletHandler_ProcessOperation_InsufficientFundsProblem(t *testing.T) {
+	service := &MockWalletService{}
+	handler := NewWalletHandler(service)
+
+	reqBody := model.WalletOperationRequest{
+		WalletID:      uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"),
+		OperationType: model.OperationTypeWithdraw,
+		Amount:        decimal.NewFromInt(5000),
+	}
+	body, _ := json.Marshal(reqBody)
+
+	req := httptest.NewRequest("POST", "/api/v1/wallet", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	handler.ProcessOperation(rr, req)
+
+	var problem apperror.Problem
+	json.Unmarshal(rr.Body.Bytes(), &problem)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, apperror.CodeInsufficientFunds, problem.Code)
+}
+
+func TestRespondWithProblem_WrappedError(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/v1/wallets/123e4567-e89b-12d3-a456-426614174000", nil)
+
+	// Обернутая доменная ошибка сохраняет свой код
+	rr := httptest.NewRecorder()
+	respondWithProblem(rr, req, fmt.Errorf("lookup failed: %w", service.ErrWalletNotFound))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Неизвестная ошибка не раскрывается клиенту
+	rr = httptest.NewRecorder()
+	respondWithProblem(rr, req, errors.New("pq: connection refused"))
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "connection refused")
+}
+
+func TestWalletHandler_GetOperation(t *testing.T) {
+	router := NewRouter(&MockWalletService{}, nil, RouterOptions{})
+
+	req := httptest.NewRequest("GET", "/api/v1/operations/"+testOperationID.String(), nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var op model.Operation
+	json.Unmarshal(rr.Body.Bytes(), &op)
+	assert.True(t, decimal.NewFromInt(40).Equal(op.ReversedAmount))
+
+	req = httptest.NewRequest("GET", "/api/v1/operations/"+uuid.NewString(), nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestWalletHandler_ReverseOperation(t *testing.T) {
+	router := NewRouter(&MockWalletService{}, nil, RouterOptions{})
+	path := "/api/v1/operations/" + testOperationID.String() + "/reverse"
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+		code     apperror.Code
+	}{
+		{"full remaining amount", "", http.StatusOK, ""},
+		{"partial amount", `{"amount":"25.50"}`, http.StatusOK, ""},
+		{"more than remaining", `{"amount":"60.01"}`, http.StatusUnprocessableEntity, apperror.CodeReversalExceeded},
+		{"negative amount", `{"amount":"-1"}`, http.StatusBadRequest, apperror.CodeValidationFailed},
+		{"too many decimal places", `{"amount":"0.001"}`, http.StatusBadRequest, apperror.CodeValidationFailed},
+		{"malformed body", `{"amount":`, http.StatusBadRequest, apperror.CodeMalformedRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", path, bytes.NewReader([]byte(tt.body)))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expected, rr.Code)
+			if tt.code != "" {
+				var problem apperror.Problem
+				json.Unmarshal(rr.Body.Bytes(), &problem)
+				assert.Equal(t, tt.code, problem.Code)
+				return
+			}
+
+			var response model.OperationResponse
+			json.Unmarshal(rr.Body.Bytes(), &response)
+			assert.Equal(t, model.OperationTypeWithdraw, response.Operation.OperationType)
+			assert.Equal(t, &testOperationID, response.Operation.ReversalOf)
+		})
+	}
+}
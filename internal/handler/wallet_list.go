@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// maxOwnerRefLength и maxDisplayNameLength совпадают с размерами колонок wallets
+	maxOwnerRefLength    = 128
+	maxDisplayNameLength = 256
+	maxLabels            = 32
+	maxLabelValueLength  = 256
+)
+
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_./-]{1,63}$`)
+
+// ListWallets - страница кошельков тенанта:
+// GET /api/v1/wallets?owner=&label=key:value&status=&minBalance=&maxBalance=&sort=&cursor=&limit=
+func (h *WalletHandler) ListWallets(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseWalletFilter(r)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	page, err := h.walletService.ListWallets(r.Context(), filter)
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	resp := model.WalletListResponse{Wallets: make([]model.BalanceResponse, 0, len(page.Wallets))}
+	for _, wallet := range page.Wallets {
+		resp.Wallets = append(resp.Wallets, balanceResponse(wallet))
+	}
+	if page.Next != nil {
+		resp.NextCursor = page.Next.Encode()
+	}
+	respondWithJSON(w, resp)
+}
+
+// UpdateMetadata меняет владельца, имя и метки кошелька (PATCH /api/v1/wallets/{walletId})
+func (h *WalletHandler) UpdateMetadata(w http.ResponseWriter, r *http.Request) {
+	walletID, err := uuid.Parse(mux.Vars(r)["walletId"])
+	if err != nil {
+		respondWithProblem(w, r, apperror.Validation(apperror.FieldError{Field: "walletId", Message: "must be a valid UUID"}))
+		return
+	}
+
+	var req model.WalletMetadataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithProblem(w, r, apperror.ErrMalformedRequest.WithDetail("request body is not valid JSON"))
+		return
+	}
+	if err := validateMetadataRequest(req); err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	wallet, err := h.walletService.UpdateMetadata(r.Context(), walletID, model.WalletMetadata{
+		OwnerRef:    req.OwnerRef,
+		DisplayName: req.DisplayName,
+		Labels:      req.Labels,
+	})
+	if err != nil {
+		respondWithProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(wallet.Version))
+	respondWithJSON(w, balanceResponse(wallet))
+}
+
+func validateMetadataRequest(req model.WalletMetadataRequest) error {
+	var fields []apperror.FieldError
+
+	if req.OwnerRef == nil && req.DisplayName == nil && req.Labels == nil {
+		fields = append(fields, apperror.FieldError{Field: "body", Message: "must set ownerRef, displayName or labels"})
+	}
+	if req.OwnerRef != nil && len(*req.OwnerRef) > maxOwnerRefLength {
+		fields = append(fields, apperror.FieldError{Field: "ownerRef", Message: "must be at most 128 characters"})
+	}
+	if req.DisplayName != nil && len(*req.DisplayName) > maxDisplayNameLength {
+		fields = append(fields, apperror.FieldError{Field: "displayName", Message: "must be at most 256 characters"})
+	}
+	if len(req.Labels) > maxLabels {
+		fields = append(fields, apperror.FieldError{Field: "labels", Message: "must have at most 32 labels"})
+	}
+	for key, value := range req.Labels {
+		field := "labels." + key
+		if !labelKeyPattern.MatchString(key) {
+			fields = append(fields, apperror.FieldError{Field: field, Message: "key must be 1-63 characters of A-Z, a-z, 0-9, _, ., / and -"})
+		}
+		if len(value) > maxLabelValueLength {
+			fields = append(fields, apperror.FieldError{Field: field, Message: "must be at most 256 characters"})
+		}
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation(fields...)
+	}
+	return nil
+}
+
+func parseWalletFilter(r *http.Request) (model.WalletFilter, error) {
+	q := r.URL.Query()
+	filter := model.WalletFilter{OwnerRef: q.Get("owner")}
+	var fields []apperror.FieldError
+
+	for _, label := range q["label"] {
+		key, value, ok := strings.Cut(label, ":")
+		if !ok || !labelKeyPattern.MatchString(key) {
+			fields = append(fields, apperror.FieldError{Field: "label", Message: fmt.Sprintf("%q must be key:value", label)})
+			continue
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		filter.Labels[key] = value
+	}
+
+	switch status := model.WalletStatus(q.Get("status")); status {
+	case "", model.WalletActive, model.WalletFrozen:
+		filter.Status = status
+	default:
+		fields = append(fields, apperror.FieldError{Field: "status", Message: "must be ACTIVE or FROZEN"})
+	}
+
+	parseDecimal := func(name string) *decimal.Decimal {
+		s := q.Get(name)
+		if s == "" {
+			return nil
+		}
+		d, err := decimal.NewFromString(s)
+		if err != nil {
+			fields = append(fields, apperror.FieldError{Field: name, Message: "must be a decimal number"})
+			return nil
+		}
+		return &d
+	}
+	filter.MinBalance = parseDecimal("minBalance")
+	filter.MaxBalance = parseDecimal("maxBalance")
+	if filter.MinBalance != nil && filter.MaxBalance != nil && filter.MinBalance.GreaterThan(*filter.MaxBalance) {
+		fields = append(fields, apperror.FieldError{Field: "minBalance", Message: "must not exceed maxBalance"})
+	}
+
+	filter.Sort = model.WalletSort(q.Get("sort"))
+	if filter.Sort != "" && !filter.Sort.Valid() {
+		sorts := make([]string, len(model.WalletSorts))
+		for i, s := range model.WalletSorts {
+			sorts[i] = string(s)
+		}
+		fields = append(fields, apperror.FieldError{Field: "sort", Message: "must be one of " + strings.Join(sorts, ", ")})
+	}
+
+	if s := q.Get("cursor"); s != "" {
+		cursor, err := model.ParseWalletCursor(s)
+		if err != nil {
+			fields = append(fields, apperror.FieldError{Field: "cursor", Message: "is not a cursor returned by this API"})
+		}
+		filter.After = &cursor
+	}
+
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			fields = append(fields, apperror.FieldError{Field: "limit", Message: "must be a non-negative integer"})
+		}
+		filter.Limit = n
+	}
+
+	if len(fields) > 0 {
+		return model.WalletFilter{}, apperror.Validation(fields...)
+	}
+	return filter, nil
+}
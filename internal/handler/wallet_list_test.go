@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletHandler_ListWallets(t *testing.T) {
+	service := &MockWalletService{}
+	router := NewRouter(service, nil, RouterOptions{})
+
+	req := httptest.NewRequest("GET", "/api/v1/wallets?owner=customer-7&label=segment:vip&label=region:eu&status=ACTIVE&minBalance=10&sort=-balance&limit=1", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	filter := service.listFilter
+	assert.Equal(t, "customer-7", filter.OwnerRef)
+	assert.Equal(t, map[string]string{"segment": "vip", "region": "eu"}, filter.Labels)
+	assert.Equal(t, model.WalletActive, filter.Status)
+	assert.Equal(t, "10", filter.MinBalance.String())
+	assert.Nil(t, filter.MaxBalance)
+	assert.Equal(t, model.SortBalanceDesc, filter.Sort)
+
+	var resp model.WalletListResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	require.Len(t, resp.Wallets, 1)
+	assert.Equal(t, map[string]string{}, resp.Wallets[0].Labels)
+	require.NotEmpty(t, resp.NextCursor)
+
+	// Курсор возвращается в запрос следующей страницы как есть
+	req = httptest.NewRequest("GET", "/api/v1/wallets?sort=-balance&cursor="+resp.NextCursor, nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotNil(t, service.listFilter.After)
+	assert.Equal(t, "1000", service.listFilter.After.Value)
+}
+
+func TestWalletHandler_ListWallets_Validation(t *testing.T) {
+	router := NewRouter(&MockWalletService{}, nil, RouterOptions{})
+
+	req := httptest.NewRequest("GET", "/api/v1/wallets?label=segment&status=CLOSED&minBalance=5&maxBalance=1&sort=name&cursor=abc&limit=-1", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var problem apperror.Problem
+	json.Unmarshal(rr.Body.Bytes(), &problem)
+	fields := map[string]bool{}
+	for _, f := range problem.Errors {
+		fields[f.Field] = true
+	}
+	for _, field := range []string{"label", "status", "minBalance", "sort", "cursor", "limit"} {
+		assert.True(t, fields[field], field)
+	}
+}
+
+func TestWalletHandler_UpdateMetadata(t *testing.T) {
+	router := NewRouter(&MockWalletService{}, nil, RouterOptions{})
+	path := "/api/v1/wallets/123e4567-e89b-12d3-a456-426614174000"
+
+	body := `{"ownerRef":"customer-7","displayName":"Main","labels":{"segment":"vip"}}`
+	req := httptest.NewRequest("PATCH", path, bytes.NewReader([]byte(body)))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"4"`, rr.Header().Get("ETag"))
+	var resp model.BalanceResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "customer-7", resp.OwnerRef)
+	assert.Equal(t, "Main", resp.DisplayName)
+	assert.Equal(t, "vip", resp.Labels["segment"])
+
+	for _, body := range []string{`{}`, `{"labels":{"bad key":"x"}}`} {
+		req := httptest.NewRequest("PATCH", path, bytes.NewReader([]byte(body)))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
@@ -0,0 +1,34 @@
+// Package metrics содержит счетчики сервиса, публикуемые через expvar (/debug/vars)
+package metrics
+
+import "expvar"
+
+var (
+	// OperationAttempts - распределение операций по числу попыток ("1", "2", ...)
+	OperationAttempts = expvar.NewMap("wallet_operation_attempts")
+	// OperationRetries - общее число повторов операций
+	OperationRetries = expvar.NewInt("wallet_operation_retries")
+	// OperationRetriesExhausted - операции, которые так и не удалось применить
+	OperationRetriesExhausted = expvar.NewInt("wallet_operation_retries_exhausted")
+)
+
+var (
+	// CacheHits и CacheMisses - чтения кошельков из кеша и мимо него
+	CacheHits   = expvar.NewInt("wallet_cache_hits")
+	CacheMisses = expvar.NewInt("wallet_cache_misses")
+	// CacheInvalidations - записи кеша, устаревшие из-за изменения кошелька
+	CacheInvalidations = expvar.NewInt("wallet_cache_invalidations")
+)
+
+var (
+	// ReplicaReads - чтения кошельков с реплик
+	ReplicaReads = expvar.NewInt("wallet_replica_reads")
+	// ReplicaFallbacks - чтения, ушедшие на основной сервер, потому что реплика
+	// не догнала токен согласованности или не ответила
+	ReplicaFallbacks = expvar.NewInt("wallet_replica_fallbacks")
+)
+
+// PublishPools публикует статистику пулов соединений с базой как wallet_db_pools
+func PublishPools(stats func() any) {
+	expvar.Publish("wallet_db_pools", expvar.Func(stats))
+}
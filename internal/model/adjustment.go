@@ -0,0 +1,73 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type AdjustmentDirection string
+
+const (
+    AdjustmentCredit AdjustmentDirection = "CREDIT"
+    AdjustmentDebit  AdjustmentDirection = "DEBIT"
+)
+
+// OperationType - операция, которой корректировка применяется к кошельку
+func (d AdjustmentDirection) OperationType() OperationType {
+    if d == AdjustmentCredit {
+        return OperationTypeDeposit
+    }
+    return OperationTypeWithdraw
+}
+
+type AdjustmentStatus string
+
+const (
+    AdjustmentPending  AdjustmentStatus = "PENDING"
+    AdjustmentApproved AdjustmentStatus = "APPROVED"
+    AdjustmentRejected AdjustmentStatus = "REJECTED"
+)
+
+// AdjustmentReasons - допустимые коды причин корректировки
+var AdjustmentReasons = map[string]bool{
+    "ERROR_CORRECTION": true,
+    "CHARGEBACK":       true,
+    "GOODWILL":         true,
+    "FEE_REFUND":       true,
+    "MIGRATION":        true,
+    "OTHER":            true,
+}
+
+// Adjustment - ручная корректировка баланса. Предлагает один оператор,
+// одобряет другой; к кошельку применяется только одобренная
+type Adjustment struct {
+    ID            uuid.UUID           `json:"id"`
+    WalletID      uuid.UUID           `json:"walletId"`
+    Direction     AdjustmentDirection `json:"direction"`
+    Amount        decimal.Decimal     `json:"amount"`
+    ReasonCode    string              `json:"reasonCode"`
+    Comment       string              `json:"comment"`
+    Status        AdjustmentStatus    `json:"status"`
+    ProposedBy    string              `json:"proposedBy"`
+    ProposedAt    time.Time           `json:"proposedAt"`
+    ReviewedBy    string              `json:"reviewedBy,omitempty"`
+    ReviewedAt    *time.Time          `json:"reviewedAt,omitempty"`
+    ReviewComment string              `json:"reviewComment,omitempty"`
+    // OperationID - операция, которой применена одобренная корректировка
+    OperationID   *uuid.UUID          `json:"operationId,omitempty"`
+}
+
+// AdjustmentReview - решение оператора по корректировке
+type AdjustmentReview struct {
+    AdjustmentID uuid.UUID
+    Operator     string
+    Comment      string
+}
+
+// AdjustmentFilter - отбор корректировок; пустые поля не ограничивают выборку
+type AdjustmentFilter struct {
+    WalletID *uuid.UUID
+    Status   AdjustmentStatus
+}
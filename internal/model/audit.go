@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditOutcome string
+
+const (
+    AuditSuccess AuditOutcome = "SUCCESS"
+    AuditFailure AuditOutcome = "FAILURE"
+)
+
+// AuditRecord - запись журнала аудита об одном изменяющем запросе или запуске планировщика
+type AuditRecord struct {
+    ID          int64          `json:"id"`
+    CreatedAt   time.Time      `json:"createdAt"`
+    RequestID   string         `json:"requestId"`
+    // TenantID - тенант запроса; пустой, если запрос отклонен до его определения
+    TenantID    string         `json:"tenantId,omitempty"`
+    // Actor - кто выполнил запрос: оператор админского API по токену,
+    // тенант:отпечаток API-ключа клиента, scheduler или anonymous
+    Actor       string         `json:"actor"`
+    ClientIP    string         `json:"clientIp,omitempty"`
+    Method      string         `json:"method"`
+    // Endpoint - шаблон маршрута, например /api/v1/operations/{operationId}/reverse
+    Endpoint    string         `json:"endpoint"`
+    Path        string         `json:"path"`
+    WalletID    *uuid.UUID     `json:"walletId,omitempty"`
+    OperationID *uuid.UUID     `json:"operationId,omitempty"`
+    // Summary - поля тела запроса верхнего уровня; длинные строки обрезаны, вложенные значения свернуты
+    Summary     map[string]any `json:"summary,omitempty"`
+    Status      int            `json:"status"`
+    Outcome     AuditOutcome   `json:"outcome"`
+    ErrorCode   string         `json:"errorCode,omitempty"`
+    LatencyMs   float64        `json:"latencyMs"`
+}
+
+// AuditFilter - отбор записей аудита; пустые поля не ограничивают выборку.
+// Записи идут по возрастанию id, AfterID - курсор следующей страницы
+type AuditFilter struct {
+    Actor    string
+    WalletID *uuid.UUID
+    Endpoint string
+    Outcome  AuditOutcome
+    From     *time.Time
+    To       *time.Time
+    AfterID  int64
+    Limit    int
+}
+
+// AuditPage - страница журнала аудита; NextAfter - курсор, nil на последней странице
+type AuditPage struct {
+    Records   []AuditRecord `json:"records"`
+    NextAfter *int64        `json:"nextAfter,omitempty"`
+}
@@ -0,0 +1,164 @@
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Hash - SHA-256; в JSON - шестнадцатеричная строка
+type Hash []byte
+
+// GenesisHash - предыдущий хеш первого звена цепочки кошелька
+var GenesisHash = Hash(make([]byte, sha256.Size))
+
+func (h Hash) MarshalText() ([]byte, error) {
+    return []byte(hex.EncodeToString(h)), nil
+}
+
+func (h *Hash) UnmarshalText(text []byte) error {
+    b, err := hex.DecodeString(string(text))
+    if err != nil {
+        return err
+    }
+    *h = b
+    return nil
+}
+
+func (h Hash) Equal(other Hash) bool {
+    return bytes.Equal(h, other)
+}
+
+const (
+    // ChainFormatV1 - первый формат хеша звена: без тенанта, из комиссии - только
+    // итог и кошелек комиссий. Звенья, записанные до v2, проверяются по нему
+    ChainFormatV1 = 1
+    // ChainFormatV2 - v1 плюс тенант операции и все поля комиссии
+    ChainFormatV2 = 2
+    // ChainFormat - формат новых звеньев
+    ChainFormat = ChainFormatV2
+)
+
+// ChainHash - хеш звена в формате ChainFormat: неизменяемые поля операции, ее тенант,
+// номер звена и хеш предыдущего звена. Суммы - с двумя знаками, как в БД, время - в микросекундах
+func ChainHash(prev Hash, seq int64, tenantID string, op Operation) Hash {
+    reversalOf, adjustmentID := chainRefs(op)
+    var fee string
+    if op.Fee != nil {
+        fee = fmt.Sprintf("%s/%s/%s@%s#%s", op.Fee.Fixed.StringFixed(2), op.Fee.Variable.StringFixed(2),
+            op.Fee.Total.StringFixed(2), op.Fee.WalletID, op.Fee.OperationID)
+    }
+
+    h := sha256.New()
+    fmt.Fprintf(h, "v2|%s|%s|%d|%s|%s|%s|%s|%d|%s|%s|%s|%d|%x",
+        tenantID, op.WalletID, seq, op.ID, op.OperationType, op.Amount.StringFixed(2), op.BalanceAfter.StringFixed(2),
+        op.WalletVersion, reversalOf, adjustmentID, fee, op.CreatedAt.UnixMicro(), []byte(prev))
+    return h.Sum(nil)
+}
+
+// chainHashV1 - хеш звена в формате ChainFormatV1
+func chainHashV1(prev Hash, seq int64, op Operation) Hash {
+    reversalOf, adjustmentID := chainRefs(op)
+    var fee string
+    if op.Fee != nil {
+        fee = op.Fee.Total.StringFixed(2) + "@" + op.Fee.WalletID.String()
+    }
+
+    h := sha256.New()
+    fmt.Fprintf(h, "v1|%s|%d|%s|%s|%s|%s|%d|%s|%s|%s|%d|%x",
+        op.WalletID, seq, op.ID, op.OperationType, op.Amount.StringFixed(2), op.BalanceAfter.StringFixed(2),
+        op.WalletVersion, reversalOf, adjustmentID, fee, op.CreatedAt.UnixMicro(), []byte(prev))
+    return h.Sum(nil)
+}
+
+func chainRefs(op Operation) (reversalOf, adjustmentID string) {
+    if op.ReversalOf != nil {
+        reversalOf = op.ReversalOf.String()
+    }
+    if op.AdjustmentID != nil {
+        adjustmentID = op.AdjustmentID.String()
+    }
+    return reversalOf, adjustmentID
+}
+
+// ChainLink - звено цепочки вместе с операцией, которую оно заверяет
+type ChainLink struct {
+    Seq       int64
+    // Format - формат хеша звена (ChainFormatV1, ChainFormatV2)
+    Format    int
+    PrevHash  Hash
+    Hash      Hash
+    Operation Operation
+    // TenantID - тенант операции из operations
+    TenantID  string
+    // Missing - операции звена нет в operations
+    Missing   bool
+}
+
+// ExpectedHash - хеш, который должен быть у звена в его формате при хеше
+// предыдущего звена prev; nil - формат неизвестен
+func (l ChainLink) ExpectedHash(prev Hash) Hash {
+    switch l.Format {
+    case ChainFormatV1:
+        return chainHashV1(prev, l.Seq, l.Operation)
+    case ChainFormatV2:
+        return ChainHash(prev, l.Seq, l.TenantID, l.Operation)
+    }
+    return nil
+}
+
+// ChainHead - последнее звено цепочки кошелька
+type ChainHead struct {
+    WalletID    uuid.UUID  `json:"walletId"`
+    Seq         int64      `json:"seq"`
+    Hash        Hash       `json:"hash"`
+    OperationID *uuid.UUID `json:"operationId,omitempty"`
+}
+
+// ChainBreak - первое нарушенное звено
+type ChainBreak struct {
+    Seq         int64      `json:"seq"`
+    OperationID *uuid.UUID `json:"operationId,omitempty"`
+    Reason      string     `json:"reason"`
+}
+
+// ChainVerification - результат проверки цепочки кошелька
+type ChainVerification struct {
+    WalletID   uuid.UUID   `json:"walletId"`
+    Valid      bool        `json:"valid"`
+    // Checked - сколько звеньев проверено до первого нарушения
+    Checked    int64       `json:"checked"`
+    Head       *ChainHead  `json:"head,omitempty"`
+    // Checkpoint - голова цепочки из последней подписанной контрольной точки
+    Checkpoint *ChainHead  `json:"checkpoint,omitempty"`
+    BrokenAt   *ChainBreak `json:"brokenAt,omitempty"`
+}
+
+// ChainCheckpoint - подписанная контрольная точка: головы цепочек, изменившихся
+// с предыдущей точки. Digest покрывает и digest предыдущей точки, поэтому
+// точки тоже образуют цепочку
+type ChainCheckpoint struct {
+    ID         int64       `json:"id"`
+    CreatedAt  time.Time   `json:"createdAt"`
+    PrevDigest Hash        `json:"prevDigest"`
+    Digest     Hash        `json:"digest"`
+    // Signature - Ed25519-подпись Digest
+    Signature  []byte      `json:"signature"`
+    Heads      []ChainHead `json:"heads"`
+}
+
+// CheckpointDigest - хеш подписываемого содержимого контрольной точки.
+// Головы должны идти по возрастанию walletId
+func CheckpointDigest(prev Hash, createdAt time.Time, heads []ChainHead) Hash {
+    h := sha256.New()
+    fmt.Fprintf(h, "wallet-service chain checkpoint v1\nprev %x\ntime %s\n",
+        []byte(prev), createdAt.UTC().Format(time.RFC3339Nano))
+    for _, head := range heads {
+        fmt.Fprintf(h, "%s %d %x\n", head.WalletID, head.Seq, []byte(head.Hash))
+    }
+    return h.Sum(nil)
+}
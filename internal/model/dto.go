@@ -1,21 +1,133 @@
 package model
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
 type WalletOperationRequest struct {
+    WalletID        uuid.UUID       `json:"walletId"`
+    OperationType   OperationType   `json:"operationType"`
+    Amount          decimal.Decimal `json:"amount"`
+    // Currency - валюта кошелька; для существующего кошелька должна совпадать с его валютой
+    Currency        string          `json:"currency,omitempty"`
+    ExpectedVersion *int            `json:"expectedVersion,omitempty"`
+}
+
+type OperationResponse struct {
+    Status    string    `json:"status"`
+    Operation Operation `json:"operation"`
+}
+
+type ReverseOperationRequest struct {
+    // Amount - сумма частичного сторно; если не указана, сторнируется весь остаток
+    Amount *decimal.Decimal `json:"amount,omitempty"`
+}
+
+type BalanceResponse struct {
+    WalletID    uuid.UUID         `json:"walletId"`
+    Balance     decimal.Decimal   `json:"balance"`
+    // CreditLimit - разрешенный овердрафт; Available = Balance + CreditLimit
+    CreditLimit decimal.Decimal   `json:"creditLimit"`
+    Available   decimal.Decimal   `json:"available"`
+    Tier        string            `json:"tier"`
+    Currency    string            `json:"currency"`
+    Status      WalletStatus      `json:"status"`
+    OwnerRef    string            `json:"ownerRef,omitempty"`
+    DisplayName string            `json:"displayName,omitempty"`
+    Labels      map[string]string `json:"labels"`
+    CreatedAt   time.Time         `json:"createdAt"`
+    UpdatedAt   time.Time         `json:"updatedAt"`
+    Version     int               `json:"version"`
+}
+
+// WalletListResponse - страница списка кошельков; NextCursor пуст на последней странице
+type WalletListResponse struct {
+    Wallets    []BalanceResponse `json:"wallets"`
+    NextCursor string            `json:"nextCursor,omitempty"`
+}
+
+// WalletMetadataRequest - PATCH кошелька: не переданное поле не меняется,
+// labels заменяются целиком ({} удаляет все метки)
+type WalletMetadataRequest struct {
+    OwnerRef    *string           `json:"ownerRef"`
+    DisplayName *string           `json:"displayName"`
+    Labels      map[string]string `json:"labels"`
+}
+
+// ScheduleOperationRequest - разовая операция задается RunAt, повторяющаяся - Schedule.
+// Если заданы оба поля, первый запуск повторяющейся операции - не раньше RunAt
+type ScheduleOperationRequest struct {
     WalletID      uuid.UUID       `json:"walletId"`
     OperationType OperationType   `json:"operationType"`
     Amount        decimal.Decimal `json:"amount"`
+    RunAt         *time.Time      `json:"runAt,omitempty"`
+    Schedule      string          `json:"schedule,omitempty"`
 }
 
-type BalanceResponse struct {
+// BalanceAsOfResponse - баланс кошелька на момент времени по истории проводок
+type BalanceAsOfResponse struct {
     WalletID uuid.UUID       `json:"walletId"`
     Balance  decimal.Decimal `json:"balance"`
+    AsOf     time.Time       `json:"asOf"`
+}
+
+type BalancesAsOfRequest struct {
+    WalletIDs []uuid.UUID `json:"walletIds"`
+    AsOf      *time.Time  `json:"asOf"`
+}
+
+// BalancesAsOfResponse - балансы в порядке запроса; несуществующие кошельки - в NotFound
+type BalancesAsOfResponse struct {
+    AsOf     time.Time             `json:"asOf"`
+    Balances []BalanceAsOfResponse `json:"balances"`
+    NotFound []uuid.UUID           `json:"notFound"`
+}
+
+type AdjustmentRequest struct {
+    WalletID   uuid.UUID           `json:"walletId"`
+    Direction  AdjustmentDirection `json:"direction"`
+    Amount     decimal.Decimal     `json:"amount"`
+    ReasonCode string              `json:"reasonCode"`
+    Comment    string              `json:"comment"`
+}
+
+type AdjustmentReviewRequest struct {
+    Comment string `json:"comment"`
+}
+
+// ChainCheckpointResponse - последняя контрольная точка и ключ проверки ее подписи
+type ChainCheckpointResponse struct {
+    ChainCheckpoint
+    // PublicKey - Ed25519-ключ проверки подписи, если сервис знает ключ подписи
+    PublicKey []byte `json:"publicKey,omitempty"`
+}
+
+type CreditLimitRequest struct {
+    CreditLimit decimal.Decimal `json:"creditLimit"`
+}
+
+type TierRequest struct {
+    Tier string `json:"tier"`
+}
+
+type StatusRequest struct {
+    Status WalletStatus `json:"status"`
 }
 
 type ErrorResponse struct {
     Error string `json:"error"`
-}
\ No newline at end of file
+}
+// HealthResponse - ответ /readyz с результатами проверок зависимостей
+type HealthResponse struct {
+    Status string                 `json:"status"`
+    Checks map[string]HealthCheck `json:"checks"`
+}
+
+type HealthCheck struct {
+    Status  string         `json:"status"`
+    Error   string         `json:"error,omitempty"`
+    Details map[string]any `json:"details,omitempty"`
+}
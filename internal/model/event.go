@@ -0,0 +1,70 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type BalanceEventType string
+
+const (
+    // EventSnapshot - текущее состояние кошелька: первое событие потока без
+    // lastEventId и замена пропущенных событий, если их слишком много
+    EventSnapshot  BalanceEventType = "snapshot"
+    // EventOperation - закоммиченная операция по кошельку
+    EventOperation BalanceEventType = "operation"
+    // EventUpdated - изменение настроек или метаданных кошелька без операции
+    EventUpdated   BalanceEventType = "updated"
+)
+
+// BalanceEvent - изменение баланса кошелька. Version - версия кошелька после
+// изменения; она же id события для возобновления потока
+type BalanceEvent struct {
+    Type          BalanceEventType `json:"type"`
+    WalletID      uuid.UUID        `json:"walletId"`
+    Balance       decimal.Decimal  `json:"balance"`
+    Version       int              `json:"version"`
+    OperationID   *uuid.UUID       `json:"operationId,omitempty"`
+    OperationType OperationType    `json:"operationType,omitempty"`
+    Amount        *decimal.Decimal `json:"amount,omitempty"`
+    At            time.Time        `json:"at"`
+}
+
+// OperationEvent - событие операции, уже записанной в журнал
+func OperationEvent(op Operation) BalanceEvent {
+    id, amount := op.ID, op.Amount
+    return BalanceEvent{
+        Type:          EventOperation,
+        WalletID:      op.WalletID,
+        Balance:       op.BalanceAfter,
+        Version:       op.WalletVersion,
+        OperationID:   &id,
+        OperationType: op.OperationType,
+        Amount:        &amount,
+        At:            op.CreatedAt,
+    }
+}
+
+// UpdatedEvent - событие изменения настроек или метаданных кошелька
+func UpdatedEvent(w Wallet) BalanceEvent {
+    return BalanceEvent{
+        Type:     EventUpdated,
+        WalletID: w.ID,
+        Balance:  w.Balance,
+        Version:  w.Version,
+        At:       w.UpdatedAt,
+    }
+}
+
+// SnapshotEvent - событие с текущим состоянием кошелька
+func SnapshotEvent(w Wallet, at time.Time) BalanceEvent {
+    return BalanceEvent{
+        Type:     EventSnapshot,
+        WalletID: w.ID,
+        Balance:  w.Balance,
+        Version:  w.Version,
+        At:       at,
+    }
+}
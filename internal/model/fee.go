@@ -0,0 +1,87 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultTier - тариф кошелька, которому тариф не назначен
+const DefaultTier = "standard"
+
+// FeeRule - комиссия за операцию: Fixed + Percent% от суммы, ограниченная Min и Max
+type FeeRule struct {
+    OperationType OperationType
+    // Tier - тариф кошелька; пустой - правило для всех тарифов
+    Tier          string
+    Fixed         decimal.Decimal
+    Percent       decimal.Decimal
+    Min           *decimal.Decimal
+    Max           *decimal.Decimal
+}
+
+// Compute считает комиссию с суммы операции. Результат округляется до копеек
+func (r FeeRule) Compute(amount decimal.Decimal) FeeBreakdown {
+    fee := FeeBreakdown{
+        Fixed:    r.Fixed,
+        Variable: amount.Mul(r.Percent).Div(decimal.NewFromInt(100)).Round(2),
+    }
+    fee.Total = fee.Fixed.Add(fee.Variable)
+    if r.Min != nil && fee.Total.LessThan(*r.Min) {
+        fee.Total = *r.Min
+    }
+    if r.Max != nil && fee.Total.GreaterThan(*r.Max) {
+        fee.Total = *r.Max
+    }
+    return fee
+}
+
+// FeeSchedule - тарифная сетка. Комиссии зачисляются на кошелек WalletID;
+// uuid.Nil отключает комиссии
+type FeeSchedule struct {
+    WalletID uuid.UUID
+    Rules    []FeeRule
+}
+
+// Rule подбирает правило для операции: правило тарифа важнее общего
+func (s FeeSchedule) Rule(opType OperationType, tier string) (FeeRule, bool) {
+    var fallback *FeeRule
+    for i, rule := range s.Rules {
+        if rule.OperationType != opType {
+            continue
+        }
+        if rule.Tier == tier && tier != "" {
+            return rule, true
+        }
+        if rule.Tier == "" && fallback == nil {
+            fallback = &s.Rules[i]
+        }
+    }
+    if fallback != nil {
+        return *fallback, true
+    }
+    return FeeRule{}, false
+}
+
+// HasTierRules сообщает, зависит ли комиссия за операцию от тарифа кошелька
+func (s FeeSchedule) HasTierRules(opType OperationType) bool {
+    for _, rule := range s.Rules {
+        if rule.OperationType == opType && rule.Tier != "" {
+            return true
+        }
+    }
+    return false
+}
+
+// FeeBreakdown - из чего сложилась комиссия операции. Списание уменьшает баланс
+// на amount + total, пополнение увеличивает на amount - total
+type FeeBreakdown struct {
+    Fixed          decimal.Decimal `json:"fixed"`
+    // Variable - процентная часть
+    Variable       decimal.Decimal `json:"variable"`
+    // Total - итоговая комиссия после ограничений min/max
+    Total          decimal.Decimal `json:"total"`
+    // WalletID - кошелек, на который зачислена комиссия
+    WalletID       uuid.UUID       `json:"walletId"`
+    // OperationID - операция FEE на кошельке комиссий
+    OperationID    uuid.UUID       `json:"operationId"`
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Account - счет двойной записи: кошелек или системный счет
+type Account string
+
+const (
+    // AccountCashIn - внешний источник пополнений
+    AccountCashIn         Account = "system:cash-in"
+    // AccountCashOut - внешний получатель списаний
+    AccountCashOut        Account = "system:cash-out"
+    // AccountOpeningBalance - входящие остатки кошельков на момент перехода на двойную запись
+    AccountOpeningBalance Account = "system:opening-balance"
+    // AccountAdjustments - встречный счет ручных корректировок
+    AccountAdjustments    Account = "system:adjustments"
+)
+
+const walletAccountPrefix = "wallet:"
+
+// WalletAccount - счет кошелька. Кошелек комиссий - тоже кошелек, его счет играет роль счета комиссий
+func WalletAccount(id uuid.UUID) Account {
+    return Account(walletAccountPrefix + id.String())
+}
+
+// Posting - запись проводки: положительная сумма увеличивает остаток счета
+type Posting struct {
+    Account Account         `json:"account"`
+    Amount  decimal.Decimal `json:"amount"`
+}
+
+// JournalEntry - проводка. Для операций id проводки совпадает с id операции
+type JournalEntry struct {
+    ID        uuid.UUID `json:"id"`
+    Postings  []Posting `json:"postings"`
+    CreatedAt time.Time `json:"createdAt"`
+}
+
+// PostingsSum - сумма записей; у сбалансированной проводки она равна нулю
+func PostingsSum(postings []Posting) decimal.Decimal {
+    sum := decimal.Zero
+    for _, p := range postings {
+        sum = sum.Add(p.Amount)
+    }
+    return sum
+}
+
+// AccountBalance - остаток счета по проводкам. Для счетов кошельков WalletBalance -
+// баланс из wallets, который должен с ним совпадать
+type AccountBalance struct {
+    Account       Account          `json:"account"`
+    Balance       decimal.Decimal  `json:"balance"`
+    WalletBalance *decimal.Decimal `json:"walletBalance,omitempty"`
+}
+
+// TrialBalance - оборотно-сальдовая ведомость: остатки всех счетов. Total всегда
+// должен быть нулевым, Mismatches - кошельки, чей баланс разошелся с проводками.
+// Balanced - сумма нулевая и расхождений нет
+type TrialBalance struct {
+    Accounts   []AccountBalance `json:"accounts"`
+    Total      decimal.Decimal  `json:"total"`
+    Balanced   bool             `json:"balanced"`
+    Mismatches []AccountBalance `json:"mismatches"`
+}
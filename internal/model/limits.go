@@ -0,0 +1,59 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Limits - ограничения на операции кошелька. nil - ограничение не задано
+type Limits struct {
+    MaxOperationAmount      *decimal.Decimal `json:"maxOperationAmount"`
+    DailyWithdrawalAmount   *decimal.Decimal `json:"dailyWithdrawalAmount"`
+    DailyWithdrawalCount    *int             `json:"dailyWithdrawalCount"`
+    MonthlyWithdrawalAmount *decimal.Decimal `json:"monthlyWithdrawalAmount"`
+    MonthlyWithdrawalCount  *int             `json:"monthlyWithdrawalCount"`
+}
+
+// Merge возвращает лимиты, в которых незаданные значения взяты из defaults
+func (l Limits) Merge(defaults Limits) Limits {
+    if l.MaxOperationAmount == nil {
+        l.MaxOperationAmount = defaults.MaxOperationAmount
+    }
+    if l.DailyWithdrawalAmount == nil {
+        l.DailyWithdrawalAmount = defaults.DailyWithdrawalAmount
+    }
+    if l.DailyWithdrawalCount == nil {
+        l.DailyWithdrawalCount = defaults.DailyWithdrawalCount
+    }
+    if l.MonthlyWithdrawalAmount == nil {
+        l.MonthlyWithdrawalAmount = defaults.MonthlyWithdrawalAmount
+    }
+    if l.MonthlyWithdrawalCount == nil {
+        l.MonthlyWithdrawalCount = defaults.MonthlyWithdrawalCount
+    }
+    return l
+}
+
+// HasWithdrawalLimits сообщает, нужно ли считать использование лимитов списаний
+func (l Limits) HasWithdrawalLimits() bool {
+    return l.DailyWithdrawalAmount != nil || l.DailyWithdrawalCount != nil ||
+        l.MonthlyWithdrawalAmount != nil || l.MonthlyWithdrawalCount != nil
+}
+
+// LimitUsage - сумма и число списаний за текущие сутки и месяц (UTC)
+type LimitUsage struct {
+    DailyWithdrawalAmount   decimal.Decimal `json:"dailyWithdrawalAmount"`
+    DailyWithdrawalCount    int             `json:"dailyWithdrawalCount"`
+    MonthlyWithdrawalAmount decimal.Decimal `json:"monthlyWithdrawalAmount"`
+    MonthlyWithdrawalCount  int             `json:"monthlyWithdrawalCount"`
+}
+
+// WalletLimits - лимиты кошелька и их текущее использование
+type WalletLimits struct {
+    WalletID  uuid.UUID  `json:"walletId"`
+    // Effective - действующие лимиты с учетом глобальных значений по умолчанию
+    Effective Limits     `json:"effective"`
+    // Overrides - лимиты, заданные именно для этого кошелька
+    Overrides Limits     `json:"overrides"`
+    Usage     LimitUsage `json:"usage"`
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type ScheduleStatus string
+
+const (
+    ScheduleStatusActive    ScheduleStatus = "ACTIVE"
+    ScheduleStatusCompleted ScheduleStatus = "COMPLETED"
+    ScheduleStatusFailed    ScheduleStatus = "FAILED"
+    ScheduleStatusCancelled ScheduleStatus = "CANCELLED"
+)
+
+// ScheduledOperation - отложенная (разовая) или повторяющаяся операция
+type ScheduledOperation struct {
+    ID            uuid.UUID       `json:"id"`
+    // TenantID - от имени какого тенанта планировщик выполняет операцию
+    TenantID      string          `json:"-"`
+    WalletID      uuid.UUID       `json:"walletId"`
+    OperationType OperationType   `json:"operationType"`
+    Amount        decimal.Decimal `json:"amount"`
+    // Schedule - cron-выражение (5 полей, UTC); пустое - разовая операция
+    Schedule      string          `json:"schedule,omitempty"`
+    // NextRunAt - время следующего запуска; nil, если заданий больше не будет
+    NextRunAt     *time.Time      `json:"nextRunAt,omitempty"`
+    Status        ScheduleStatus  `json:"status"`
+    CreatedAt     time.Time       `json:"createdAt"`
+}
+
+type RunStatus string
+
+const (
+    RunStatusSucceeded RunStatus = "SUCCEEDED"
+    RunStatusFailed    RunStatus = "FAILED"
+)
+
+// ScheduledRun - результат одного запуска запланированной операции
+type ScheduledRun struct {
+    ScheduledOperationID uuid.UUID  `json:"scheduledOperationId"`
+    ScheduledAt          time.Time  `json:"scheduledAt"`
+    ExecutedAt           time.Time  `json:"executedAt"`
+    Status               RunStatus  `json:"status"`
+    OperationID          *uuid.UUID `json:"operationId,omitempty"`
+    // ErrorCode - код ошибки из docs/errors.md, если запуск не удался
+    ErrorCode            string     `json:"errorCode,omitempty"`
+    Error                string     `json:"error,omitempty"`
+}
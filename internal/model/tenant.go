@@ -0,0 +1,50 @@
+package model
+
+// Tenant - владелец кошельков (бренд). Кошельки и все связанные с ними данные
+// видны только своему тенанту
+type Tenant struct {
+    ID         string
+    // Currencies - допустимые валюты кошельков; первая - валюта по умолчанию
+    Currencies []string
+    // Limits - лимиты по умолчанию для кошельков тенанта
+    Limits     Limits
+    // Fees - тарифная сетка тенанта; комиссии зачисляются на его кошелек
+    Fees       FeeSchedule
+    // APIKeys - ключи X-API-Key, по которым клиент опознается как этот тенант
+    APIKeys    []string
+}
+
+// DefaultCurrency - валюта нового кошелька, если клиент ее не указал
+func (t Tenant) DefaultCurrency() string {
+    if len(t.Currencies) == 0 {
+        return ""
+    }
+    return t.Currencies[0]
+}
+
+// AllowsCurrency сообщает, можно ли тенанту открыть кошелек в валюте currency.
+// Тенант без списка валют не ограничен
+func (t Tenant) AllowsCurrency(currency string) bool {
+    if len(t.Currencies) == 0 {
+        return true
+    }
+    for _, c := range t.Currencies {
+        if c == currency {
+            return true
+        }
+    }
+    return false
+}
+
+// ValidCurrencyCode проверяет формат кода валюты ISO 4217: три заглавные латинские буквы
+func ValidCurrencyCode(code string) bool {
+    if len(code) != 3 {
+        return false
+    }
+    for _, c := range code {
+        if c < 'A' || c > 'Z' {
+            return false
+        }
+    }
+    return true
+}
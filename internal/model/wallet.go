@@ -1,14 +1,60 @@
 package model
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
 type Wallet struct {
-    ID      uuid.UUID       `json:"id" db:"id"`
-    Balance decimal.Decimal `json:"balance" db:"balance"`
-    Version int             `json:"version" db:"version"`
+    ID          uuid.UUID         `json:"id" db:"id"`
+    Balance     decimal.Decimal   `json:"balance" db:"balance"`
+    Version     int               `json:"version" db:"version"`
+    // CreditLimit - на сколько баланс может уйти в минус (овердрафт)
+    CreditLimit decimal.Decimal   `json:"creditLimit" db:"credit_limit"`
+    // Tier - тариф кошелька, от него может зависеть комиссия
+    Tier        string            `json:"tier" db:"tier"`
+    // Currency - валюта кошелька (ISO 4217), задается при создании
+    Currency    string            `json:"currency" db:"currency"`
+    // OwnerRef - id владельца во внешней системе клиента
+    OwnerRef    string            `json:"ownerRef,omitempty" db:"owner_ref"`
+    DisplayName string            `json:"displayName,omitempty" db:"display_name"`
+    Labels      map[string]string `json:"labels" db:"labels"`
+    Status      WalletStatus      `json:"status" db:"status"`
+    CreatedAt   time.Time         `json:"createdAt" db:"created_at"`
+    // UpdatedAt - время последнего изменения баланса, настроек или метаданных
+    UpdatedAt   time.Time         `json:"updatedAt" db:"updated_at"`
+}
+
+type WalletStatus string
+
+const (
+    WalletActive WalletStatus = "ACTIVE"
+    // WalletFrozen - операции по кошельку запрещены
+    WalletFrozen WalletStatus = "FROZEN"
+)
+
+// Available - сколько можно списать с учетом кредитного лимита
+func (w Wallet) Available() decimal.Decimal {
+    return w.Balance.Add(w.CreditLimit)
+}
+
+const (
+    // AmountScale - знаков после запятой в денежных колонках (NUMERIC(20,2))
+    AmountScale = 2
+    // AmountIntDigits - цифр целой части в денежных колонках
+    AmountIntDigits = 18
+)
+
+// AmountPrecisionMessage - текст ошибки валидации для суммы, которую нельзя сохранить
+const AmountPrecisionMessage = "must have at most 2 decimal places and 18 integer digits"
+
+var maxAmount = decimal.New(1, AmountIntDigits)
+
+// ValidAmount проверяет, что сумма хранится в БД без округления и переполнения
+func ValidAmount(amount decimal.Decimal) bool {
+    return amount.Equal(amount.Truncate(AmountScale)) && amount.Abs().LessThan(maxAmount)
 }
 
 type OperationType string
@@ -16,10 +62,63 @@ type OperationType string
 const (
     OperationTypeDeposit  OperationType = "DEPOSIT"
     OperationTypeWithdraw OperationType = "WITHDRAW"
+    // OperationTypeFee - зачисление комиссии на кошелек комиссий
+    OperationTypeFee      OperationType = "FEE"
 )
 
 type WalletOperation struct {
-    WalletID      uuid.UUID       `json:"walletId"`
-    OperationType OperationType   `json:"operationType"`
-    Amount        decimal.Decimal `json:"amount"`
+    WalletID        uuid.UUID       `json:"walletId"`
+    OperationType   OperationType   `json:"operationType"`
+    Amount          decimal.Decimal `json:"amount"`
+    // Currency - валюта кошелька; пустая - валюта по умолчанию тенанта при создании
+    // и любая при операции с существующим кошельком
+    Currency        string          `json:"currency,omitempty"`
+    // Версия кошелька, которую видел клиент (If-Match); nil - без проверки
+    ExpectedVersion *int            `json:"expectedVersion,omitempty"`
+    // MustExist - If-Match: * - операция применяется только к существующему кошельку
+    MustExist       bool            `json:"-"`
+    // Ключ из заголовка Idempotency-Key: повтор с тем же ключом не применяется дважды
+    IdempotencyKey  string          `json:"-"`
+    // Fee - комиссия, рассчитанная сервисом; nil - без комиссии
+    Fee             *FeeBreakdown   `json:"-"`
+    // FeeTier - тариф кошелька, по которому сервис считал комиссию; пустой - комиссия
+    // от тарифа не зависит
+    FeeTier         string          `json:"-"`
+    // AdjustmentID - одобренная ручная корректировка, которую применяет операция
+    AdjustmentID    *uuid.UUID      `json:"-"`
+}
+
+// Opposite возвращает тип операции, компенсирующей данную
+func (t OperationType) Opposite() OperationType {
+    if t == OperationTypeDeposit {
+        return OperationTypeWithdraw
+    }
+    return OperationTypeDeposit
+}
+
+// Operation - примененная к кошельку операция
+type Operation struct {
+    ID             uuid.UUID       `json:"id"`
+    WalletID       uuid.UUID       `json:"walletId"`
+    OperationType  OperationType   `json:"operationType"`
+    Amount         decimal.Decimal `json:"amount"`
+    BalanceAfter   decimal.Decimal `json:"balanceAfter"`
+    WalletVersion  int             `json:"walletVersion"`
+    // ReversalOf - id сторнируемой операции, если это сторно
+    ReversalOf     *uuid.UUID      `json:"reversalOf,omitempty"`
+    // ReversedAmount - сколько из этой операции уже сторнировано
+    ReversedAmount decimal.Decimal `json:"reversedAmount"`
+    // Fee - удержанная комиссия; nil - операция без комиссии
+    Fee            *FeeBreakdown   `json:"fee,omitempty"`
+    // AdjustmentID - ручная корректировка, которой создана операция
+    AdjustmentID   *uuid.UUID      `json:"adjustmentId,omitempty"`
+    CreatedAt      time.Time       `json:"createdAt"`
+}
+
+// Reversal - запрос на полное или частичное сторно операции
+type Reversal struct {
+    OperationID    uuid.UUID
+    // Amount - сумма сторно; nil - весь несторнированный остаток
+    Amount         *decimal.Decimal
+    IdempotencyKey string
 }
\ No newline at end of file
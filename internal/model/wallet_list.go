@@ -0,0 +1,117 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// WalletSort - порядок списка кошельков: поле, с минусом - по убыванию
+type WalletSort string
+
+const (
+    SortCreatedAt     WalletSort = "createdAt"
+    SortCreatedAtDesc WalletSort = "-createdAt"
+    SortUpdatedAt     WalletSort = "updatedAt"
+    SortUpdatedAtDesc WalletSort = "-updatedAt"
+    SortBalance       WalletSort = "balance"
+    SortBalanceDesc   WalletSort = "-balance"
+)
+
+// WalletSorts - все допустимые порядки списка
+var WalletSorts = []WalletSort{SortCreatedAt, SortCreatedAtDesc, SortUpdatedAt, SortUpdatedAtDesc, SortBalance, SortBalanceDesc}
+
+func (s WalletSort) Valid() bool {
+    for _, sort := range WalletSorts {
+        if s == sort {
+            return true
+        }
+    }
+    return false
+}
+
+// Desc сообщает, что список идет по убыванию поля
+func (s WalletSort) Desc() bool {
+    return len(s) > 0 && s[0] == '-'
+}
+
+// CursorValue - значение поля сортировки кошелька для курсора следующей страницы
+func (s WalletSort) CursorValue(w Wallet) string {
+    switch s {
+    case SortBalance, SortBalanceDesc:
+        return w.Balance.String()
+    case SortUpdatedAt, SortUpdatedAtDesc:
+        return w.UpdatedAt.UTC().Format(time.RFC3339Nano)
+    default:
+        return w.CreatedAt.UTC().Format(time.RFC3339Nano)
+    }
+}
+
+// WalletCursor - последний кошелек предыдущей страницы. Курсор действует только
+// для того порядка, в котором получен
+type WalletCursor struct {
+    Sort  WalletSort `json:"s"`
+    Value string     `json:"v"`
+    ID    uuid.UUID  `json:"id"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode - непрозрачная для клиента строка курсора
+func (c WalletCursor) Encode() string {
+    data, _ := json.Marshal(c)
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func ParseWalletCursor(s string) (WalletCursor, error) {
+    data, err := base64.RawURLEncoding.DecodeString(s)
+    if err != nil {
+        return WalletCursor{}, ErrInvalidCursor
+    }
+    var c WalletCursor
+    if err := json.Unmarshal(data, &c); err != nil || !c.Sort.Valid() || c.ID == uuid.Nil {
+        return WalletCursor{}, ErrInvalidCursor
+    }
+    // Значение уходит в SQL с приведением типа, поэтому проверяется заранее
+    switch c.Sort {
+    case SortBalance, SortBalanceDesc:
+        _, err = decimal.NewFromString(c.Value)
+    default:
+        _, err = time.Parse(time.RFC3339Nano, c.Value)
+    }
+    if err != nil {
+        return WalletCursor{}, ErrInvalidCursor
+    }
+    return c, nil
+}
+
+// WalletFilter - отбор кошельков тенанта; пустые поля не ограничивают выборку
+type WalletFilter struct {
+    OwnerRef   string
+    // Labels - кошелек должен иметь все эти метки с такими значениями
+    Labels     map[string]string
+    Status     WalletStatus
+    MinBalance *decimal.Decimal
+    MaxBalance *decimal.Decimal
+    Sort       WalletSort
+    // After - курсор следующей страницы; его порядок должен совпадать с Sort
+    After      *WalletCursor
+    Limit      int
+}
+
+// WalletPage - страница списка кошельков; Next - курсор, nil на последней странице
+type WalletPage struct {
+    Wallets []Wallet
+    Next    *WalletCursor
+}
+
+// WalletMetadata - изменение метаданных кошелька; nil-поля не меняются
+type WalletMetadata struct {
+    OwnerRef    *string
+    DisplayName *string
+    Labels      map[string]string
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrAdjustmentNotFound   = apperror.ErrAdjustmentNotFound
+	ErrAdjustmentNotPending = apperror.ErrAdjustmentNotPending
+	ErrSelfApproval         = apperror.ErrSelfApproval
+)
+
+type AdjustmentRepository interface {
+	// CreateAdjustment сохраняет предложенную корректировку; кошелек должен существовать
+	CreateAdjustment(ctx context.Context, adj model.Adjustment) (model.Adjustment, error)
+	GetAdjustment(ctx context.Context, id uuid.UUID) (model.Adjustment, error)
+	ListAdjustments(ctx context.Context, filter model.AdjustmentFilter) ([]model.Adjustment, error)
+	// ApproveAdjustment применяет корректировку к кошельку и отмечает ее одобренной
+	// в одной транзакции. Одобрить свою корректировку нельзя - ErrSelfApproval
+	ApproveAdjustment(ctx context.Context, review model.AdjustmentReview) (model.Adjustment, error)
+	RejectAdjustment(ctx context.Context, review model.AdjustmentReview) (model.Adjustment, error)
+}
+
+// adjustmentRepository применяет корректировки тем же applyOperation, что и
+// обычные операции: блокировка кошелька, проверка средств, журнал и проводка
+type adjustmentRepository struct {
+	pool    *pgxpool.Pool
+	wallets *walletRepository
+}
+
+// NewAdjustmentRepository - tenants нужны applyOperation так же, как репозиторию кошельков
+func NewAdjustmentRepository(pool *pgxpool.Pool, lock LockStrategy, tenants *tenant.Registry) AdjustmentRepository {
+	return &adjustmentRepository{pool: pool, wallets: &walletRepository{pool: pool, lock: lock, tenants: tenants}}
+}
+
+const adjustmentColumns = `id, wallet_id, direction, amount, reason_code, comment, status,
+	proposed_by, proposed_at, reviewed_by, reviewed_at, review_comment, operation_id`
+
+func scanAdjustment(row rowScanner) (model.Adjustment, error) {
+	var adj model.Adjustment
+	var reviewedBy, reviewComment sql.NullString
+	var reviewedAt sql.NullTime
+	var operationID uuid.NullUUID
+
+	err := row.Scan(&adj.ID, &adj.WalletID, &adj.Direction, &adj.Amount, &adj.ReasonCode, &adj.Comment, &adj.Status,
+		&adj.ProposedBy, &adj.ProposedAt, &reviewedBy, &reviewedAt, &reviewComment, &operationID)
+	if err != nil {
+		return model.Adjustment{}, err
+	}
+	adj.ReviewedBy = reviewedBy.String
+	adj.ReviewComment = reviewComment.String
+	if reviewedAt.Valid {
+		adj.ReviewedAt = &reviewedAt.Time
+	}
+	if operationID.Valid {
+		adj.OperationID = &operationID.UUID
+	}
+	return adj, nil
+}
+
+func (r *adjustmentRepository) CreateAdjustment(ctx context.Context, adj model.Adjustment) (model.Adjustment, error) {
+	// Внешний ключ (tenant_id, wallet_id): кошелек чужого тенанта - как несуществующий
+	query := `INSERT INTO adjustments (id, tenant_id, wallet_id, direction, amount, reason_code, comment, status, proposed_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING proposed_at`
+	err := r.pool.QueryRow(ctx, query, adj.ID, tenant.FromContext(ctx), adj.WalletID, adj.Direction, adj.Amount,
+		adj.ReasonCode, adj.Comment, adj.Status, adj.ProposedBy,
+	).Scan(&adj.ProposedAt)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return model.Adjustment{}, ErrWalletNotFound
+		}
+		return model.Adjustment{}, fmt.Errorf("failed to create adjustment: %w", err)
+	}
+	return adj, nil
+}
+
+func (r *adjustmentRepository) GetAdjustment(ctx context.Context, id uuid.UUID) (model.Adjustment, error) {
+	query := `SELECT ` + adjustmentColumns + ` FROM adjustments WHERE id = $1 AND tenant_id = $2`
+	adj, err := scanAdjustment(r.pool.QueryRow(ctx, query, id, tenant.FromContext(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Adjustment{}, ErrAdjustmentNotFound
+		}
+		return model.Adjustment{}, fmt.Errorf("failed to get adjustment: %w", err)
+	}
+	return adj, nil
+}
+
+func (r *adjustmentRepository) ListAdjustments(ctx context.Context, filter model.AdjustmentFilter) ([]model.Adjustment, error) {
+	conditions := []string{"tenant_id = $1"}
+	args := []any{tenant.FromContext(ctx)}
+	if filter.WalletID != nil {
+		args = append(args, *filter.WalletID)
+		conditions = append(conditions, fmt.Sprintf("wallet_id = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	query := `SELECT ` + adjustmentColumns + ` FROM adjustments
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY proposed_at`
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	adjustments := []model.Adjustment{}
+	for rows.Next() {
+		adj, err := scanAdjustment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan adjustment: %w", err)
+		}
+		adjustments = append(adjustments, adj)
+	}
+	return adjustments, rows.Err()
+}
+
+func (r *adjustmentRepository) ApproveAdjustment(ctx context.Context, review model.AdjustmentReview) (model.Adjustment, error) {
+	tx, err := r.pool.BeginTx(ctx, r.wallets.lock.TxOptions())
+	if err != nil {
+		return model.Adjustment{}, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	adj, err := lockPendingAdjustment(ctx, tx, review.AdjustmentID)
+	if err != nil {
+		return model.Adjustment{}, err
+	}
+	if adj.ProposedBy == review.Operator {
+		return model.Adjustment{}, ErrSelfApproval
+	}
+
+	// Лимиты на корректировки не действуют, но списать больше доступного нельзя
+	op, err := r.wallets.applyOperation(ctx, tx, model.WalletOperation{
+		WalletID:      adj.WalletID,
+		OperationType: adj.Direction.OperationType(),
+		Amount:        adj.Amount,
+		AdjustmentID:  &adj.ID,
+	}, nil)
+	if err != nil {
+		return model.Adjustment{}, err
+	}
+
+	adj, err = reviewAdjustment(ctx, tx, review, model.AdjustmentApproved, &op.ID)
+	if err != nil {
+		return model.Adjustment{}, err
+	}
+	return adj, commit(ctx, tx)
+}
+
+// RejectAdjustment отклоняет корректировку. Автор тоже может ее отклонить - это отзыв
+func (r *adjustmentRepository) RejectAdjustment(ctx context.Context, review model.AdjustmentReview) (model.Adjustment, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return model.Adjustment{}, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockPendingAdjustment(ctx, tx, review.AdjustmentID); err != nil {
+		return model.Adjustment{}, err
+	}
+
+	adj, err := reviewAdjustment(ctx, tx, review, model.AdjustmentRejected, nil)
+	if err != nil {
+		return model.Adjustment{}, err
+	}
+	return adj, commit(ctx, tx)
+}
+
+// lockPendingAdjustment блокирует корректировку: параллельные решения по ней
+// выполняются по очереди, и второе увидит, что она уже рассмотрена
+func lockPendingAdjustment(ctx context.Context, tx pgx.Tx, id uuid.UUID) (model.Adjustment, error) {
+	query := `SELECT ` + adjustmentColumns + ` FROM adjustments WHERE id = $1 AND tenant_id = $2 FOR UPDATE`
+	adj, err := scanAdjustment(tx.QueryRow(ctx, query, id, tenant.FromContext(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Adjustment{}, ErrAdjustmentNotFound
+		}
+		return model.Adjustment{}, wrapDBError(err, "failed to get adjustment")
+	}
+	if adj.Status != model.AdjustmentPending {
+		return model.Adjustment{}, ErrAdjustmentNotPending.WithDetail("adjustment is already %s", strings.ToLower(string(adj.Status)))
+	}
+	return adj, nil
+}
+
+func reviewAdjustment(ctx context.Context, tx pgx.Tx, review model.AdjustmentReview, status model.AdjustmentStatus, operationID *uuid.UUID) (model.Adjustment, error) {
+	query := `UPDATE adjustments
+		SET status = $2, reviewed_by = $3, reviewed_at = now(), review_comment = $4, operation_id = $5
+		WHERE id = $1
+		RETURNING ` + adjustmentColumns
+	adj, err := scanAdjustment(tx.QueryRow(ctx, query, review.AdjustmentID, status, review.Operator,
+		sql.NullString{String: review.Comment, Valid: review.Comment != ""}, operationID))
+	if err != nil {
+		return model.Adjustment{}, wrapDBError(err, "failed to review adjustment")
+	}
+	return adj, nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"wallet-service/internal/database"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDatabaseEnv - строка подключения к тестовой базе; без нее тесты с Postgres пропускаются
+const testDatabaseEnv = "WALLET_TEST_DATABASE_URL"
+
+// testPool подключается к тестовой базе и накатывает миграции
+func testPool(t *testing.T) *pgxpool.Pool {
+	url := os.Getenv(testDatabaseEnv)
+	if url == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+
+	pool, err := pgxpool.New(context.Background(), url)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	// Миграции читаются из каталога относительно корня модуля
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir("../.."))
+	defer os.Chdir(wd)
+	require.NoError(t, database.RunMigrations(stdlib.OpenDBFromPool(pool)))
+	return pool
+}
+
+func TestNewAdjustmentRepository_UsesTenants(t *testing.T) {
+	tenants := tenant.NewRegistry()
+	repo := NewAdjustmentRepository(nil, pessimisticLock{}, tenants).(*adjustmentRepository)
+	assert.Same(t, tenants, repo.wallets.tenants)
+}
+
+func TestAdjustmentRepository_ApproveIgnoresLimits(t *testing.T) {
+	pool := testPool(t)
+	limited := model.Tenant{
+		ID:         "adj-" + uuid.NewString()[:8],
+		Currencies: []string{"USD"},
+		Limits:     model.Limits{MaxOperationAmount: amount(100)},
+	}
+	tenants := tenant.NewRegistry(limited)
+	ctx := tenant.NewContext(context.Background(), limited.ID)
+
+	wallets := NewWalletRepository(pool, pessimisticLock{}, tenants)
+	walletID := uuid.New()
+	_, err := wallets.UpdateBalance(ctx, model.WalletOperation{
+		WalletID:      walletID,
+		OperationType: model.OperationTypeDeposit,
+		Amount:        decimal.NewFromInt(50),
+	})
+	require.NoError(t, err)
+
+	repo := NewAdjustmentRepository(pool, pessimisticLock{}, tenants)
+	for _, adj := range []model.Adjustment{
+		{Direction: model.AdjustmentCredit, Amount: decimal.NewFromInt(500)},
+		{Direction: model.AdjustmentDebit, Amount: decimal.NewFromInt(300)},
+	} {
+		adj.ID = uuid.New()
+		adj.WalletID = walletID
+		adj.ReasonCode = "ERROR_CORRECTION"
+		adj.Comment = "limits test"
+		adj.Status = model.AdjustmentPending
+		adj.ProposedBy = "alice"
+		_, err := repo.CreateAdjustment(ctx, adj)
+		require.NoError(t, err)
+
+		// Сумма больше maxOperationAmount тенанта: лимиты на корректировки не действуют
+		approved, err := repo.ApproveAdjustment(ctx, model.AdjustmentReview{AdjustmentID: adj.ID, Operator: "bob"})
+		require.NoError(t, err)
+		assert.Equal(t, model.AdjustmentApproved, approved.Status)
+		require.NotNil(t, approved.OperationID)
+	}
+
+	wallet, err := wallets.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(250).Equal(wallet.Balance), "balance %s", wallet.Balance)
+}
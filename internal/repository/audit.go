@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+)
+
+// AuditRepository - журнал аудита. Записи только добавляются
+type AuditRepository interface {
+	InsertAudit(ctx context.Context, rec model.AuditRecord) (model.AuditRecord, error)
+	ListAudit(ctx context.Context, filter model.AuditFilter) ([]model.AuditRecord, error)
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+const auditColumns = `id, created_at, request_id, tenant_id, actor, client_ip, method, endpoint, path,
+	wallet_id, operation_id, summary, status, outcome, error_code, latency_ms`
+
+func scanAudit(row rowScanner) (model.AuditRecord, error) {
+	var rec model.AuditRecord
+	var tenantID, clientIP, summary, errorCode sql.NullString
+	var walletID, operationID uuid.NullUUID
+
+	err := row.Scan(&rec.ID, &rec.CreatedAt, &rec.RequestID, &tenantID, &rec.Actor, &clientIP, &rec.Method, &rec.Endpoint, &rec.Path,
+		&walletID, &operationID, &summary, &rec.Status, &rec.Outcome, &errorCode, &rec.LatencyMs)
+	if err != nil {
+		return model.AuditRecord{}, err
+	}
+	rec.TenantID = tenantID.String
+	rec.ClientIP = clientIP.String
+	rec.ErrorCode = errorCode.String
+	if walletID.Valid {
+		rec.WalletID = &walletID.UUID
+	}
+	if operationID.Valid {
+		rec.OperationID = &operationID.UUID
+	}
+	if summary.Valid {
+		if err := json.Unmarshal([]byte(summary.String), &rec.Summary); err != nil {
+			return model.AuditRecord{}, fmt.Errorf("failed to decode audit summary: %w", err)
+		}
+	}
+	return rec, nil
+}
+
+func (r *auditRepository) InsertAudit(ctx context.Context, rec model.AuditRecord) (model.AuditRecord, error) {
+	// Сводка пишется в JSONB строкой, без сводки - NULL
+	var summary sql.NullString
+	if rec.Summary != nil {
+		data, err := json.Marshal(rec.Summary)
+		if err != nil {
+			return model.AuditRecord{}, fmt.Errorf("failed to encode audit summary: %w", err)
+		}
+		summary = sql.NullString{String: string(data), Valid: true}
+	}
+
+	query := `INSERT INTO audit_log (request_id, tenant_id, actor, client_ip, method, endpoint, path,
+			wallet_id, operation_id, summary, status, outcome, error_code, latency_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING id, created_at`
+	err := r.db.QueryRowContext(ctx, query, rec.RequestID, sql.NullString{String: rec.TenantID, Valid: rec.TenantID != ""}, rec.Actor,
+		sql.NullString{String: rec.ClientIP, Valid: rec.ClientIP != ""}, rec.Method, rec.Endpoint, rec.Path,
+		rec.WalletID, rec.OperationID, summary, rec.Status, rec.Outcome,
+		sql.NullString{String: rec.ErrorCode, Valid: rec.ErrorCode != ""}, rec.LatencyMs,
+	).Scan(&rec.ID, &rec.CreatedAt)
+	if err != nil {
+		return model.AuditRecord{}, fmt.Errorf("failed to write audit record: %w", err)
+	}
+	return rec, nil
+}
+
+func (r *auditRepository) ListAudit(ctx context.Context, filter model.AuditFilter) ([]model.AuditRecord, error) {
+	conditions := []string{"id > $1"}
+	args := []any{filter.AfterID}
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "$%d", fmt.Sprintf("$%d", len(args))))
+	}
+	// Запросы, отклоненные до определения тенанта, видны в журнале тенанта по умолчанию
+	add("(tenant_id = $%d OR ($%d = '"+tenant.DefaultID+"' AND tenant_id IS NULL))", tenant.FromContext(ctx))
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.WalletID != nil {
+		add("wallet_id = $%d", *filter.WalletID)
+	}
+	if filter.Endpoint != "" {
+		add("endpoint = $%d", filter.Endpoint)
+	}
+	if filter.Outcome != "" {
+		add("outcome = $%d", filter.Outcome)
+	}
+	if filter.From != nil {
+		add("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		add("created_at < $%d", *filter.To)
+	}
+	args = append(args, filter.Limit)
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY id LIMIT $` + fmt.Sprint(len(args))
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []model.AuditRecord{}
+	for rows.Next() {
+		rec, err := scanAudit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit record: %w", err)
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"wallet-service/internal/cache"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// cachedWalletRepository читает кошельки через кеш. Свои изменения делают запись устаревшей
+// сразу после коммита, изменения других реплик и админского API - по NOTIFY
+type cachedWalletRepository struct {
+	WalletRepository
+	cache *cache.WalletCache
+}
+
+// NewCachedWalletRepository оборачивает repo кешем. Чтобы кеш видел изменения с других
+// реплик, он должен быть наблюдателем events.Hub, который слушает Postgres
+func NewCachedWalletRepository(repo WalletRepository, c *cache.WalletCache) WalletRepository {
+	return &cachedWalletRepository{WalletRepository: repo, cache: c}
+}
+
+func (r *cachedWalletRepository) GetBalance(ctx context.Context, id uuid.UUID) (decimal.Decimal, error) {
+	wallet, err := r.GetWallet(ctx, id)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return wallet.Balance, nil
+}
+
+func (r *cachedWalletRepository) GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error) {
+	if cache.Bypassed(ctx) {
+		return r.WalletRepository.GetWallet(ctx, id)
+	}
+	return r.cache.GetOrLoad(tenant.FromContext(ctx), id, func() (model.Wallet, error) {
+		return r.WalletRepository.GetWallet(ctx, id)
+	})
+}
+
+func (r *cachedWalletRepository) UpdateBalance(ctx context.Context, op model.WalletOperation) (model.Operation, error) {
+	result, err := r.WalletRepository.UpdateBalance(ctx, op)
+	if err == nil {
+		r.cache.Invalidate(tenant.FromContext(ctx), result.WalletID, result.WalletVersion)
+	}
+	return result, err
+}
+
+func (r *cachedWalletRepository) ReverseOperation(ctx context.Context, rev model.Reversal) (model.Operation, error) {
+	result, err := r.WalletRepository.ReverseOperation(ctx, rev)
+	if err == nil {
+		r.cache.Invalidate(tenant.FromContext(ctx), result.WalletID, result.WalletVersion)
+	}
+	return result, err
+}
+
+func (r *cachedWalletRepository) UpdateMetadata(ctx context.Context, id uuid.UUID, meta model.WalletMetadata) (model.Wallet, error) {
+	wallet, err := r.WalletRepository.UpdateMetadata(ctx, id, meta)
+	if err == nil {
+		r.cache.Invalidate(tenant.FromContext(ctx), wallet.ID, wallet.Version)
+	}
+	return wallet, err
+}
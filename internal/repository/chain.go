@@ -0,0 +1,375 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrCheckpointNotFound = apperror.ErrCheckpointNotFound
+
+// ChainRepository - чтение цепочек хешей операций и контрольные точки.
+// Звенья пишет WalletRepository в транзакции операции
+type ChainRepository interface {
+	// WalkChain передает fn звенья цепочки кошелька по возрастанию seq
+	WalkChain(ctx context.Context, walletID uuid.UUID, fn func(model.ChainLink) error) error
+	// FirstUnchained - первая операция кошелька после начала цепочки, у которой нет звена, или nil
+	FirstUnchained(ctx context.Context, walletID uuid.UUID) (*model.Operation, error)
+	// ChainWallets - кошельки тенанта запроса, у которых есть цепочка
+	ChainWallets(ctx context.Context) ([]uuid.UUID, error)
+	// CheckpointHead - голова цепочки кошелька тенанта запроса из последней точки, где он есть, или nil
+	CheckpointHead(ctx context.Context, walletID uuid.UUID) (*model.ChainHead, error)
+	// LatestCheckpoint - последняя точка с головами только тенанта запроса. Digest и
+	// подпись покрывают головы всех тенантов, по такой точке их не пересчитать
+	LatestCheckpoint(ctx context.Context) (model.ChainCheckpoint, error)
+	// ListCheckpoints - точки с головами всех тенантов для проверки digest и подписей.
+	// Не для ответов тенантам: головы чужих кошельков в них не фильтруются
+	ListCheckpoints(ctx context.Context, afterID int64, limit int) ([]model.ChainCheckpoint, error)
+	// CreateCheckpoint фиксирует головы, изменившиеся с предыдущей точки, и подписывает
+	// digest через sign. Если головы не менялись или точку создает другая реплика, возвращает nil
+	CreateCheckpoint(ctx context.Context, createdAt time.Time, sign func(digest model.Hash) ([]byte, error)) (*model.ChainCheckpoint, error)
+}
+
+type chainRepository struct {
+	db *sql.DB
+}
+
+func NewChainRepository(db *sql.DB) ChainRepository {
+	return &chainRepository{db: db}
+}
+
+var (
+	chainHeadStmt   = statement{name: "chain_head", sql: `SELECT seq, hash FROM operation_chain WHERE wallet_id = $1 ORDER BY seq DESC LIMIT 1`}
+	appendChainStmt = statement{name: "append_chain",
+		sql: `INSERT INTO operation_chain (wallet_id, tenant_id, seq, operation_id, prev_hash, hash, format) VALUES ($1, $2, $3, $4, $5, $6, $7)`}
+)
+
+// appendChain добавляет операцию в цепочку ее кошелька. Кошелек в этот момент
+// заблокирован транзакцией операции, поэтому звенья одного кошелька пишутся по очереди;
+// если стратегия блокировок это не гарантирует, повтор обеспечит первичный ключ
+func appendChain(ctx context.Context, tx pgx.Tx, op model.Operation) error {
+	seq := int64(1)
+	prev := model.GenesisHash
+
+	var last int64
+	var lastHash []byte
+	err := chainHeadStmt.queryRow(ctx, tx, op.WalletID).Scan(&last, &lastHash)
+	switch {
+	case err == nil:
+		seq, prev = last+1, lastHash
+	case !errors.Is(err, pgx.ErrNoRows):
+		return wrapDBError(err, "failed to read chain head")
+	}
+
+	tenantID := tenant.FromContext(ctx)
+	hash := model.ChainHash(prev, seq, tenantID, op)
+	if _, err := appendChainStmt.exec(ctx, tx, op.WalletID, tenantID, seq, op.ID, []byte(prev), []byte(hash), model.ChainFormat); err != nil {
+		if isUniqueViolation(err) {
+			return ErrOptimisticLock
+		}
+		return wrapDBError(err, "failed to append chain link")
+	}
+	return nil
+}
+
+func (r *chainRepository) WalkChain(ctx context.Context, walletID uuid.UUID, fn func(model.ChainLink) error) error {
+	var exists bool
+	existsQuery := `SELECT EXISTS (SELECT 1 FROM wallets WHERE id = $1 AND tenant_id = $2)`
+	if err := r.db.QueryRowContext(ctx, existsQuery, walletID, tenant.FromContext(ctx)).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to get wallet: %w", err)
+	}
+	if !exists {
+		return ErrWalletNotFound
+	}
+
+	// LEFT JOIN: звено, операцию которого подменили или удалили, тоже должно попасть в проверку
+	query := `SELECT c.seq, c.format, c.prev_hash, c.hash, c.operation_id, o.id IS NOT NULL, COALESCE(o.tenant_id, ''),
+			COALESCE(o.operation_type, ''), COALESCE(o.amount, 0), COALESCE(o.balance_after, 0), COALESCE(o.wallet_version, 0),
+			o.reversal_of, o.fee, o.adjustment_id, COALESCE(o.created_at, 'epoch'), COALESCE(o.wallet_id, c.wallet_id)
+		FROM operation_chain c LEFT JOIN operations o ON o.id = c.operation_id
+		WHERE c.wallet_id = $1
+		ORDER BY c.seq`
+	rows, err := r.db.QueryContext(ctx, query, walletID)
+	if err != nil {
+		return fmt.Errorf("failed to read chain: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var link model.ChainLink
+		var prevHash, hash []byte
+		var found bool
+		var reversalOf, adjustmentID uuid.NullUUID
+		var fee sql.NullString
+		op := &link.Operation
+		err := rows.Scan(&link.Seq, &link.Format, &prevHash, &hash, &op.ID, &found, &link.TenantID,
+			&op.OperationType, &op.Amount, &op.BalanceAfter, &op.WalletVersion,
+			&reversalOf, &fee, &adjustmentID, &op.CreatedAt, &op.WalletID)
+		if err != nil {
+			return fmt.Errorf("failed to scan chain link: %w", err)
+		}
+		link.PrevHash, link.Hash = prevHash, hash
+		link.Missing = !found
+		if reversalOf.Valid {
+			op.ReversalOf = &reversalOf.UUID
+		}
+		if adjustmentID.Valid {
+			op.AdjustmentID = &adjustmentID.UUID
+		}
+		if op.Fee, err = decodeFee(fee); err != nil {
+			return err
+		}
+
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read chain: %w", err)
+	}
+	return nil
+}
+
+func (r *chainRepository) FirstUnchained(ctx context.Context, walletID uuid.UUID) (*model.Operation, error) {
+	query := `SELECT ` + operationColumns + ` FROM operations o
+		WHERE o.wallet_id = $1
+			AND o.created_at >= (SELECT o1.created_at FROM operation_chain c1 JOIN operations o1 ON o1.id = c1.operation_id
+				WHERE c1.wallet_id = $1 ORDER BY c1.seq LIMIT 1)
+			AND NOT EXISTS (SELECT 1 FROM operation_chain c WHERE c.operation_id = o.id)
+		ORDER BY o.created_at, o.wallet_version
+		LIMIT 1`
+	op, err := scanOperation(r.db.QueryRowContext(ctx, query, walletID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unchained operations: %w", err)
+	}
+	return &op, nil
+}
+
+func (r *chainRepository) ChainWallets(ctx context.Context) ([]uuid.UUID, error) {
+	query := `SELECT DISTINCT wallet_id FROM operation_chain WHERE tenant_id = $1 ORDER BY wallet_id`
+	rows, err := r.db.QueryContext(ctx, query, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chains: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *chainRepository) CheckpointHead(ctx context.Context, walletID uuid.UUID) (*model.ChainHead, error) {
+	head := model.ChainHead{WalletID: walletID}
+	var hash []byte
+	query := `SELECT seq, hash FROM chain_checkpoint_heads
+		WHERE tenant_id = $1 AND wallet_id = $2 ORDER BY checkpoint_id DESC LIMIT 1`
+	err := r.db.QueryRowContext(ctx, query, tenant.FromContext(ctx), walletID).Scan(&head.Seq, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint head: %w", err)
+	}
+	head.Hash = hash
+	return &head, nil
+}
+
+const checkpointColumns = `id, created_at, prev_digest, digest, signature`
+
+func (r *chainRepository) LatestCheckpoint(ctx context.Context) (model.ChainCheckpoint, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+checkpointColumns+` FROM chain_checkpoints ORDER BY id DESC LIMIT 1`)
+	if err != nil {
+		return model.ChainCheckpoint{}, fmt.Errorf("failed to get checkpoint: %w", err)
+	}
+	tenantID := tenant.FromContext(ctx)
+	checkpoints, err := r.scanCheckpoints(ctx, rows, &tenantID)
+	if err != nil {
+		return model.ChainCheckpoint{}, err
+	}
+	if len(checkpoints) == 0 {
+		return model.ChainCheckpoint{}, ErrCheckpointNotFound
+	}
+	return checkpoints[0], nil
+}
+
+func (r *chainRepository) ListCheckpoints(ctx context.Context, afterID int64, limit int) ([]model.ChainCheckpoint, error) {
+	query := `SELECT ` + checkpointColumns + ` FROM chain_checkpoints WHERE id > $1 ORDER BY id LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	return r.scanCheckpoints(ctx, rows, nil)
+}
+
+// scanCheckpoints читает точки и догружает их головы одним запросом: головы тенанта
+// tenantID или, если он nil, головы всех тенантов
+func (r *chainRepository) scanCheckpoints(ctx context.Context, rows *sql.Rows, tenantID *string) ([]model.ChainCheckpoint, error) {
+	defer rows.Close()
+
+	var checkpoints []model.ChainCheckpoint
+	index := map[int64]int{}
+	for rows.Next() {
+		var cp model.ChainCheckpoint
+		var prevDigest, digest []byte
+		if err := rows.Scan(&cp.ID, &cp.CreatedAt, &prevDigest, &digest, &cp.Signature); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint: %w", err)
+		}
+		cp.PrevDigest, cp.Digest = prevDigest, digest
+		cp.Heads = []model.ChainHead{}
+		index[cp.ID] = len(checkpoints)
+		checkpoints = append(checkpoints, cp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	if len(checkpoints) == 0 {
+		return checkpoints, nil
+	}
+
+	ids := make([]int64, 0, len(checkpoints))
+	for _, cp := range checkpoints {
+		ids = append(ids, cp.ID)
+	}
+	args := []any{ids}
+	condition := ""
+	if tenantID != nil {
+		args = append(args, *tenantID)
+		condition = " AND tenant_id = $2"
+	}
+	query := `SELECT checkpoint_id, wallet_id, seq, hash FROM chain_checkpoint_heads
+		WHERE checkpoint_id = ANY($1::bigint[])` + condition + ` ORDER BY checkpoint_id, wallet_id`
+	headRows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint heads: %w", err)
+	}
+	defer headRows.Close()
+
+	for headRows.Next() {
+		var id int64
+		var head model.ChainHead
+		var hash []byte
+		if err := headRows.Scan(&id, &head.WalletID, &head.Seq, &hash); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint head: %w", err)
+		}
+		head.Hash = hash
+		cp := &checkpoints[index[id]]
+		cp.Heads = append(cp.Heads, head)
+	}
+	if err := headRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint heads: %w", err)
+	}
+	return checkpoints, nil
+}
+
+// checkpointLockKey - ключ advisory-блокировки: точки создает одна реплика за раз
+const checkpointLockKey = 0x63686b70
+
+func (r *chainRepository) CreateCheckpoint(ctx context.Context, createdAt time.Time, sign func(digest model.Hash) ([]byte, error)) (*model.ChainCheckpoint, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, checkpointLockKey).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("failed to lock checkpoints: %w", err)
+	}
+	if !locked {
+		return nil, nil
+	}
+
+	// Время хранится с точностью до микросекунд - digest считаем от него же
+	cp := model.ChainCheckpoint{CreatedAt: createdAt.UTC().Truncate(time.Microsecond), PrevDigest: model.GenesisHash}
+	var prevDigest []byte
+	err = tx.QueryRowContext(ctx, `SELECT digest FROM chain_checkpoints ORDER BY id DESC LIMIT 1`).Scan(&prevDigest)
+	switch {
+	case err == nil:
+		cp.PrevDigest = prevDigest
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("failed to get last checkpoint: %w", err)
+	}
+
+	// Незакоммиченные звенья не видны и попадут в следующую точку
+	query := `SELECT h.tenant_id, h.wallet_id, h.seq, c.hash
+		FROM (SELECT tenant_id, wallet_id, MAX(seq) AS seq FROM operation_chain GROUP BY tenant_id, wallet_id) h
+		JOIN operation_chain c ON c.wallet_id = h.wallet_id AND c.seq = h.seq
+		LEFT JOIN LATERAL (
+			SELECT seq FROM chain_checkpoint_heads p
+			WHERE p.tenant_id = h.tenant_id AND p.wallet_id = h.wallet_id
+			ORDER BY checkpoint_id DESC LIMIT 1
+		) p ON true
+		WHERE p.seq IS NULL OR h.seq > p.seq
+		ORDER BY h.wallet_id`
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain heads: %w", err)
+	}
+	defer rows.Close()
+
+	// Тенант в digest не входит, но хранится с головой для ответов тенанту
+	var tenants []string
+	for rows.Next() {
+		var head model.ChainHead
+		var tenantID string
+		var hash []byte
+		if err := rows.Scan(&tenantID, &head.WalletID, &head.Seq, &hash); err != nil {
+			return nil, fmt.Errorf("failed to scan chain head: %w", err)
+		}
+		head.Hash = hash
+		cp.Heads = append(cp.Heads, head)
+		tenants = append(tenants, tenantID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get chain heads: %w", err)
+	}
+	if len(cp.Heads) == 0 {
+		return nil, nil
+	}
+
+	cp.Digest = model.CheckpointDigest(cp.PrevDigest, cp.CreatedAt, cp.Heads)
+	if cp.Signature, err = sign(cp.Digest); err != nil {
+		return nil, err
+	}
+
+	insertQuery := `INSERT INTO chain_checkpoints (created_at, prev_digest, digest, signature) VALUES ($1, $2, $3, $4) RETURNING id`
+	err = tx.QueryRowContext(ctx, insertQuery, cp.CreatedAt, []byte(cp.PrevDigest), []byte(cp.Digest), cp.Signature).Scan(&cp.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record checkpoint: %w", err)
+	}
+
+	wallets := make([]uuid.UUID, len(cp.Heads))
+	seqs := make([]int64, len(cp.Heads))
+	hashes := make([][]byte, len(cp.Heads))
+	for i, head := range cp.Heads {
+		wallets[i], seqs[i], hashes[i] = head.WalletID, head.Seq, head.Hash
+	}
+	headsQuery := `INSERT INTO chain_checkpoint_heads (checkpoint_id, tenant_id, wallet_id, seq, hash)
+		SELECT $1, h.tenant_id, h.wallet_id, h.seq, h.hash
+		FROM unnest($2::varchar[], $3::uuid[], $4::bigint[], $5::bytea[]) AS h(tenant_id, wallet_id, seq, hash)`
+	if _, err := tx.ExecContext(ctx, headsQuery, cp.ID, tenants, wallets, seqs, hashes); err != nil {
+		return nil, fmt.Errorf("failed to record checkpoint heads: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit checkpoint: %w", err)
+	}
+	return &cp, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainRepository_CheckpointHeadsAreTenantScoped(t *testing.T) {
+	pool := testPool(t)
+	suffix := uuid.NewString()[:8]
+	acme, globex := "acme-"+suffix, "globex-"+suffix
+	wallets := NewWalletRepository(pool, pessimisticLock{}, tenant.NewRegistry())
+
+	walletOf := map[string]uuid.UUID{}
+	for _, id := range []string{acme, globex} {
+		walletOf[id] = uuid.New()
+		_, err := wallets.UpdateBalance(tenant.NewContext(context.Background(), id), model.WalletOperation{
+			WalletID:      walletOf[id],
+			OperationType: model.OperationTypeDeposit,
+			Amount:        decimal.NewFromInt(10),
+			Currency:      "USD",
+		})
+		require.NoError(t, err)
+	}
+
+	chain := NewChainRepository(stdlib.OpenDBFromPool(pool))
+	sign := func(digest model.Hash) ([]byte, error) { return []byte("signature"), nil }
+	_, err := chain.CreateCheckpoint(context.Background(), time.Now(), sign)
+	require.NoError(t, err)
+
+	acmeCtx := tenant.NewContext(context.Background(), acme)
+	head, err := chain.CheckpointHead(acmeCtx, walletOf[acme])
+	require.NoError(t, err)
+	require.NotNil(t, head)
+
+	// Кошелек другого тенанта не виден, как и его голова в точке
+	head, err = chain.CheckpointHead(acmeCtx, walletOf[globex])
+	require.NoError(t, err)
+	assert.Nil(t, head)
+
+	latest, err := chain.LatestCheckpoint(acmeCtx)
+	require.NoError(t, err)
+	var heads []uuid.UUID
+	for _, head := range latest.Heads {
+		heads = append(heads, head.WalletID)
+	}
+	assert.Contains(t, heads, walletOf[acme])
+	assert.NotContains(t, heads, walletOf[globex])
+
+	// Для проверки digest нужны головы всех тенантов
+	all, err := chain.ListCheckpoints(context.Background(), latest.ID-1, 1)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.GreaterOrEqual(t, len(all[0].Heads), 2)
+	assert.True(t, model.CheckpointDigest(all[0].PrevDigest, all[0].CreatedAt, all[0].Heads).Equal(all[0].Digest))
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Классы ошибок Postgres, которые различает вызывающий код. Ошибка репозитория
+// сравнивается с ними через errors.Is, исходная *pgconn.PgError доступна через errors.As
+var (
+	// Транзакция откачена Postgres (SQLSTATE 40001 / 40P01) - ее можно повторить
+	ErrSerializationFailure = errors.New("serialization failure")
+	ErrDeadlock             = errors.New("deadlock detected")
+	ErrUniqueViolation      = errors.New("unique violation")
+	ErrForeignKeyViolation  = errors.New("foreign key violation")
+	ErrCheckViolation       = errors.New("check violation")
+	// ErrNumericOverflow - значение не помещается в колонку (22003)
+	ErrNumericOverflow = errors.New("numeric value out of range")
+	// ErrQueryCanceled - запрос отменен по statement_timeout или конфликту с репликацией
+	ErrQueryCanceled = errors.New("query canceled")
+	// ErrReadOnly - запись на реплику или в READ ONLY транзакции
+	ErrReadOnly = errors.New("read-only transaction")
+)
+
+var pgErrorClasses = map[string]error{
+	"40001": ErrSerializationFailure,
+	"40P01": ErrDeadlock,
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"23514": ErrCheckViolation,
+	"22003": ErrNumericOverflow,
+	"57014": ErrQueryCanceled,
+	"25006": ErrReadOnly,
+}
+
+// DBError - ошибка Postgres известного класса
+type DBError struct {
+	// Class - одна из ошибок выше
+	Class error
+	// Code - SQLSTATE, Constraint - нарушенное ограничение, если есть
+	Code       string
+	Constraint string
+	err        error
+}
+
+func (e *DBError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Class, e.err)
+}
+
+func (e *DBError) Unwrap() []error {
+	return []error{e.Class, e.err}
+}
+
+// classify заворачивает ошибку Postgres известного класса в *DBError,
+// остальные ошибки возвращает как есть
+func classify(err error) error {
+	var dbErr *DBError
+	var pgErr *pgconn.PgError
+	if errors.As(err, &dbErr) || !errors.As(err, &pgErr) {
+		return err
+	}
+	class, ok := pgErrorClasses[pgErr.Code]
+	if !ok {
+		return err
+	}
+	return &DBError{Class: class, Code: pgErr.Code, Constraint: pgErr.ConstraintName, err: err}
+}
+
+// wrapDBError добавляет к ошибке контекст и класс: по нему сервис, например,
+// решает, можно ли повторить операцию
+func wrapDBError(err error, msg string) error {
+	return fmt.Errorf("%s: %w", msg, classify(err))
+}
+
+func isUniqueViolation(err error) bool {
+	return errors.Is(classify(err), ErrUniqueViolation)
+}
+
+func isCheckViolation(err error) bool {
+	return errors.Is(classify(err), ErrCheckViolation)
+}
+
+func isForeignKeyViolation(err error) bool {
+	return errors.Is(classify(err), ErrForeignKeyViolation)
+}
+
+func isNumericOverflow(err error) bool {
+	return errors.Is(classify(err), ErrNumericOverflow)
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapDBError(t *testing.T) {
+	tests := []struct {
+		code  string
+		class error
+	}{
+		{"40001", ErrSerializationFailure},
+		{"40P01", ErrDeadlock},
+		{"23505", ErrUniqueViolation},
+		{"23503", ErrForeignKeyViolation},
+		{"23514", ErrCheckViolation},
+		{"22003", ErrNumericOverflow},
+		{"57014", ErrQueryCanceled},
+		{"25006", ErrReadOnly},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			pgErr := &pgconn.PgError{Code: tt.code, ConstraintName: "wallets_balance_check"}
+			err := wrapDBError(pgErr, "failed to update balance")
+
+			assert.ErrorIs(t, err, tt.class)
+			var dbErr *DBError
+			require.ErrorAs(t, err, &dbErr)
+			assert.Equal(t, tt.code, dbErr.Code)
+			assert.Equal(t, "wallets_balance_check", dbErr.Constraint)
+			// Исходная ошибка Postgres тоже доступна
+			var original *pgconn.PgError
+			require.ErrorAs(t, err, &original)
+			assert.Same(t, pgErr, original)
+		})
+	}
+}
+
+func TestWrapDBError_Unclassified(t *testing.T) {
+	pgErr := &pgconn.PgError{Code: "42P01"}
+	err := wrapDBError(pgErr, "failed to list wallets")
+	var dbErr *DBError
+	assert.False(t, errors.As(err, &dbErr))
+	assert.ErrorIs(t, err, pgErr)
+
+	// Повторное оборачивание не добавляет второй DBError
+	unique := &pgconn.PgError{Severity: "ERROR", Message: "duplicate key value violates unique constraint", Code: "23505"}
+	wrapped := wrapDBError(fmt.Errorf("inner: %w", classify(unique)), "outer")
+	assert.True(t, isUniqueViolation(wrapped))
+	assert.Equal(t, "outer: inner: unique violation: ERROR: duplicate key value violates unique constraint (SQLSTATE 23505)", wrapped.Error())
+}
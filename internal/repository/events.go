@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"wallet-service/internal/events"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EventRepository - события балансов из журнала операций для возобновления потока
+type EventRepository interface {
+	// EventsSince - события операций кошелька тенанта с версией больше afterVersion
+	// по возрастанию версии, не больше limit
+	EventsSince(ctx context.Context, walletID uuid.UUID, afterVersion, limit int) ([]model.BalanceEvent, error)
+}
+
+type eventRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewEventRepository(pool *pgxpool.Pool) EventRepository {
+	return &eventRepository{pool: pool}
+}
+
+func (r *eventRepository) EventsSince(ctx context.Context, walletID uuid.UUID, afterVersion, limit int) ([]model.BalanceEvent, error) {
+	query := `SELECT ` + operationColumns + ` FROM operations
+		WHERE tenant_id = $1 AND wallet_id = $2 AND wallet_version > $3
+		ORDER BY wallet_version LIMIT $4`
+	rows, err := r.pool.Query(ctx, query, tenant.FromContext(ctx), walletID, afterVersion, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wallet events: %w", err)
+	}
+	defer rows.Close()
+
+	var result []model.BalanceEvent
+	for rows.Next() {
+		op, err := scanOperation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan operation: %w", err)
+		}
+		result = append(result, model.OperationEvent(op))
+	}
+	return result, rows.Err()
+}
+
+// notifyOperation ставит событие операции в очередь NOTIFY. Postgres доставит его
+// слушателям только после коммита транзакции и не доставит при откате
+func notifyOperation(ctx context.Context, tx pgx.Tx, op model.Operation) error {
+	return notify(ctx, tx, model.OperationEvent(op))
+}
+
+var notifyStmt = statement{name: "notify", sql: `SELECT pg_notify($1, $2)`}
+
+func notify(ctx context.Context, tx pgx.Tx, event model.BalanceEvent) error {
+	payload, err := json.Marshal(events.Notification{TenantID: tenant.FromContext(ctx), Event: event})
+	if err != nil {
+		return err
+	}
+	if _, err := notifyStmt.exec(ctx, tx, events.Channel, string(payload)); err != nil {
+		return wrapDBError(err, "failed to notify balance event")
+	}
+	return nil
+}
+
+// updateWallet выполняет UPDATE кошелька с RETURNING walletColumns и в той же
+// транзакции шлет событие его новой версии: изменение настроек видят кеши
+// и ожидающие изменения клиенты всех реплик. pgx.ErrNoRows возвращается как есть
+func updateWallet(ctx context.Context, pool *pgxpool.Pool, query string, args ...any) (model.Wallet, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return model.Wallet{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	wallet, err := scanWallet(tx.QueryRow(ctx, query, args...))
+	if err != nil {
+		return model.Wallet{}, err
+	}
+	if err := notify(ctx, tx, model.UpdatedEvent(wallet)); err != nil {
+		return model.Wallet{}, err
+	}
+	return wallet, tx.Commit(ctx)
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+)
+
+var creditFeeStmt = statement{name: "credit_fee",
+	sql: `INSERT INTO wallets (id, tenant_id, currency, balance, version) VALUES ($1, $2, $3, $4, 1)
+		ON CONFLICT (id) DO UPDATE SET balance = wallets.balance + EXCLUDED.balance, version = wallets.version + 1, updated_at = now()
+		WHERE wallets.tenant_id = EXCLUDED.tenant_id AND wallets.currency = EXCLUDED.currency
+		RETURNING balance, version`}
+
+// creditFee зачисляет комиссию операции на кошелек комиссий и возвращает операцию
+// с id зачисления в расшифровке. Кошелек комиссий создается при первом зачислении.
+// Он блокируется последним в транзакции, поэтому порядок блокировок везде одинаков.
+// Строка кошелька комиссий общая для всех платных операций тенанта: они выстраиваются
+// в очередь на ее блокировке до коммита (см. README, раздел "Комиссии")
+func creditFee(ctx context.Context, tx pgx.Tx, op model.WalletOperation) (model.WalletOperation, error) {
+	if op.Fee == nil || !op.Fee.Total.IsPositive() {
+		op.Fee = nil
+		return op, nil
+	}
+	// Копия: при повторе операции сервис передает ту же расшифровку
+	fee := *op.Fee
+
+	// Кошелек комиссий принадлежит тому же тенанту и ведется в той же валюте.
+	// Если это не так, конфликт не обновляет строку и RETURNING пуст
+	var balance decimal.Decimal
+	var version int
+	err := creditFeeStmt.queryRow(ctx, tx, fee.WalletID, tenant.FromContext(ctx), op.Currency, fee.Total).Scan(&balance, &version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// EnsureFeeWallets проверяет это при запуске; сюда попадаем, только если
+		// кошелек изменили в обход сервиса
+		return model.WalletOperation{}, apperror.ErrFeeWalletInvalid.Wrap(
+			fmt.Errorf("fee wallet %s belongs to another tenant or is not in %s", fee.WalletID, op.Currency))
+	}
+	if err != nil {
+		return model.WalletOperation{}, wrapDBError(err, "failed to credit fee")
+	}
+
+	credit, err := insertOperation(ctx, tx, model.WalletOperation{
+		WalletID:      fee.WalletID,
+		OperationType: model.OperationTypeFee,
+		Amount:        fee.Total,
+	}, balance, version, nil)
+	if err != nil {
+		return model.WalletOperation{}, err
+	}
+
+	fee.OperationID = credit.ID
+	op.Fee = &fee
+	return op, nil
+}
+
+// EnsureFeeWallets создает кошельки комиссий тенантов, которых еще нет, и проверяет,
+// что существующие принадлежат своему тенанту и ведутся в его валюте. Иначе каждая
+// операция с комиссией завершалась бы ошибкой, поэтому сервис не запускается
+func EnsureFeeWallets(ctx context.Context, pool *pgxpool.Pool, tenants []model.Tenant) error {
+	for _, t := range tenants {
+		walletID := t.Fees.WalletID
+		if walletID == uuid.Nil {
+			continue
+		}
+		// Конфигурация с комиссиями допускает ровно одну валюту тенанта
+		currency := t.DefaultCurrency()
+		_, err := pool.Exec(ctx, `INSERT INTO wallets (id, tenant_id, currency, balance, version) VALUES ($1, $2, $3, 0, 1)
+			ON CONFLICT (id) DO NOTHING`, walletID, t.ID, currency)
+		if err != nil {
+			return wrapDBError(err, "failed to reserve fee wallet")
+		}
+
+		var owner, ownerCurrency string
+		err = pool.QueryRow(ctx, `SELECT tenant_id, currency FROM wallets WHERE id = $1`, walletID).Scan(&owner, &ownerCurrency)
+		if err != nil {
+			return wrapDBError(err, "failed to check fee wallet")
+		}
+		if owner != t.ID || ownerCurrency != currency {
+			return fmt.Errorf("fee wallet %s of tenant %s belongs to tenant %s in %s, want %s",
+				walletID, t.ID, owner, ownerCurrency, currency)
+		}
+	}
+	return nil
+}
+
+// encodeFee готовит расшифровку для колонки JSONB; без комиссии - NULL
+func encodeFee(fee *model.FeeBreakdown) (sql.NullString, error) {
+	if fee == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(fee)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to encode fee: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+func decodeFee(data sql.NullString) (*model.FeeBreakdown, error) {
+	if !data.Valid {
+		return nil, nil
+	}
+	var fee model.FeeBreakdown
+	if err := json.Unmarshal([]byte(data.String), &fee); err != nil {
+		return nil, fmt.Errorf("failed to decode fee: %w", err)
+	}
+	return &fee, nil
+}
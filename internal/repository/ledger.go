@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+var ErrEntryNotFound = apperror.ErrEntryNotFound
+
+// LedgerRepository - чтение двойной записи. Проводки пишет WalletRepository
+// в транзакции операции
+type LedgerRepository interface {
+	GetEntry(ctx context.Context, id uuid.UUID) (model.JournalEntry, error)
+	TrialBalance(ctx context.Context) (model.TrialBalance, error)
+	BalancesAsOf(ctx context.Context, walletIDs []uuid.UUID, asOf time.Time) (map[uuid.UUID]decimal.Decimal, error)
+	TakeSnapshots(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+type ledgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) LedgerRepository {
+	return &ledgerRepository{db: db}
+}
+
+// entryPostings строит записи проводки операции. Встречный счет - внешний:
+// cash-in для пополнений, cash-out для списаний, system:adjustments для корректировок.
+// Сторно проводится по встречному счету исходной операции, поэтому возврат
+// пополнения уменьшает cash-in, а не растит cash-out
+func entryPostings(op model.WalletOperation, reversal bool) []model.Posting {
+	amount := op.Amount
+	if op.OperationType != model.OperationTypeDeposit {
+		amount = amount.Neg()
+	}
+
+	counter := model.AccountCashOut
+	if (op.OperationType == model.OperationTypeDeposit) != reversal {
+		counter = model.AccountCashIn
+	}
+	if op.AdjustmentID != nil {
+		counter = model.AccountAdjustments
+	}
+
+	postings := []model.Posting{
+		{Account: model.WalletAccount(op.WalletID), Amount: balanceDelta(op)},
+		{Account: counter, Amount: amount.Neg()},
+	}
+	if op.Fee != nil {
+		postings = append(postings, model.Posting{Account: model.WalletAccount(op.Fee.WalletID), Amount: op.Fee.Total})
+	}
+	return postings
+}
+
+// Суммы передаются строками и приводятся к numeric в запросе: pgx кодирует
+// массив numeric в двоичном формате, а decimal.Decimal - только текстом
+var insertEntryStmt = statement{name: "insert_entry",
+	sql: `WITH entry AS (INSERT INTO journal_entries (id, tenant_id) VALUES ($1, $4) RETURNING id, tenant_id)
+		INSERT INTO postings (entry_id, tenant_id, account, amount)
+		SELECT entry.id, entry.tenant_id, p.account, p.amount::numeric FROM entry, unnest($2::text[], $3::text[]) AS p(account, amount)`}
+
+// insertEntry записывает проводку тенанта запроса одним запросом. Баланс проверяется
+// и здесь, и триггером postings_balanced при коммите
+func insertEntry(ctx context.Context, tx pgx.Tx, id uuid.UUID, postings []model.Posting) error {
+	if sum := model.PostingsSum(postings); !sum.IsZero() {
+		return fmt.Errorf("journal entry %s is not balanced: postings sum to %s", id, sum)
+	}
+
+	accounts := make([]string, 0, len(postings))
+	amounts := make([]string, 0, len(postings))
+	for _, p := range postings {
+		if p.Amount.IsZero() {
+			continue
+		}
+		accounts = append(accounts, string(p.Account))
+		amounts = append(amounts, p.Amount.String())
+	}
+
+	if _, err := insertEntryStmt.exec(ctx, tx, id, accounts, amounts, tenant.FromContext(ctx)); err != nil {
+		return wrapDBError(err, "failed to record journal entry")
+	}
+	return nil
+}
+
+func (r *ledgerRepository) GetEntry(ctx context.Context, id uuid.UUID) (model.JournalEntry, error) {
+	query := `SELECT e.created_at, p.account, p.amount
+		FROM journal_entries e JOIN postings p ON p.entry_id = e.id
+		WHERE e.id = $1 AND e.tenant_id = $2 ORDER BY p.id`
+	rows, err := r.db.QueryContext(ctx, query, id, tenant.FromContext(ctx))
+	if err != nil {
+		return model.JournalEntry{}, fmt.Errorf("failed to get journal entry: %w", err)
+	}
+	defer rows.Close()
+
+	entry := model.JournalEntry{ID: id, Postings: []model.Posting{}}
+	for rows.Next() {
+		var p model.Posting
+		if err := rows.Scan(&entry.CreatedAt, &p.Account, &p.Amount); err != nil {
+			return model.JournalEntry{}, fmt.Errorf("failed to scan posting: %w", err)
+		}
+		entry.Postings = append(entry.Postings, p)
+	}
+	if err := rows.Err(); err != nil {
+		return model.JournalEntry{}, fmt.Errorf("failed to get journal entry: %w", err)
+	}
+	if len(entry.Postings) == 0 {
+		return model.JournalEntry{}, ErrEntryNotFound
+	}
+	return entry, nil
+}
+
+// TrialBalance считает остатки всех счетов тенанта по проводкам и сверяет счета
+// кошельков с wallets.balance. Читает все проводки - это отчет, а не горячий путь
+func (r *ledgerRepository) TrialBalance(ctx context.Context) (model.TrialBalance, error) {
+	// REPEATABLE READ: остатки и балансы кошельков из одного снимка, иначе
+	// параллельные операции дадут ложные расхождения
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return model.TrialBalance{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT COALESCE(p.account, 'wallet:' || w.id::text), COALESCE(p.balance, 0), w.balance
+		FROM (SELECT account, SUM(amount) AS balance FROM postings WHERE tenant_id = $1 GROUP BY account) p
+		FULL JOIN (SELECT id, balance FROM wallets WHERE tenant_id = $1) w ON p.account = 'wallet:' || w.id::text
+		WHERE p.account IS NOT NULL OR w.balance <> 0
+		ORDER BY 1`
+	rows, err := tx.QueryContext(ctx, query, tenant.FromContext(ctx))
+	if err != nil {
+		return model.TrialBalance{}, fmt.Errorf("failed to build trial balance: %w", err)
+	}
+	defer rows.Close()
+
+	report := model.TrialBalance{Accounts: []model.AccountBalance{}, Mismatches: []model.AccountBalance{}}
+	for rows.Next() {
+		var account model.AccountBalance
+		var walletBalance decimal.NullDecimal
+		if err := rows.Scan(&account.Account, &account.Balance, &walletBalance); err != nil {
+			return model.TrialBalance{}, fmt.Errorf("failed to scan account balance: %w", err)
+		}
+		if walletBalance.Valid {
+			account.WalletBalance = &walletBalance.Decimal
+			if !walletBalance.Decimal.Equal(account.Balance) {
+				report.Mismatches = append(report.Mismatches, account)
+			}
+		}
+		report.Accounts = append(report.Accounts, account)
+		report.Total = report.Total.Add(account.Balance)
+	}
+	if err := rows.Err(); err != nil {
+		return model.TrialBalance{}, fmt.Errorf("failed to build trial balance: %w", err)
+	}
+
+	report.Balanced = report.Total.IsZero() && len(report.Mismatches) == 0
+	return report, nil
+}
+
+// BalancesAsOf - балансы кошельков на момент asOf по проводкам: последний снимок
+// не позже asOf плюс записи после него. Кошельков, которых нет сейчас у тенанта, в результате нет
+func (r *ledgerRepository) BalancesAsOf(ctx context.Context, walletIDs []uuid.UUID, asOf time.Time) (map[uuid.UUID]decimal.Decimal, error) {
+	query := `SELECT r.id, COALESCE(s.balance, 0) + COALESCE(d.delta, 0)
+		FROM (SELECT id, 'wallet:' || id::text AS account FROM wallets WHERE id = ANY($1::uuid[]) AND tenant_id = $3) r
+		LEFT JOIN LATERAL (
+			SELECT taken_at, balance FROM balance_snapshots
+			WHERE tenant_id = $3 AND account = r.account AND taken_at <= $2
+			ORDER BY taken_at DESC LIMIT 1
+		) s ON true
+		LEFT JOIN LATERAL (
+			SELECT SUM(amount) AS delta FROM postings
+			WHERE tenant_id = $3 AND account = r.account AND created_at <= $2 AND created_at > COALESCE(s.taken_at, '-infinity')
+		) d ON true`
+	rows, err := r.db.QueryContext(ctx, query, walletIDs, asOf, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+	defer rows.Close()
+
+	balances := make(map[uuid.UUID]decimal.Decimal, len(walletIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var balance decimal.Decimal
+		if err := rows.Scan(&id, &balance); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		balances[id] = balance
+	}
+	return balances, rows.Err()
+}
+
+// snapshotLockKey - ключ advisory-блокировки: снимки снимает одна реплика за раз
+const snapshotLockKey = 0x736e6170
+
+// TakeSnapshots снимает остатки на cutoff для счетов, по которым были записи после
+// предыдущего снимка. cutoff должен отставать от текущего времени больше, чем длится
+// самая долгая транзакция: запись получает время начала транзакции, а видна после коммита
+func (r *ledgerRepository) TakeSnapshots(ctx context.Context, cutoff time.Time) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, snapshotLockKey).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to lock snapshots: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	// До первого снимка берутся все записи
+	var from any = "-infinity"
+	var previous time.Time
+	err = tx.QueryRowContext(ctx, `SELECT taken_at FROM balance_snapshot_runs ORDER BY taken_at DESC LIMIT 1`).Scan(&previous)
+	switch {
+	case err == nil:
+		if !cutoff.After(previous) {
+			return 0, nil
+		}
+		from = previous
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, fmt.Errorf("failed to get last snapshot: %w", err)
+	}
+
+	// Счета без записей с прошлого снимка не трогаем: их последний снимок все еще верен.
+	// Снимки общие для всех тенантов, но системные счета у каждого тенанта свои
+	query := `INSERT INTO balance_snapshots (tenant_id, account, taken_at, balance)
+		SELECT d.tenant_id, d.account, $2, COALESCE(l.balance, 0) + d.delta
+		FROM (
+			SELECT tenant_id, account, SUM(amount) AS delta FROM postings
+			WHERE created_at > $1 AND created_at <= $2
+			GROUP BY tenant_id, account
+		) d
+		LEFT JOIN LATERAL (
+			SELECT balance FROM balance_snapshots s
+			WHERE s.tenant_id = d.tenant_id AND s.account = d.account
+			ORDER BY taken_at DESC LIMIT 1
+		) l ON true`
+	result, err := tx.ExecContext(ctx, query, from, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to take snapshots: %w", err)
+	}
+	accounts, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO balance_snapshot_runs (taken_at, accounts) VALUES ($1, $2)`, cutoff, accounts); err != nil {
+		return 0, fmt.Errorf("failed to record snapshot run: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit snapshots: %w", err)
+	}
+	return int(accounts), nil
+}
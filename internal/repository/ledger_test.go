@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"testing"
+
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryPostings(t *testing.T) {
+	walletID := uuid.New()
+	feeWallet := uuid.New()
+	fee := &model.FeeBreakdown{Total: decimal.NewFromInt(16), WalletID: feeWallet}
+	adjustmentID := uuid.New()
+
+	tests := []struct {
+		name     string
+		op       model.WalletOperation
+		reversal bool
+		expected map[model.Account]int64
+	}{
+		{
+			name:     "deposit",
+			op:       model.WalletOperation{OperationType: model.OperationTypeDeposit, Amount: decimal.NewFromInt(1000)},
+			expected: map[model.Account]int64{model.WalletAccount(walletID): 1000, model.AccountCashIn: -1000},
+		},
+		{
+			name: "withdraw with fee",
+			op:   model.WalletOperation{OperationType: model.OperationTypeWithdraw, Amount: decimal.NewFromInt(1000), Fee: fee},
+			expected: map[model.Account]int64{
+				model.WalletAccount(walletID): -1016, model.AccountCashOut: 1000, model.WalletAccount(feeWallet): 16,
+			},
+		},
+		{
+			name: "deposit with fee",
+			op:   model.WalletOperation{OperationType: model.OperationTypeDeposit, Amount: decimal.NewFromInt(1000), Fee: fee},
+			expected: map[model.Account]int64{
+				model.WalletAccount(walletID): 984, model.AccountCashIn: -1000, model.WalletAccount(feeWallet): 16,
+			},
+		},
+		{
+			// Возврат пополнения уменьшает cash-in, а не проводится как выплата
+			name:     "reversal of deposit",
+			op:       model.WalletOperation{OperationType: model.OperationTypeWithdraw, Amount: decimal.NewFromInt(300)},
+			reversal: true,
+			expected: map[model.Account]int64{model.WalletAccount(walletID): -300, model.AccountCashIn: 300},
+		},
+		{
+			name:     "reversal of withdraw",
+			op:       model.WalletOperation{OperationType: model.OperationTypeDeposit, Amount: decimal.NewFromInt(300)},
+			reversal: true,
+			expected: map[model.Account]int64{model.WalletAccount(walletID): 300, model.AccountCashOut: -300},
+		},
+		{
+			name:     "debit adjustment",
+			op:       model.WalletOperation{OperationType: model.OperationTypeWithdraw, Amount: decimal.NewFromInt(50), AdjustmentID: &adjustmentID},
+			expected: map[model.Account]int64{model.WalletAccount(walletID): -50, model.AccountAdjustments: 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.op.WalletID = walletID
+			postings := entryPostings(tt.op, tt.reversal)
+
+			assert.True(t, model.PostingsSum(postings).IsZero())
+			actual := map[model.Account]int64{}
+			for _, p := range postings {
+				actual[p.Account] = p.Amount.IntPart()
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
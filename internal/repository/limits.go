@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+)
+
+var ErrLimitExceeded = apperror.ErrLimitExceeded
+
+type LimitsRepository interface {
+	// GetLimits возвращает лимиты, заданные для кошелька (без глобальных значений)
+	GetLimits(ctx context.Context, walletID uuid.UUID) (model.Limits, error)
+	SetLimits(ctx context.Context, walletID uuid.UUID, limits model.Limits) error
+	GetUsage(ctx context.Context, walletID uuid.UUID) (model.LimitUsage, error)
+	// SetCreditLimit меняет овердрафт кошелька; лимит меньше текущего долга - ErrCreditLimitBelowDebt
+	SetCreditLimit(ctx context.Context, walletID uuid.UUID, limit decimal.Decimal) (model.Wallet, error)
+	// SetTier меняет тариф кошелька, по которому выбирается комиссия
+	SetTier(ctx context.Context, walletID uuid.UUID, tier string) (model.Wallet, error)
+	// SetStatus замораживает или размораживает кошелек
+	SetStatus(ctx context.Context, walletID uuid.UUID, status model.WalletStatus) (model.Wallet, error)
+}
+
+type limitsRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewLimitsRepository(pool *pgxpool.Pool) LimitsRepository {
+	return &limitsRepository{pool: pool}
+}
+
+// querier - общее у *pgxpool.Pool и pgx.Tx: лимиты читаются и вне транзакции, и внутри UpdateBalance
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+const limitColumns = `max_operation_amount, daily_withdrawal_amount, daily_withdrawal_count, monthly_withdrawal_amount, monthly_withdrawal_count`
+
+var walletLimitsStmt = statement{name: "wallet_limits",
+	sql: `SELECT ` + limitColumns + ` FROM wallet_limits WHERE wallet_id = $1 AND tenant_id = $2`}
+
+func (r *limitsRepository) GetLimits(ctx context.Context, walletID uuid.UUID) (model.Limits, error) {
+	// LEFT JOIN отличает кошелек без своих лимитов от несуществующего кошелька
+	query := `SELECT ` + limitColumns + ` FROM wallets w LEFT JOIN wallet_limits l ON l.wallet_id = w.id WHERE w.id = $1 AND w.tenant_id = $2`
+	limits, err := scanLimits(r.pool.QueryRow(ctx, query, walletID, tenant.FromContext(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Limits{}, ErrWalletNotFound
+		}
+		return model.Limits{}, fmt.Errorf("failed to get limits: %w", err)
+	}
+	return limits, nil
+}
+
+func (r *limitsRepository) SetLimits(ctx context.Context, walletID uuid.UUID, limits model.Limits) error {
+	// Новые лимиты кошельку чужого тенанта не даст задать внешний ключ (tenant_id, wallet_id),
+	// а существующие не обновит условие WHERE - тогда не изменится ни одна строка
+	query := `INSERT INTO wallet_limits (wallet_id, tenant_id, ` + limitColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (wallet_id) DO UPDATE SET
+			max_operation_amount = EXCLUDED.max_operation_amount,
+			daily_withdrawal_amount = EXCLUDED.daily_withdrawal_amount,
+			daily_withdrawal_count = EXCLUDED.daily_withdrawal_count,
+			monthly_withdrawal_amount = EXCLUDED.monthly_withdrawal_amount,
+			monthly_withdrawal_count = EXCLUDED.monthly_withdrawal_count,
+			updated_at = now()
+		WHERE wallet_limits.tenant_id = EXCLUDED.tenant_id`
+	result, err := r.pool.Exec(ctx, query, walletID, tenant.FromContext(ctx),
+		nullDecimal(limits.MaxOperationAmount), nullDecimal(limits.DailyWithdrawalAmount), nullInt(limits.DailyWithdrawalCount),
+		nullDecimal(limits.MonthlyWithdrawalAmount), nullInt(limits.MonthlyWithdrawalCount))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrWalletNotFound
+		}
+		return fmt.Errorf("failed to set limits: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrWalletNotFound
+	}
+	return nil
+}
+
+func (r *limitsRepository) GetUsage(ctx context.Context, walletID uuid.UUID) (model.LimitUsage, error) {
+	usage, err := loadUsage(ctx, r.pool, walletID, time.Now())
+	if err != nil {
+		return model.LimitUsage{}, fmt.Errorf("failed to get limit usage: %w", err)
+	}
+	return usage, nil
+}
+
+func (r *limitsRepository) SetCreditLimit(ctx context.Context, walletID uuid.UUID, limit decimal.Decimal) (model.Wallet, error) {
+	// Версия растет: от кредитного лимита зависит доступный остаток, а значит и ETag кошелька
+	query := `UPDATE wallets SET credit_limit = $2, version = version + 1, updated_at = now() WHERE id = $1 AND tenant_id = $3
+		RETURNING ` + walletColumns
+	wallet, err := updateWallet(ctx, r.pool, query, walletID, limit, tenant.FromContext(ctx))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Wallet{}, ErrWalletNotFound
+		}
+		// wallets_balance_check: баланс ниже -limit
+		if isCheckViolation(err) {
+			return model.Wallet{}, apperror.ErrCreditLimitBelowDebt
+		}
+		return model.Wallet{}, fmt.Errorf("failed to set credit limit: %w", err)
+	}
+	return wallet, nil
+}
+
+func (r *limitsRepository) SetTier(ctx context.Context, walletID uuid.UUID, tier string) (model.Wallet, error) {
+	query := `UPDATE wallets SET tier = $2, version = version + 1, updated_at = now() WHERE id = $1 AND tenant_id = $3
+		RETURNING ` + walletColumns
+	wallet, err := updateWallet(ctx, r.pool, query, walletID, tier, tenant.FromContext(ctx))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Wallet{}, ErrWalletNotFound
+		}
+		return model.Wallet{}, fmt.Errorf("failed to set tier: %w", err)
+	}
+	return wallet, nil
+}
+
+func (r *limitsRepository) SetStatus(ctx context.Context, walletID uuid.UUID, status model.WalletStatus) (model.Wallet, error) {
+	query := `UPDATE wallets SET status = $2, version = version + 1, updated_at = now() WHERE id = $1 AND tenant_id = $3
+		RETURNING ` + walletColumns
+	wallet, err := updateWallet(ctx, r.pool, query, walletID, status, tenant.FromContext(ctx))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Wallet{}, ErrWalletNotFound
+		}
+		return model.Wallet{}, fmt.Errorf("failed to set status: %w", err)
+	}
+	return wallet, nil
+}
+
+// enforceLimits проверяет лимиты внутри транзакции операции. Параллельные списания
+// не могут вместе превысить лимит, но по разным причинам: pessimistic и advisory
+// держат блокировку кошелька до коммита, и второе списание видит расход первого;
+// при optimistic второе списание проверяет лимит по устаревшему расходу, но его
+// UPDATE не проходит проверку версии и операция повторяется; при serializable
+// Postgres откатывает одну из транзакций (40001) и она тоже повторяется.
+// Сторно лимитами не ограничивается
+func (r *walletRepository) enforceLimits(ctx context.Context, tx pgx.Tx, op model.WalletOperation, reversalOf *uuid.UUID) error {
+	if reversalOf != nil || op.AdjustmentID != nil {
+		return nil
+	}
+
+	overrides, err := scanLimits(walletLimitsStmt.queryRow(ctx, tx, op.WalletID, tenant.FromContext(ctx)))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return wrapDBError(err, "failed to get limits")
+	}
+	limits := overrides.Merge(r.tenants.Current(ctx).Limits)
+
+	var usage model.LimitUsage
+	if op.OperationType == model.OperationTypeWithdraw && limits.HasWithdrawalLimits() {
+		usage, err = loadUsage(ctx, tx, op.WalletID, time.Now())
+		if err != nil {
+			return wrapDBError(err, "failed to get limit usage")
+		}
+	}
+
+	return checkLimits(limits, usage, op)
+}
+
+// checkLimits возвращает LIMIT_EXCEEDED с именем нарушенного лимита в details
+func checkLimits(limits model.Limits, usage model.LimitUsage, op model.WalletOperation) error {
+	if limits.MaxOperationAmount != nil && op.Amount.GreaterThan(*limits.MaxOperationAmount) {
+		return ErrLimitExceeded.
+			WithDetail("maxOperationAmount limit exceeded").
+			WithDetails(map[string]any{"limit": "maxOperationAmount", "max": *limits.MaxOperationAmount})
+	}
+	if op.OperationType != model.OperationTypeWithdraw {
+		return nil
+	}
+
+	if limits.DailyWithdrawalCount != nil && usage.DailyWithdrawalCount+1 > *limits.DailyWithdrawalCount {
+		return limitExceeded("dailyWithdrawalCount", *limits.DailyWithdrawalCount, usage.DailyWithdrawalCount)
+	}
+	if limits.DailyWithdrawalAmount != nil && usage.DailyWithdrawalAmount.Add(op.Amount).GreaterThan(*limits.DailyWithdrawalAmount) {
+		return limitExceeded("dailyWithdrawalAmount", *limits.DailyWithdrawalAmount, usage.DailyWithdrawalAmount)
+	}
+	if limits.MonthlyWithdrawalCount != nil && usage.MonthlyWithdrawalCount+1 > *limits.MonthlyWithdrawalCount {
+		return limitExceeded("monthlyWithdrawalCount", *limits.MonthlyWithdrawalCount, usage.MonthlyWithdrawalCount)
+	}
+	if limits.MonthlyWithdrawalAmount != nil && usage.MonthlyWithdrawalAmount.Add(op.Amount).GreaterThan(*limits.MonthlyWithdrawalAmount) {
+		return limitExceeded("monthlyWithdrawalAmount", *limits.MonthlyWithdrawalAmount, usage.MonthlyWithdrawalAmount)
+	}
+	return nil
+}
+
+func limitExceeded(name string, limit, used any) error {
+	return ErrLimitExceeded.
+		WithDetail("%s limit exceeded", name).
+		WithDetails(map[string]any{"limit": name, "max": limit, "used": used})
+}
+
+// loadUsage считает списания (без сторно) с начала текущих суток и месяца по UTC
+func loadUsage(ctx context.Context, q querier, walletID uuid.UUID, now time.Time) (model.LimitUsage, error) {
+	dayStart, monthStart := usagePeriods(now)
+
+	var usage model.LimitUsage
+	query := `SELECT
+			COALESCE(SUM(amount) FILTER (WHERE created_at >= $2), 0),
+			COUNT(*) FILTER (WHERE created_at >= $2),
+			COALESCE(SUM(amount), 0),
+			COUNT(*)
+		FROM operations
+		WHERE wallet_id = $1 AND tenant_id = $5 AND operation_type = $4 AND reversal_of IS NULL AND adjustment_id IS NULL AND created_at >= $3`
+	err := q.QueryRow(ctx, query, walletID, dayStart, monthStart, model.OperationTypeWithdraw, tenant.FromContext(ctx)).Scan(
+		&usage.DailyWithdrawalAmount, &usage.DailyWithdrawalCount,
+		&usage.MonthlyWithdrawalAmount, &usage.MonthlyWithdrawalCount)
+	return usage, err
+}
+
+func usagePeriods(now time.Time) (dayStart, monthStart time.Time) {
+	now = now.UTC()
+	dayStart = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return dayStart, monthStart
+}
+
+func scanLimits(row rowScanner) (model.Limits, error) {
+	var maxAmount, dailyAmount, monthlyAmount decimal.NullDecimal
+	var dailyCount, monthlyCount sql.NullInt64
+
+	if err := row.Scan(&maxAmount, &dailyAmount, &dailyCount, &monthlyAmount, &monthlyCount); err != nil {
+		return model.Limits{}, err
+	}
+	return model.Limits{
+		MaxOperationAmount:      decimalPtr(maxAmount),
+		DailyWithdrawalAmount:   decimalPtr(dailyAmount),
+		DailyWithdrawalCount:    intPtr(dailyCount),
+		MonthlyWithdrawalAmount: decimalPtr(monthlyAmount),
+		MonthlyWithdrawalCount:  intPtr(monthlyCount),
+	}, nil
+}
+
+func decimalPtr(d decimal.NullDecimal) *decimal.Decimal {
+	if !d.Valid {
+		return nil
+	}
+	return &d.Decimal
+}
+
+func intPtr(n sql.NullInt64) *int {
+	if !n.Valid {
+		return nil
+	}
+	v := int(n.Int64)
+	return &v
+}
+
+func nullDecimal(d *decimal.Decimal) decimal.NullDecimal {
+	if d == nil {
+		return decimal.NullDecimal{}
+	}
+	return decimal.NullDecimal{Decimal: *d, Valid: true}
+}
+
+func nullInt(n *int) sql.NullInt64 {
+	if n == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*n), Valid: true}
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func amount(v int64) *decimal.Decimal {
+	d := decimal.NewFromInt(v)
+	return &d
+}
+
+func count(v int) *int {
+	return &v
+}
+
+func TestCheckLimits(t *testing.T) {
+	limits := model.Limits{
+		MaxOperationAmount:      amount(1000),
+		DailyWithdrawalAmount:   amount(1500),
+		DailyWithdrawalCount:    count(3),
+		MonthlyWithdrawalAmount: amount(5000),
+	}
+	usage := model.LimitUsage{
+		DailyWithdrawalAmount:   decimal.NewFromInt(1000),
+		DailyWithdrawalCount:    2,
+		MonthlyWithdrawalAmount: decimal.NewFromInt(4800),
+		MonthlyWithdrawalCount:  10,
+	}
+
+	tests := []struct {
+		name      string
+		opType    model.OperationType
+		amount    int64
+		usage     model.LimitUsage
+		violation string
+	}{
+		{"deposit within max amount", model.OperationTypeDeposit, 1000, usage, ""},
+		{"deposit over max amount", model.OperationTypeDeposit, 1001, usage, "maxOperationAmount"},
+		{"withdraw fits all limits", model.OperationTypeWithdraw, 100, model.LimitUsage{}, ""},
+		{"daily amount reached exactly", model.OperationTypeWithdraw, 500, model.LimitUsage{DailyWithdrawalAmount: decimal.NewFromInt(1000)}, ""},
+		{"daily amount exceeded", model.OperationTypeWithdraw, 501, model.LimitUsage{DailyWithdrawalAmount: decimal.NewFromInt(1000)}, "dailyWithdrawalAmount"},
+		{"daily count exceeded", model.OperationTypeWithdraw, 1, model.LimitUsage{DailyWithdrawalCount: 3}, "dailyWithdrawalCount"},
+		{"monthly amount exceeded", model.OperationTypeWithdraw, 300, usage, "monthlyWithdrawalAmount"},
+		{"deposits ignore withdrawal usage", model.OperationTypeDeposit, 10, model.LimitUsage{DailyWithdrawalCount: 100}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := model.WalletOperation{WalletID: uuid.New(), OperationType: tt.opType, Amount: decimal.NewFromInt(tt.amount)}
+			err := checkLimits(limits, tt.usage, op)
+
+			if tt.violation == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, apperror.ErrLimitExceeded)
+			var appErr *apperror.Error
+			if assert.True(t, errors.As(err, &appErr)) {
+				assert.Equal(t, tt.violation, appErr.Details["limit"])
+			}
+		})
+	}
+}
+
+func TestCheckLimits_NoLimits(t *testing.T) {
+	op := model.WalletOperation{OperationType: model.OperationTypeWithdraw, Amount: decimal.NewFromInt(1_000_000)}
+
+	assert.NoError(t, checkLimits(model.Limits{}, model.LimitUsage{DailyWithdrawalCount: 1000}, op))
+}
+
+func TestUsagePeriods(t *testing.T) {
+	// 01:30 по Москве - еще предыдущие сутки по UTC
+	now := time.Date(2024, time.March, 1, 1, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	day, month := usagePeriods(now)
+
+	assert.Equal(t, time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC), day)
+	assert.Equal(t, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), month)
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// LockStrategy определяет, как UpdateBalance защищает кошелек от конкурентных изменений:
+// уровень изоляции транзакции и способ чтения строки кошелька
+type LockStrategy interface {
+	Name() string
+	TxOptions() pgx.TxOptions
+	// LockWallet читает кошелек (баланс, версию, кредитный лимит, валюту, статус) внутри транзакции.
+	// Если у тенанта запроса такого кошелька нет, возвращает pgx.ErrNoRows
+	LockWallet(ctx context.Context, tx pgx.Tx, id uuid.UUID) (model.Wallet, error)
+}
+
+const (
+	LockPessimistic  = "pessimistic"
+	LockOptimistic   = "optimistic"
+	LockSerializable = "serializable"
+	LockAdvisory     = "advisory"
+)
+
+// LockStrategies - все доступные стратегии в порядке, удобном для сравнения в нагрузочном тесте
+var LockStrategies = []string{LockPessimistic, LockOptimistic, LockSerializable, LockAdvisory}
+
+func ParseLockStrategy(name string) (LockStrategy, error) {
+	switch name {
+	case "", LockPessimistic:
+		return pessimisticLock{}, nil
+	case LockOptimistic:
+		return optimisticLock{}, nil
+	case LockSerializable:
+		return serializableLock{}, nil
+	case LockAdvisory:
+		return advisoryLock{}, nil
+	default:
+		return nil, fmt.Errorf("unknown lock strategy %q", name)
+	}
+}
+
+// selectWalletQuery читает кошелек тенанта запроса: чужой кошелек - как несуществующий
+const selectWalletQuery = `SELECT balance, version, credit_limit, currency, status FROM wallets WHERE id = $1 AND tenant_id = $2`
+
+var (
+	selectWalletStmt    = statement{name: "select_wallet", sql: selectWalletQuery}
+	selectForUpdateStmt = statement{name: "select_wallet_for_update", sql: selectWalletQuery + ` FOR UPDATE`}
+)
+
+func selectWallet(ctx context.Context, tx pgx.Tx, stmt statement, id uuid.UUID) (model.Wallet, error) {
+	wallet := model.Wallet{ID: id}
+	err := stmt.queryRow(ctx, tx, id, tenant.FromContext(ctx)).Scan(&wallet.Balance, &wallet.Version, &wallet.CreditLimit, &wallet.Currency, &wallet.Status)
+	return wallet, err
+}
+
+// pessimisticLock - READ COMMITTED + SELECT ... FOR UPDATE: конкурирующие операции ждут друг друга
+type pessimisticLock struct{}
+
+func (pessimisticLock) Name() string { return LockPessimistic }
+
+func (pessimisticLock) TxOptions() pgx.TxOptions {
+	return pgx.TxOptions{IsoLevel: pgx.ReadCommitted}
+}
+
+func (pessimisticLock) LockWallet(ctx context.Context, tx pgx.Tx, id uuid.UUID) (model.Wallet, error) {
+	return selectWallet(ctx, tx, selectForUpdateStmt, id)
+}
+
+// optimisticLock - без блокировки строки, конфликт ловится проверкой версии в UPDATE
+type optimisticLock struct{}
+
+func (optimisticLock) Name() string { return LockOptimistic }
+
+func (optimisticLock) TxOptions() pgx.TxOptions {
+	return pgx.TxOptions{IsoLevel: pgx.ReadCommitted}
+}
+
+func (optimisticLock) LockWallet(ctx context.Context, tx pgx.Tx, id uuid.UUID) (model.Wallet, error) {
+	return selectWallet(ctx, tx, selectWalletStmt, id)
+}
+
+// serializableLock - SERIALIZABLE без явных блокировок; конфликты Postgres
+// возвращает как serialization failure (40001), и сервис повторяет операцию
+type serializableLock struct{}
+
+func (serializableLock) Name() string { return LockSerializable }
+
+func (serializableLock) TxOptions() pgx.TxOptions {
+	return pgx.TxOptions{IsoLevel: pgx.Serializable}
+}
+
+func (serializableLock) LockWallet(ctx context.Context, tx pgx.Tx, id uuid.UUID) (model.Wallet, error) {
+	return selectWallet(ctx, tx, selectWalletStmt, id)
+}
+
+// advisoryLock - транзакционная advisory-блокировка по id кошелька. В отличие от
+// FOR UPDATE сериализует и создание еще не существующего кошелька
+type advisoryLock struct{}
+
+func (advisoryLock) Name() string { return LockAdvisory }
+
+func (advisoryLock) TxOptions() pgx.TxOptions {
+	return pgx.TxOptions{IsoLevel: pgx.ReadCommitted}
+}
+
+func (advisoryLock) LockWallet(ctx context.Context, tx pgx.Tx, id uuid.UUID) (model.Wallet, error) {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, advisoryKey(id)); err != nil {
+		return model.Wallet{}, err
+	}
+	return selectWallet(ctx, tx, selectWalletStmt, id)
+}
+
+// advisoryKey сворачивает UUID в bigint-ключ advisory-блокировки
+func advisoryKey(id uuid.UUID) int64 {
+	return int64(binary.BigEndian.Uint64(id[:8]) ^ binary.BigEndian.Uint64(id[8:]))
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+const operationColumns = `id, wallet_id, operation_type, amount, balance_after, wallet_version, reversal_of, reversed_amount, fee, adjustment_id, created_at`
+
+var (
+	getOperationStmt    = statement{name: "get_operation", sql: `SELECT ` + operationColumns + ` FROM operations WHERE id = $1 AND tenant_id = $2`}
+	findIdempotencyStmt = statement{name: "find_idempotency_key",
+		sql: `SELECT ` + operationColumns + ` FROM operations WHERE tenant_id = $1 AND idempotency_key = $2`}
+	insertOperationStmt = statement{name: "insert_operation",
+		sql: `INSERT INTO operations (id, tenant_id, wallet_id, operation_type, amount, balance_after, wallet_version, idempotency_key, reversal_of, fee, adjustment_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING amount, balance_after, created_at`}
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOperation(row rowScanner) (model.Operation, error) {
+	var op model.Operation
+	var reversalOf uuid.NullUUID
+	var fee sql.NullString
+	var adjustmentID uuid.NullUUID
+
+	err := row.Scan(&op.ID, &op.WalletID, &op.OperationType, &op.Amount, &op.BalanceAfter,
+		&op.WalletVersion, &reversalOf, &op.ReversedAmount, &fee, &adjustmentID, &op.CreatedAt)
+	if err != nil {
+		return model.Operation{}, err
+	}
+	if reversalOf.Valid {
+		op.ReversalOf = &reversalOf.UUID
+	}
+	if adjustmentID.Valid {
+		op.AdjustmentID = &adjustmentID.UUID
+	}
+	op.Fee, err = decodeFee(fee)
+	return op, err
+}
+
+func (r *walletRepository) GetOperation(ctx context.Context, id uuid.UUID) (model.Operation, error) {
+	var op model.Operation
+	err := getOperationStmt.queryPool(ctx, r.pool, func(row pgx.Row) (err error) {
+		op, err = scanOperation(row)
+		return err
+	}, id, tenant.FromContext(ctx))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Operation{}, apperror.ErrOperationNotFound
+		}
+		return model.Operation{}, fmt.Errorf("failed to get operation: %w", err)
+	}
+	return op, nil
+}
+
+func (r *walletRepository) ReverseOperation(ctx context.Context, rev model.Reversal) (model.Operation, error) {
+	tx, err := r.pool.BeginTx(ctx, r.lock.TxOptions())
+	if err != nil {
+		return model.Operation{}, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	existing, err := findByIdempotencyKey(ctx, tx, rev.IdempotencyKey)
+	if err != nil {
+		return model.Operation{}, err
+	}
+	if existing != nil {
+		if existing.ReversalOf == nil || *existing.ReversalOf != rev.OperationID ||
+			(rev.Amount != nil && !existing.Amount.Equal(*rev.Amount)) {
+			return model.Operation{}, apperror.ErrIdempotencyKeyReused
+		}
+		return *existing, nil
+	}
+
+	// Блокируем исходную операцию: параллельные сторно одной операции выполняются по очереди
+	query := `SELECT ` + operationColumns + ` FROM operations WHERE id = $1 AND tenant_id = $2 FOR UPDATE`
+	original, err := scanOperation(tx.QueryRow(ctx, query, rev.OperationID, tenant.FromContext(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Operation{}, apperror.ErrOperationNotFound
+		}
+		return model.Operation{}, wrapDBError(err, "failed to get operation")
+	}
+
+	if original.ReversalOf != nil {
+		return model.Operation{}, apperror.ErrReversalNotAllowed.WithDetail("a reversal cannot be reversed")
+	}
+	if original.OperationType == model.OperationTypeFee {
+		return model.Operation{}, apperror.ErrReversalNotAllowed.WithDetail("fee credits cannot be reversed")
+	}
+	// Сторно обошло бы двойной контроль - исправляется встречной корректировкой
+	if original.AdjustmentID != nil {
+		return model.Operation{}, apperror.ErrReversalNotAllowed.WithDetail("adjustments cannot be reversed, propose an opposite adjustment")
+	}
+
+	remaining := original.Amount.Sub(original.ReversedAmount)
+	if !remaining.IsPositive() {
+		return model.Operation{}, apperror.ErrAlreadyReversed
+	}
+
+	amount := remaining
+	if rev.Amount != nil {
+		if rev.Amount.GreaterThan(remaining) {
+			return model.Operation{}, apperror.ErrReversalExceeded.WithDetails(map[string]any{"remaining": remaining})
+		}
+		amount = *rev.Amount
+	}
+
+	reversal, err := r.applyOperation(ctx, tx, model.WalletOperation{
+		WalletID:       original.WalletID,
+		OperationType:  original.OperationType.Opposite(),
+		Amount:         amount,
+		IdempotencyKey: rev.IdempotencyKey,
+	}, &original.ID)
+	if errors.Is(err, ErrInsufficientFunds) {
+		// Средства от сторнируемого пополнения уже потрачены
+		return model.Operation{}, ErrInsufficientFunds.WithDetail("reversal would overdraw the wallet")
+	}
+	if err != nil {
+		return model.Operation{}, err
+	}
+
+	updateQuery := `UPDATE operations SET reversed_amount = reversed_amount + $1 WHERE id = $2`
+	if _, err := tx.Exec(ctx, updateQuery, amount, original.ID); err != nil {
+		return model.Operation{}, wrapDBError(err, "failed to update reversed amount")
+	}
+
+	return reversal, commit(ctx, tx)
+}
+
+func insertOperation(ctx context.Context, tx pgx.Tx, op model.WalletOperation, balanceAfter decimal.Decimal, walletVersion int, reversalOf *uuid.UUID) (model.Operation, error) {
+	result := model.Operation{
+		ID:            uuid.New(),
+		WalletID:      op.WalletID,
+		OperationType: op.OperationType,
+		Amount:        op.Amount,
+		BalanceAfter:  balanceAfter,
+		WalletVersion: walletVersion,
+		ReversalOf:    reversalOf,
+		Fee:           op.Fee,
+		AdjustmentID:  op.AdjustmentID,
+	}
+
+	fee, err := encodeFee(op.Fee)
+	if err != nil {
+		return model.Operation{}, err
+	}
+
+	var reversal uuid.NullUUID
+	if reversalOf != nil {
+		reversal = uuid.NullUUID{UUID: *reversalOf, Valid: true}
+	}
+
+	var adjustment uuid.NullUUID
+	if op.AdjustmentID != nil {
+		adjustment = uuid.NullUUID{UUID: *op.AdjustmentID, Valid: true}
+	}
+
+	// Суммы читаем обратно округленными БД: хеш звена цепочки должен совпасть при проверке
+	err = insertOperationStmt.queryRow(ctx, tx, result.ID, tenant.FromContext(ctx), result.WalletID, result.OperationType, result.Amount,
+		balanceAfter, walletVersion, sql.NullString{String: op.IdempotencyKey, Valid: op.IdempotencyKey != ""}, reversal, fee, adjustment,
+	).Scan(&result.Amount, &result.BalanceAfter, &result.CreatedAt)
+	if err != nil {
+		// Тот же ключ идемпотентности параллельно записал другой запрос - после повтора вернем его результат
+		if isUniqueViolation(err) {
+			return model.Operation{}, ErrOptimisticLock
+		}
+		return model.Operation{}, wrapDBError(err, "failed to record operation")
+	}
+
+	if err := appendChain(ctx, tx, result); err != nil {
+		return model.Operation{}, err
+	}
+	if err := notifyOperation(ctx, tx, result); err != nil {
+		return model.Operation{}, err
+	}
+	return result, nil
+}
+
+// findByIdempotencyKey возвращает ранее выполненную операцию тенанта с тем же ключом или nil
+func findByIdempotencyKey(ctx context.Context, tx pgx.Tx, key string) (*model.Operation, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	op, err := scanOperation(findIdempotencyStmt.queryRow(ctx, tx, tenant.FromContext(ctx), key))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, wrapDBError(err, "failed to check idempotency key")
+	}
+	return &op, nil
+}
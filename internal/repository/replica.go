@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+
+	"wallet-service/internal/database"
+	"wallet-service/internal/metrics"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+)
+
+// ReplicaPicker выбирает реплику для чтения; nil - читать с основного сервера
+type ReplicaPicker interface {
+	Replica(ctx context.Context) *pgxpool.Pool
+}
+
+// replicaWalletRepository читает кошельки с реплик, а изменения и остальные запросы
+// выполняет на основном сервере. Реплика, вернувшая ошибку, подменяется основным
+// сервером: чтение не должно падать из-за отставшей или недоступной реплики
+type replicaWalletRepository struct {
+	WalletRepository
+	picker   ReplicaPicker
+	replicas map[*pgxpool.Pool]WalletRepository
+}
+
+// NewReplicaWalletRepository направляет чтения кошельков primary на реплики cluster.
+// Чтение с токеном согласованности в контексте (database.WithConsistencyToken) ждет,
+// пока реплика догонит токен, или идет на основной сервер
+func NewReplicaWalletRepository(primary WalletRepository, cluster *database.Cluster, lock LockStrategy, tenants *tenant.Registry) WalletRepository {
+	replicas := make(map[*pgxpool.Pool]WalletRepository, len(cluster.Replicas))
+	for _, pool := range cluster.Replicas {
+		replicas[pool] = NewWalletRepository(pool, lock, tenants)
+	}
+	return &replicaWalletRepository{WalletRepository: primary, picker: cluster, replicas: replicas}
+}
+
+func (r *replicaWalletRepository) GetBalance(ctx context.Context, id uuid.UUID) (decimal.Decimal, error) {
+	if replica := r.replica(ctx); replica != nil {
+		balance, err := replica.GetBalance(ctx, id)
+		if err == nil {
+			metrics.ReplicaReads.Add(1)
+			return balance, nil
+		}
+		metrics.ReplicaFallbacks.Add(1)
+	}
+	return r.WalletRepository.GetBalance(ctx, id)
+}
+
+func (r *replicaWalletRepository) GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error) {
+	if replica := r.replica(ctx); replica != nil {
+		wallet, err := replica.GetWallet(ctx, id)
+		if err == nil {
+			metrics.ReplicaReads.Add(1)
+			return wallet, nil
+		}
+		metrics.ReplicaFallbacks.Add(1)
+	}
+	return r.WalletRepository.GetWallet(ctx, id)
+}
+
+func (r *replicaWalletRepository) replica(ctx context.Context) WalletRepository {
+	pool := r.picker.Replica(ctx)
+	if pool == nil {
+		return nil
+	}
+	return r.replicas[pool]
+}
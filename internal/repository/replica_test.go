@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixedPicker struct {
+	pool *pgxpool.Pool
+}
+
+func (p fixedPicker) Replica(ctx context.Context) *pgxpool.Pool {
+	return p.pool
+}
+
+// stubWalletRepository отдает кошелек с заданной версией или ошибку
+type stubWalletRepository struct {
+	WalletRepository
+	version int
+	err     error
+	reads   int
+}
+
+func (r *stubWalletRepository) GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error) {
+	r.reads++
+	return model.Wallet{ID: id, Version: r.version}, r.err
+}
+
+func TestReplicaWalletRepository_GetWallet(t *testing.T) {
+	// Пул не подключается до первого запроса - нужен только как ключ
+	pool, err := pgxpool.New(context.Background(), "")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	primary := &stubWalletRepository{version: 2}
+	replica := &stubWalletRepository{version: 1}
+	repo := &replicaWalletRepository{
+		WalletRepository: primary,
+		picker:           fixedPicker{pool: pool},
+		replicas:         map[*pgxpool.Pool]WalletRepository{pool: replica},
+	}
+
+	wallet, err := repo.GetWallet(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 1, wallet.Version)
+	assert.Equal(t, 0, primary.reads)
+
+	// Ошибка реплики - читаем с основного сервера
+	replica.err = errors.New("connection refused")
+	wallet, err = repo.GetWallet(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 2, wallet.Version)
+
+	// Реплика не догнала токен - тоже основной сервер
+	repo.picker = fixedPicker{}
+	replica.reads = 0
+	repo.GetWallet(context.Background(), uuid.New())
+	assert.Equal(t, 0, replica.reads)
+	assert.Equal(t, 2, primary.reads)
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrScheduleNotFound  = apperror.ErrScheduleNotFound
+	ErrScheduleNotActive = apperror.ErrScheduleNotActive
+)
+
+// RunFunc выполняет наступившее задание и возвращает результат запуска и время
+// следующего запуска (nil - заданий больше не будет)
+type RunFunc func(job model.ScheduledOperation) (model.ScheduledRun, *time.Time)
+
+type ScheduleRepository interface {
+	CreateScheduled(ctx context.Context, job model.ScheduledOperation) (model.ScheduledOperation, error)
+	GetScheduled(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error)
+	ListScheduled(ctx context.Context, walletID uuid.UUID) ([]model.ScheduledOperation, error)
+	CancelScheduled(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error)
+	ListRuns(ctx context.Context, id uuid.UUID) ([]model.ScheduledRun, error)
+	// ClaimDue забирает до limit наступивших заданий (FOR UPDATE SKIP LOCKED), выполняет
+	// каждое через run и записывает результат. Задания, заблокированные другой репликой, пропускаются
+	ClaimDue(ctx context.Context, now time.Time, limit int, run RunFunc) (int, error)
+}
+
+type scheduleRepository struct {
+	db *sql.DB
+}
+
+func NewScheduleRepository(db *sql.DB) ScheduleRepository {
+	return &scheduleRepository{db: db}
+}
+
+const scheduledColumns = `id, tenant_id, wallet_id, operation_type, amount, schedule, next_run_at, status, created_at`
+
+func scanScheduled(row rowScanner) (model.ScheduledOperation, error) {
+	var job model.ScheduledOperation
+	var schedule sql.NullString
+	var nextRunAt sql.NullTime
+
+	err := row.Scan(&job.ID, &job.TenantID, &job.WalletID, &job.OperationType, &job.Amount, &schedule, &nextRunAt, &job.Status, &job.CreatedAt)
+	if err != nil {
+		return model.ScheduledOperation{}, err
+	}
+	job.Schedule = schedule.String
+	if nextRunAt.Valid {
+		job.NextRunAt = &nextRunAt.Time
+	}
+	return job, nil
+}
+
+func (r *scheduleRepository) CreateScheduled(ctx context.Context, job model.ScheduledOperation) (model.ScheduledOperation, error) {
+	job.TenantID = tenant.FromContext(ctx)
+	query := `INSERT INTO scheduled_operations (id, tenant_id, wallet_id, operation_type, amount, schedule, next_run_at, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at`
+	err := r.db.QueryRowContext(ctx, query, job.ID, job.TenantID, job.WalletID, job.OperationType, job.Amount,
+		sql.NullString{String: job.Schedule, Valid: job.Schedule != ""}, job.NextRunAt, job.Status,
+	).Scan(&job.CreatedAt)
+	if err != nil {
+		return model.ScheduledOperation{}, fmt.Errorf("failed to create scheduled operation: %w", err)
+	}
+	return job, nil
+}
+
+func (r *scheduleRepository) GetScheduled(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error) {
+	query := `SELECT ` + scheduledColumns + ` FROM scheduled_operations WHERE id = $1 AND tenant_id = $2`
+	job, err := scanScheduled(r.db.QueryRowContext(ctx, query, id, tenant.FromContext(ctx)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.ScheduledOperation{}, ErrScheduleNotFound
+		}
+		return model.ScheduledOperation{}, fmt.Errorf("failed to get scheduled operation: %w", err)
+	}
+	return job, nil
+}
+
+func (r *scheduleRepository) ListScheduled(ctx context.Context, walletID uuid.UUID) ([]model.ScheduledOperation, error) {
+	query := `SELECT ` + scheduledColumns + ` FROM scheduled_operations WHERE wallet_id = $1 AND tenant_id = $2 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, walletID, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled operations: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []model.ScheduledOperation{}
+	for rows.Next() {
+		job, err := scanScheduled(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled operation: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (r *scheduleRepository) CancelScheduled(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error) {
+	// Если задание сейчас выполняет планировщик, UPDATE дождется конца его транзакции
+	query := `UPDATE scheduled_operations SET status = $2, next_run_at = NULL, updated_at = now()
+		WHERE id = $1 AND tenant_id = $4 AND status = $3
+		RETURNING ` + scheduledColumns
+	job, err := scanScheduled(r.db.QueryRowContext(ctx, query, id, model.ScheduleStatusCancelled, model.ScheduleStatusActive, tenant.FromContext(ctx)))
+	if errors.Is(err, sql.ErrNoRows) {
+		// Отличаем уже завершенное задание от несуществующего
+		if _, err := r.GetScheduled(ctx, id); err != nil {
+			return model.ScheduledOperation{}, err
+		}
+		return model.ScheduledOperation{}, ErrScheduleNotActive
+	}
+	if err != nil {
+		return model.ScheduledOperation{}, fmt.Errorf("failed to cancel scheduled operation: %w", err)
+	}
+	return job, nil
+}
+
+func (r *scheduleRepository) ListRuns(ctx context.Context, id uuid.UUID) ([]model.ScheduledRun, error) {
+	if _, err := r.GetScheduled(ctx, id); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT scheduled_operation_id, scheduled_at, executed_at, status, operation_id, error_code, error
+		FROM scheduled_operation_runs WHERE scheduled_operation_id = $1 ORDER BY scheduled_at`
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []model.ScheduledRun{}
+	for rows.Next() {
+		var run model.ScheduledRun
+		var operationID uuid.NullUUID
+		var errorCode, errorText sql.NullString
+		if err := rows.Scan(&run.ScheduledOperationID, &run.ScheduledAt, &run.ExecutedAt, &run.Status,
+			&operationID, &errorCode, &errorText); err != nil {
+			return nil, fmt.Errorf("failed to scan run: %w", err)
+		}
+		if operationID.Valid {
+			run.OperationID = &operationID.UUID
+		}
+		run.ErrorCode = errorCode.String
+		run.Error = errorText.String
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (r *scheduleRepository) ClaimDue(ctx context.Context, now time.Time, limit int, run RunFunc) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Блокировки строк держатся до конца транзакции: пока реплика выполняет задание,
+	// другие реплики его не видят. Если реплика упадет, задание вернется в очередь,
+	// а повтор операции отсечет ключ идемпотентности
+	query := `SELECT ` + scheduledColumns + ` FROM scheduled_operations
+		WHERE status = $1 AND next_run_at <= $2
+		ORDER BY next_run_at
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED`
+	rows, err := tx.QueryContext(ctx, query, model.ScheduleStatusActive, now, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim scheduled operations: %w", err)
+	}
+	var jobs []model.ScheduledOperation
+	for rows.Next() {
+		job, err := scanScheduled(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan scheduled operation: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to claim scheduled operations: %w", err)
+	}
+
+	for _, job := range jobs {
+		result, next := run(job)
+		if err := recordRun(ctx, tx, result, next); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit scheduled runs: %w", err)
+	}
+	return len(jobs), nil
+}
+
+func recordRun(ctx context.Context, tx *sql.Tx, run model.ScheduledRun, next *time.Time) error {
+	var operationID uuid.NullUUID
+	if run.OperationID != nil {
+		operationID = uuid.NullUUID{UUID: *run.OperationID, Valid: true}
+	}
+
+	insertQuery := `INSERT INTO scheduled_operation_runs (scheduled_operation_id, scheduled_at, executed_at, status, operation_id, error_code, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (scheduled_operation_id, scheduled_at) DO NOTHING`
+	_, err := tx.ExecContext(ctx, insertQuery, run.ScheduledOperationID, run.ScheduledAt, run.ExecutedAt, run.Status, operationID,
+		sql.NullString{String: run.ErrorCode, Valid: run.ErrorCode != ""}, sql.NullString{String: run.Error, Valid: run.Error != ""})
+	if err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+
+	// Разовая операция завершается после первого запуска - успешного или нет
+	status := model.ScheduleStatusActive
+	if next == nil {
+		status = model.ScheduleStatusCompleted
+		if run.Status == model.RunStatusFailed {
+			status = model.ScheduleStatusFailed
+		}
+	}
+
+	updateQuery := `UPDATE scheduled_operations SET next_run_at = $2, status = $3, updated_at = now() WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, updateQuery, run.ScheduledOperationID, next, status); err != nil {
+		return fmt.Errorf("failed to reschedule operation: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// statement - горячий запрос, который выполняется именованным prepared statement.
+// Он готовится на соединении при первом использовании, дальше Postgres не разбирает
+// и не планирует его заново. Остальные запросы пул выполняет без подготовки на сервере
+type statement struct {
+	name string
+	sql  string
+}
+
+// conn - соединение, на котором готовится запрос: pgx.Tx или *pgxpool.Conn
+type conn interface {
+	Conn() *pgx.Conn
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func (s statement) queryRow(ctx context.Context, c conn, args ...any) pgx.Row {
+	// Повторный Prepare того же запроса на соединении не обращается к серверу
+	if _, err := c.Conn().Prepare(ctx, s.name, s.sql); err != nil {
+		return errRow{err}
+	}
+	return c.QueryRow(ctx, s.name, args...)
+}
+
+func (s statement) exec(ctx context.Context, c conn, args ...any) (pgconn.CommandTag, error) {
+	if _, err := c.Conn().Prepare(ctx, s.name, s.sql); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return c.Exec(ctx, s.name, args...)
+}
+
+// queryPool выполняет запрос на соединении из пула и передает строку в scan
+func (s statement) queryPool(ctx context.Context, pool *pgxpool.Pool, scan func(pgx.Row) error, args ...any) error {
+	return pool.AcquireFunc(ctx, func(c *pgxpool.Conn) error {
+		return scan(s.queryRow(ctx, c, args...))
+	})
+}
+
+// errRow - строка, которую не удалось запросить
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...any) error {
+	return r.err
+}
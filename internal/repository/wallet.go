@@ -3,39 +3,67 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"wallet-service/internal/apperror"
 	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/shopspring/decimal"
 )
 
 var (
-	ErrWalletNotFound		= errors.New("wallet not found")
-	ErrOptimisticLock 		= errors.New("optimistic lock conflict")
-	ErrInsufficientFunds	= errors.New("insufficient funds")
+	ErrWalletNotFound		= apperror.ErrWalletNotFound
+	ErrOptimisticLock 		= apperror.ErrConcurrencyConflict
+	ErrInsufficientFunds	= apperror.ErrInsufficientFunds
+	ErrVersionMismatch		= apperror.ErrPreconditionFailed
 )
 
 type WalletRepository interface {
     GetBalance(ctx context.Context, id uuid.UUID) (decimal.Decimal, error)
-    UpdateBalance(ctx context.Context, op model.WalletOperation) error
+    GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error)
+    UpdateBalance(ctx context.Context, op model.WalletOperation) (model.Operation, error)
+    GetOperation(ctx context.Context, id uuid.UUID) (model.Operation, error)
+    // ReverseOperation сторнирует операцию полностью или частично, создавая связанную операцию
+    ReverseOperation(ctx context.Context, rev model.Reversal) (model.Operation, error)
+    // ListWallets возвращает страницу кошельков тенанта в порядке filter.Sort
+    ListWallets(ctx context.Context, filter model.WalletFilter) ([]model.Wallet, error)
+    UpdateMetadata(ctx context.Context, id uuid.UUID, meta model.WalletMetadata) (model.Wallet, error)
 }
 
+// walletRepository видит только кошельки тенанта из контекста запроса
 type walletRepository struct {
-	db *sql.DB
+	pool *pgxpool.Pool
+	lock LockStrategy
+	// tenants - лимиты тенантов; лимиты кошелька из wallet_limits их переопределяют
+	tenants *tenant.Registry
 }
 
-func NewWalletRepository(db *sql.DB) WalletRepository {
-	return &walletRepository{db: db}
+func NewWalletRepository(pool *pgxpool.Pool, lock LockStrategy, tenants *tenant.Registry) WalletRepository {
+	return &walletRepository{pool: pool, lock: lock, tenants: tenants}
 }
 
+// Горячие запросы операций и чтения кошелька
+var (
+	getBalanceStmt    = statement{name: "get_balance", sql: `SELECT balance FROM wallets WHERE id = $1 AND tenant_id = $2`}
+	getWalletStmt     = statement{name: "get_wallet", sql: `SELECT ` + walletColumns + ` FROM wallets WHERE id = $1 AND tenant_id = $2`}
+	createWalletStmt  = statement{name: "create_wallet", sql: `INSERT INTO wallets (id, tenant_id, currency, balance, version) VALUES ($1, $2, $3, $4, $5)`}
+	updateBalanceStmt = statement{name: "update_balance",
+		sql: `UPDATE wallets SET balance = $1, version = version + 1, updated_at = now() WHERE id = $2 AND tenant_id = $3 AND version = $4`}
+)
+
 func (r *walletRepository) GetBalance(ctx context.Context, id uuid.UUID) (decimal.Decimal, error) {
 	var balance decimal.Decimal
 	
-	query := `SELECT balance FROM wallets WHERE id = $1`
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&balance)
+	err := getBalanceStmt.queryPool(ctx, r.pool, func(row pgx.Row) error {
+		return row.Scan(&balance)
+	}, id, tenant.FromContext(ctx))
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return decimal.Zero, ErrWalletNotFound
 		}
 		return decimal.Zero, fmt.Errorf("failed to get balance: %w", err)
@@ -44,72 +72,330 @@ func (r *walletRepository) GetBalance(ctx context.Context, id uuid.UUID) (decima
 	return balance, nil
 }
 
+const walletColumns = `id, balance, version, credit_limit, tier, currency, owner_ref, display_name, labels, status, created_at, updated_at`
+
+func scanWallet(row rowScanner) (model.Wallet, error) {
+	var wallet model.Wallet
+	var labels []byte
 
-func (r *walletRepository) UpdateBalance(ctx context.Context, op model.WalletOperation) error {
-    tx, err := r.db.BeginTx(ctx, &sql.TxOptions{
-        Isolation: sql.LevelReadCommitted,
-    })
-    if err != nil {
-        return fmt.Errorf("failed to begin transaction: %w", err)
-    }
-    defer tx.Rollback()
-
-    // Пытаемся найти кошелек
-    var currentBalance decimal.Decimal
-    var version int
-    
-    query := `SELECT balance, version FROM wallets WHERE id = $1 FOR UPDATE`
-    err = tx.QueryRowContext(ctx, query, op.WalletID).Scan(&currentBalance, &version)
-    
-    if err != nil && !errors.Is(err, sql.ErrNoRows) {
-        return fmt.Errorf("failed to get wallet: %w", err)
-    }
-
-    // Если кошелек не найден
-    if errors.Is(err, sql.ErrNoRows) {
-        // Для DEPOSIT - создаем новый кошелек
-        if op.OperationType == model.OperationTypeDeposit {
-            createQuery := `INSERT INTO wallets (id, balance, version) VALUES ($1, $2, $3)`
-            _, err := tx.ExecContext(ctx, createQuery, op.WalletID, op.Amount, 1)
-            if err != nil {
-                return fmt.Errorf("failed to create wallet: %w", err)
-            }
-            return tx.Commit()
-        } else {
-            // Для WITHDRAW - кошелек не существует
-            return ErrWalletNotFound
-        }
-    }
-
-    // Если кошелек существует - обычная логика
-    if op.OperationType == model.OperationTypeWithdraw {
-        if currentBalance.LessThan(op.Amount) {
-            return ErrInsufficientFunds
-        }
-    }
-
-    var newBalance decimal.Decimal
-    if op.OperationType == model.OperationTypeDeposit {
-        newBalance = currentBalance.Add(op.Amount)
-    } else {
-        newBalance = currentBalance.Sub(op.Amount)
-    }
-
-    updateQuery := `UPDATE wallets SET balance = $1, version = version + 1 WHERE id = $2 AND version = $3`
-    result, err := tx.ExecContext(ctx, updateQuery, newBalance, op.WalletID, version)
-    if err != nil {
-        return fmt.Errorf("failed to update balance: %w", err)
-    }
-
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        return fmt.Errorf("failed to get rows affected: %w", err)
-    }
-
-    if rowsAffected == 0 {
-        return ErrOptimisticLock
-    }
-
-    return tx.Commit()
+	err := row.Scan(&wallet.ID, &wallet.Balance, &wallet.Version, &wallet.CreditLimit, &wallet.Tier, &wallet.Currency,
+		&wallet.OwnerRef, &wallet.DisplayName, &labels, &wallet.Status, &wallet.CreatedAt, &wallet.UpdatedAt)
+	if err != nil {
+		return model.Wallet{}, err
+	}
+	if err := json.Unmarshal(labels, &wallet.Labels); err != nil {
+		return model.Wallet{}, fmt.Errorf("failed to decode wallet labels: %w", err)
+	}
+	return wallet, nil
 }
 
+func (r *walletRepository) GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error) {
+	var wallet model.Wallet
+	err := getWalletStmt.queryPool(ctx, r.pool, func(row pgx.Row) (err error) {
+		wallet, err = scanWallet(row)
+		return err
+	}, id, tenant.FromContext(ctx))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Wallet{}, ErrWalletNotFound
+		}
+		return model.Wallet{}, fmt.Errorf("failed to get wallet: %w", err)
+	}
+
+	return wallet, nil
+}
+
+
+func (r *walletRepository) UpdateBalance(ctx context.Context, op model.WalletOperation) (model.Operation, error) {
+	tx, err := r.pool.BeginTx(ctx, r.lock.TxOptions())
+	if err != nil {
+		return model.Operation{}, wrapDBError(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	// Повтор запроса с тем же ключом возвращает результат первого выполнения
+	existing, err := findByIdempotencyKey(ctx, tx, op.IdempotencyKey)
+	if err != nil {
+		return model.Operation{}, err
+	}
+	if existing != nil {
+		if existing.WalletID != op.WalletID || existing.OperationType != op.OperationType ||
+			!existing.Amount.Equal(op.Amount) || existing.ReversalOf != nil {
+			return model.Operation{}, apperror.ErrIdempotencyKeyReused
+		}
+		return *existing, nil
+	}
+
+	result, err := r.applyOperation(ctx, tx, op, nil)
+	if err != nil {
+		return model.Operation{}, err
+	}
+
+	return result, commit(ctx, tx)
+}
+
+// applyOperation меняет баланс кошелька и записывает операцию в журнал.
+// reversalOf - id сторнируемой операции, если это сторно
+func (r *walletRepository) applyOperation(ctx context.Context, tx pgx.Tx, op model.WalletOperation, reversalOf *uuid.UUID) (model.Operation, error) {
+	// Пытаемся найти кошелек (способ блокировки зависит от стратегии)
+	wallet, err := r.lock.LockWallet(ctx, tx, op.WalletID)
+
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return model.Operation{}, wrapDBError(err, "failed to get wallet")
+	}
+
+	// Если кошелек не найден
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Клиент ожидает конкретную версию или любую (If-Match: *), а кошелька нет -
+		// условие не выполнено
+		if op.ExpectedVersion != nil || op.MustExist {
+			return model.Operation{}, ErrVersionMismatch
+		}
+
+		// Для WITHDRAW - кошелек не существует
+		if op.OperationType != model.OperationTypeDeposit {
+			return model.Operation{}, ErrWalletNotFound
+		}
+
+		if err := r.enforceLimits(ctx, tx, op, reversalOf); err != nil {
+			return model.Operation{}, err
+		}
+
+		// id кошельков уникальны для всех тенантов: занятый чужим кошельком id
+		// повтором не исправить
+		if err := checkWalletIDFree(ctx, tx, op.WalletID); err != nil {
+			return model.Operation{}, err
+		}
+
+		// Для DEPOSIT - создаем новый кошелек
+		if op.Currency == "" {
+			op.Currency = r.tenants.Current(ctx).DefaultCurrency()
+		}
+		// Новый кошелек открывается с тарифом по умолчанию
+		if op.FeeTier != "" && op.FeeTier != model.DefaultTier {
+			return model.Operation{}, ErrOptimisticLock
+		}
+		balance := balanceDelta(op)
+		_, err := createWalletStmt.exec(ctx, tx, op.WalletID, tenant.FromContext(ctx), op.Currency, balance, 1)
+		if err != nil {
+			// Кошелек успел создать параллельный запрос - повторяем операцию
+			if isUniqueViolation(err) {
+				return model.Operation{}, ErrOptimisticLock
+			}
+			return model.Operation{}, wrapDBError(err, "failed to create wallet")
+		}
+		if op, err = creditFee(ctx, tx, op); err != nil {
+			return model.Operation{}, err
+		}
+		return recordOperation(ctx, tx, op, balance, 1, reversalOf)
+	}
+
+	// Кошелек изменился с момента, когда клиент его прочитал
+	if op.ExpectedVersion != nil && *op.ExpectedVersion != wallet.Version {
+		return model.Operation{}, ErrVersionMismatch
+	}
+
+	// Замороженный кошелек меняется только одобренной корректировкой
+	if wallet.Status == model.WalletFrozen && op.AdjustmentID == nil {
+		return model.Operation{}, apperror.ErrWalletFrozen
+	}
+
+	if op.Currency != "" && op.Currency != wallet.Currency {
+		return model.Operation{}, apperror.ErrCurrencyMismatch.WithDetail("wallet currency is %s", wallet.Currency)
+	}
+	// Комиссия зачисляется в валюте кошелька
+	op.Currency = wallet.Currency
+
+	if err := r.enforceLimits(ctx, tx, op, reversalOf); err != nil {
+		return model.Operation{}, err
+	}
+
+	// Сервис считал комиссию по тарифу, прочитанному до транзакции. Если тариф успели
+	// сменить, комиссия неверна - повтор пересчитает ее
+	if op.FeeTier != "" && op.FeeTier != wallet.Tier {
+		return model.Operation{}, ErrOptimisticLock
+	}
+
+	// Если кошелек существует - обычная логика. Списывать можно до -CreditLimit,
+	// комиссия за списание списывается вместе с суммой
+	delta := balanceDelta(op)
+	if delta.IsNegative() && wallet.Available().LessThan(delta.Neg()) {
+		return model.Operation{}, ErrInsufficientFunds
+	}
+
+	newBalance := wallet.Balance.Add(delta)
+	if !model.ValidAmount(newBalance) {
+		return model.Operation{}, balanceOutOfRange()
+	}
+
+	result, err := updateBalanceStmt.exec(ctx, tx, newBalance, op.WalletID, tenant.FromContext(ctx), wallet.Version)
+	if err != nil {
+		// wallets_balance_check: кредитный лимит успели уменьшить после чтения кошелька
+		if isCheckViolation(err) {
+			return model.Operation{}, ErrInsufficientFunds
+		}
+		if isNumericOverflow(err) {
+			return model.Operation{}, balanceOutOfRange()
+		}
+		return model.Operation{}, wrapDBError(err, "failed to update balance")
+	}
+
+	if result.RowsAffected() == 0 {
+		return model.Operation{}, ErrOptimisticLock
+	}
+
+	if op, err = creditFee(ctx, tx, op); err != nil {
+		return model.Operation{}, err
+	}
+	return recordOperation(ctx, tx, op, newBalance, wallet.Version+1, reversalOf)
+}
+
+// walletSortColumns - колонка и тип значения курсора для каждого порядка списка
+var walletSortColumns = map[model.WalletSort][2]string{
+	model.SortCreatedAt:     {"created_at", "timestamptz"},
+	model.SortCreatedAtDesc: {"created_at", "timestamptz"},
+	model.SortUpdatedAt:     {"updated_at", "timestamptz"},
+	model.SortUpdatedAtDesc: {"updated_at", "timestamptz"},
+	model.SortBalance:       {"balance", "numeric"},
+	model.SortBalanceDesc:   {"balance", "numeric"},
+}
+
+func (r *walletRepository) ListWallets(ctx context.Context, filter model.WalletFilter) ([]model.Wallet, error) {
+	conditions := []string{"tenant_id = $1"}
+	args := []any{tenant.FromContext(ctx)}
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "$%d", fmt.Sprintf("$%d", len(args))))
+	}
+	if filter.OwnerRef != "" {
+		add("owner_ref = $%d", filter.OwnerRef)
+	}
+	if len(filter.Labels) > 0 {
+		labels, err := json.Marshal(filter.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode labels filter: %w", err)
+		}
+		add("labels @> $%d::jsonb", string(labels))
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+	if filter.MinBalance != nil {
+		add("balance >= $%d", *filter.MinBalance)
+	}
+	if filter.MaxBalance != nil {
+		add("balance <= $%d", *filter.MaxBalance)
+	}
+
+	// Курсор - пара (значение, id) последнего кошелька: id различает кошельки с равным значением
+	sort, ok := walletSortColumns[filter.Sort]
+	if !ok {
+		sort = walletSortColumns[model.SortCreatedAt]
+	}
+	column, direction, compare := sort[0], "ASC", ">"
+	if filter.Sort.Desc() {
+		direction, compare = "DESC", "<"
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.Value, filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d::%s, $%d)", column, compare, len(args)-1, sort[1], len(args)))
+	}
+	args = append(args, filter.Limit)
+
+	query := `SELECT ` + walletColumns + ` FROM wallets
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY ` + column + ` ` + direction + `, id ` + direction + ` LIMIT $` + fmt.Sprint(len(args))
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wallets: %w", err)
+	}
+	defer rows.Close()
+
+	wallets := []model.Wallet{}
+	for rows.Next() {
+		wallet, err := scanWallet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wallet: %w", err)
+		}
+		wallets = append(wallets, wallet)
+	}
+	return wallets, rows.Err()
+}
+
+// UpdateMetadata меняет переданные поля метаданных. Версия растет, как при смене
+// тарифа: метаданные входят в ответ GET кошелька, а значит и в его ETag
+func (r *walletRepository) UpdateMetadata(ctx context.Context, id uuid.UUID, meta model.WalletMetadata) (model.Wallet, error) {
+	var labels sql.NullString
+	if meta.Labels != nil {
+		data, err := json.Marshal(meta.Labels)
+		if err != nil {
+			return model.Wallet{}, fmt.Errorf("failed to encode labels: %w", err)
+		}
+		labels = sql.NullString{String: string(data), Valid: true}
+	}
+
+	query := `UPDATE wallets SET owner_ref = COALESCE($3, owner_ref), display_name = COALESCE($4, display_name),
+			labels = COALESCE($5::jsonb, labels), version = version + 1, updated_at = now()
+		WHERE id = $1 AND tenant_id = $2
+		RETURNING ` + walletColumns
+	wallet, err := updateWallet(ctx, r.pool, query, id, tenant.FromContext(ctx), meta.OwnerRef, meta.DisplayName, labels)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Wallet{}, ErrWalletNotFound
+		}
+		return model.Wallet{}, fmt.Errorf("failed to update wallet metadata: %w", err)
+	}
+	return wallet, nil
+}
+
+// checkWalletIDFree проверяет, что id не занят кошельком другого тенанта.
+// Свой кошелек мог успеть создать параллельный запрос - тогда операцию повторяем
+func checkWalletIDFree(ctx context.Context, tx pgx.Tx, id uuid.UUID) error {
+	var owner string
+	err := tx.QueryRow(ctx, `SELECT tenant_id FROM wallets WHERE id = $1`, id).Scan(&owner)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil
+	case err != nil:
+		return wrapDBError(err, "failed to check wallet id")
+	case owner == tenant.FromContext(ctx):
+		return ErrOptimisticLock
+	}
+	return apperror.Validation(apperror.FieldError{Field: "walletId", Message: "is not available, use another id"})
+}
+
+// recordOperation записывает операцию в журнал операций и ее проводку
+func recordOperation(ctx context.Context, tx pgx.Tx, op model.WalletOperation, balanceAfter decimal.Decimal, walletVersion int, reversalOf *uuid.UUID) (model.Operation, error) {
+	result, err := insertOperation(ctx, tx, op, balanceAfter, walletVersion, reversalOf)
+	if err != nil {
+		return model.Operation{}, err
+	}
+	if err := insertEntry(ctx, tx, result.ID, entryPostings(op, reversalOf != nil)); err != nil {
+		return model.Operation{}, err
+	}
+	return result, nil
+}
+
+// balanceOutOfRange - баланс после операции не помещается в NUMERIC(20,2)
+func balanceOutOfRange() error {
+	return apperror.Validation(apperror.FieldError{Field: "amount", Message: "would take the wallet balance out of the supported range"})
+}
+
+// balanceDelta - изменение баланса кошелька с учетом комиссии
+func balanceDelta(op model.WalletOperation) decimal.Decimal {
+	var fee decimal.Decimal
+	if op.Fee != nil {
+		fee = op.Fee.Total
+	}
+	if op.OperationType == model.OperationTypeDeposit {
+		return op.Amount.Sub(fee)
+	}
+	return op.Amount.Add(fee).Neg()
+}
+
+func commit(ctx context.Context, tx pgx.Tx) error {
+	if err := tx.Commit(ctx); err != nil {
+		return wrapDBError(err, "failed to commit transaction")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletRepository_MustExistDoesNotCreateWallet(t *testing.T) {
+	pool := testPool(t)
+	ctx := tenant.NewContext(context.Background(), "must-exist-"+uuid.NewString()[:8])
+	wallets := NewWalletRepository(pool, pessimisticLock{}, tenant.NewRegistry())
+
+	deposit := model.WalletOperation{
+		WalletID:      uuid.New(),
+		OperationType: model.OperationTypeDeposit,
+		Amount:        decimal.NewFromInt(10),
+		Currency:      "USD",
+		MustExist:     true,
+	}
+	_, err := wallets.UpdateBalance(ctx, deposit)
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+	_, err = wallets.GetWallet(ctx, deposit.WalletID)
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+
+	// Существующий кошелек пополняется при любой версии
+	deposit.MustExist = false
+	_, err = wallets.UpdateBalance(ctx, deposit)
+	require.NoError(t, err)
+	deposit.MustExist = true
+	_, err = wallets.UpdateBalance(ctx, deposit)
+	require.NoError(t, err)
+}
+
+func TestWalletRepository_RejectsFeeOfAnotherTier(t *testing.T) {
+	pool := testPool(t)
+	ctx := tenant.NewContext(context.Background(), "fee-tier-"+uuid.NewString()[:8])
+	wallets := NewWalletRepository(pool, pessimisticLock{}, tenant.NewRegistry())
+
+	// Новый кошелек открывается с тарифом по умолчанию
+	deposit := model.WalletOperation{
+		WalletID:      uuid.New(),
+		OperationType: model.OperationTypeDeposit,
+		Amount:        decimal.NewFromInt(10),
+		Currency:      "USD",
+		FeeTier:       "premium",
+	}
+	_, err := wallets.UpdateBalance(ctx, deposit)
+	assert.ErrorIs(t, err, ErrOptimisticLock)
+
+	deposit.FeeTier = model.DefaultTier
+	_, err = wallets.UpdateBalance(ctx, deposit)
+	require.NoError(t, err)
+
+	// Комиссия посчитана по тарифу, которого у кошелька уже нет
+	deposit.FeeTier = "premium"
+	_, err = wallets.UpdateBalance(ctx, deposit)
+	assert.ErrorIs(t, err, ErrOptimisticLock)
+}
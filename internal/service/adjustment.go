@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+
+	"wallet-service/internal/audit"
+	"wallet-service/internal/model"
+	"wallet-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+// AdjustmentService - ручные корректировки баланса с двойным контролем (админский API)
+type AdjustmentService struct {
+	repo  repository.AdjustmentRepository
+	retry RetryPolicy
+}
+
+func NewAdjustmentService(repo repository.AdjustmentRepository, retry RetryPolicy) *AdjustmentService {
+	return &AdjustmentService{
+		repo:  repo,
+		retry: retry,
+	}
+}
+
+// Propose создает корректировку в статусе PENDING; баланс не меняется до одобрения
+func (s *AdjustmentService) Propose(ctx context.Context, operator string, req model.AdjustmentRequest) (model.Adjustment, error) {
+	audit.SetWallet(ctx, req.WalletID)
+	return s.repo.CreateAdjustment(ctx, model.Adjustment{
+		ID:         uuid.New(),
+		WalletID:   req.WalletID,
+		Direction:  req.Direction,
+		Amount:     req.Amount,
+		ReasonCode: req.ReasonCode,
+		Comment:    req.Comment,
+		Status:     model.AdjustmentPending,
+		ProposedBy: operator,
+	})
+}
+
+func (s *AdjustmentService) Get(ctx context.Context, id uuid.UUID) (model.Adjustment, error) {
+	return s.repo.GetAdjustment(ctx, id)
+}
+
+func (s *AdjustmentService) List(ctx context.Context, filter model.AdjustmentFilter) ([]model.Adjustment, error) {
+	return s.repo.ListAdjustments(ctx, filter)
+}
+
+// Approve применяет корректировку. Повторы при конфликтах безопасны: статус
+// перечитывается в каждой попытке, и примененную корректировку второй раз не применить
+func (s *AdjustmentService) Approve(ctx context.Context, review model.AdjustmentReview) (model.Adjustment, error) {
+	adj, err := withRetry(ctx, s.retry, func() (model.Adjustment, error) {
+		return s.repo.ApproveAdjustment(ctx, review)
+	})
+	if err == nil {
+		audit.SetWallet(ctx, adj.WalletID)
+		audit.SetOperation(ctx, *adj.OperationID)
+	}
+	return adj, err
+}
+
+func (s *AdjustmentService) Reject(ctx context.Context, review model.AdjustmentReview) (model.Adjustment, error) {
+	adj, err := s.repo.RejectAdjustment(ctx, review)
+	if err == nil {
+		audit.SetWallet(ctx, adj.WalletID)
+	}
+	return adj, err
+}
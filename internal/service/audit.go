@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+
+	"wallet-service/internal/model"
+	"wallet-service/internal/repository"
+)
+
+const (
+	// DefaultAuditPageSize и MaxAuditPageSize - размер страницы журнала аудита
+	DefaultAuditPageSize = 100
+	MaxAuditPageSize     = 1000
+)
+
+// AuditService - журнал аудита изменяющих запросов
+type AuditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) *AuditService {
+	return &AuditService{
+		repo: repo,
+	}
+}
+
+func (s *AuditService) Record(ctx context.Context, rec model.AuditRecord) error {
+	_, err := s.repo.InsertAudit(ctx, rec)
+	return err
+}
+
+// List возвращает страницу записей; следующую страницу дает filter.AfterID = NextAfter
+func (s *AuditService) List(ctx context.Context, filter model.AuditFilter) (model.AuditPage, error) {
+	if filter.Limit <= 0 || filter.Limit > MaxAuditPageSize {
+		filter.Limit = DefaultAuditPageSize
+	}
+
+	records, err := s.repo.ListAudit(ctx, filter)
+	if err != nil {
+		return model.AuditPage{}, err
+	}
+
+	page := model.AuditPage{Records: records}
+	if len(records) == filter.Limit {
+		next := records[len(records)-1].ID
+		page.NextAfter = &next
+	}
+	return page, nil
+}
+
+// Export передает в fn все записи, подходящие под фильтр, постранично.
+// filter.Limit задает размер страницы, а не общее число записей
+func (s *AuditService) Export(ctx context.Context, filter model.AuditFilter, fn func(model.AuditRecord) error) error {
+	for {
+		page, err := s.List(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, rec := range page.Records {
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+		if page.NextAfter == nil {
+			return nil
+		}
+		filter.AfterID = *page.NextAfter
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"wallet-service/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAuditRepository отдает записи с id 1..n по курсору, как ListAudit
+type fakeAuditRepository struct {
+	n      int64
+	limits []int
+}
+
+func (f *fakeAuditRepository) InsertAudit(ctx context.Context, rec model.AuditRecord) (model.AuditRecord, error) {
+	f.n++
+	rec.ID = f.n
+	return rec, nil
+}
+
+func (f *fakeAuditRepository) ListAudit(ctx context.Context, filter model.AuditFilter) ([]model.AuditRecord, error) {
+	f.limits = append(f.limits, filter.Limit)
+	records := []model.AuditRecord{}
+	for id := filter.AfterID + 1; id <= f.n && len(records) < filter.Limit; id++ {
+		records = append(records, model.AuditRecord{ID: id})
+	}
+	return records, nil
+}
+
+func TestAuditService_List(t *testing.T) {
+	repo := &fakeAuditRepository{n: 150}
+	service := NewAuditService(repo)
+
+	page, err := service.List(context.Background(), model.AuditFilter{Limit: MaxAuditPageSize + 1})
+	assert.NoError(t, err)
+	assert.Len(t, page.Records, DefaultAuditPageSize)
+	assert.Equal(t, int64(DefaultAuditPageSize), *page.NextAfter)
+
+	page, err = service.List(context.Background(), model.AuditFilter{AfterID: *page.NextAfter})
+	assert.NoError(t, err)
+	assert.Len(t, page.Records, 50)
+	assert.Nil(t, page.NextAfter)
+}
+
+func TestAuditService_ExportPages(t *testing.T) {
+	repo := &fakeAuditRepository{n: 25}
+	service := NewAuditService(repo)
+
+	var ids []int64
+	err := service.Export(context.Background(), model.AuditFilter{Limit: 10}, func(rec model.AuditRecord) error {
+		ids = append(ids, rec.ID)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, ids, 25)
+	assert.Equal(t, int64(25), ids[24])
+	assert.Equal(t, []int{10, 10, 10}, repo.limits)
+}
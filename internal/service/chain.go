@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"wallet-service/internal/model"
+	"wallet-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ErrCheckpointInvalid - контрольная точка не сходится: подпись, digest или связь с предыдущей точкой
+var ErrCheckpointInvalid = errors.New("chain checkpoint is invalid")
+
+// checkpointPageSize - сколько точек читать за раз при проверке
+const checkpointPageSize = 100
+
+// errStopWalk останавливает обход цепочки на первом нарушенном звене
+var errStopWalk = errors.New("stop walk")
+
+// ChainService проверяет цепочки хешей операций и публикует подписанные контрольные точки
+type ChainService struct {
+	repo repository.ChainRepository
+	// key - ключ подписи точек; nil - точки не создаются
+	key ed25519.PrivateKey
+}
+
+func NewChainService(repo repository.ChainRepository, key ed25519.PrivateKey) *ChainService {
+	return &ChainService{
+		repo: repo,
+		key:  key,
+	}
+}
+
+// PublicKey - ключ проверки подписей точек; nil, если ключ подписи не задан
+func (s *ChainService) PublicKey() ed25519.PublicKey {
+	if s.key == nil {
+		return nil
+	}
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Verify проходит цепочку кошелька от начала и находит первое нарушенное звено:
+// пропуск в номерах, несовпадение хеша с операцией или с предыдущим звеном,
+// расхождение с последней контрольной точкой и операции без звена
+func (s *ChainService) Verify(ctx context.Context, walletID uuid.UUID) (model.ChainVerification, error) {
+	result := model.ChainVerification{WalletID: walletID, Valid: true}
+
+	checkpoint, err := s.repo.CheckpointHead(ctx, walletID)
+	if err != nil {
+		return model.ChainVerification{}, err
+	}
+	result.Checkpoint = checkpoint
+
+	prev := model.GenesisHash
+	format := model.ChainFormatV1
+	var head *model.ChainHead
+	err = s.repo.WalkChain(ctx, walletID, func(link model.ChainLink) error {
+		operationID := link.Operation.ID
+		broken := func(reason string) error {
+			result.Valid = false
+			result.BrokenAt = &model.ChainBreak{Seq: link.Seq, OperationID: &operationID, Reason: reason}
+			return errStopWalk
+		}
+
+		switch {
+		case link.Seq != result.Checked+1:
+			result.Valid = false
+			result.BrokenAt = &model.ChainBreak{Seq: result.Checked + 1, Reason: "link is missing"}
+			return errStopWalk
+		case link.Missing:
+			return broken("operation of the link is missing")
+		case link.Operation.WalletID != walletID:
+			return broken("operation belongs to another wallet")
+		case !link.PrevHash.Equal(prev):
+			return broken("previous hash does not match the previous link")
+		case link.Format < format || link.Format > model.ChainFormat:
+			// Формат не бывает ниже, чем у предыдущего звена: понижение - подмена
+			return broken("hash format is unknown or older than the previous link")
+		case !link.ExpectedHash(prev).Equal(link.Hash):
+			return broken("hash does not match the operation")
+		case checkpoint != nil && link.Seq == checkpoint.Seq && !link.Hash.Equal(checkpoint.Hash):
+			return broken("hash does not match the signed checkpoint")
+		}
+
+		result.Checked++
+		prev, format = link.Hash, link.Format
+		head = &model.ChainHead{WalletID: walletID, Seq: link.Seq, Hash: link.Hash, OperationID: &operationID}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopWalk) {
+		return model.ChainVerification{}, err
+	}
+	result.Head = head
+	if !result.Valid {
+		return result, nil
+	}
+
+	// Звенья с конца удалены вместе с операциями - цепочка короче заверенной
+	if checkpoint != nil && result.Checked < checkpoint.Seq {
+		result.Valid = false
+		result.BrokenAt = &model.ChainBreak{Seq: result.Checked + 1, Reason: "link is missing, the chain is shorter than the signed checkpoint"}
+		return result, nil
+	}
+
+	unchained, err := s.repo.FirstUnchained(ctx, walletID)
+	if err != nil {
+		return model.ChainVerification{}, err
+	}
+	if unchained != nil {
+		result.Valid = false
+		result.BrokenAt = &model.ChainBreak{OperationID: &unchained.ID, Reason: "operation is not in the chain"}
+	}
+	return result, nil
+}
+
+// Wallets - кошельки, у которых есть цепочка
+func (s *ChainService) Wallets(ctx context.Context) ([]uuid.UUID, error) {
+	return s.repo.ChainWallets(ctx)
+}
+
+func (s *ChainService) LatestCheckpoint(ctx context.Context) (model.ChainCheckpoint, error) {
+	return s.repo.LatestCheckpoint(ctx)
+}
+
+// Checkpoint создает и подписывает контрольную точку. nil - головы не менялись
+// или точку в этот момент создает другая реплика
+func (s *ChainService) Checkpoint(ctx context.Context) (*model.ChainCheckpoint, error) {
+	if s.key == nil {
+		return nil, errors.New("chain signing key is not configured")
+	}
+	return s.repo.CreateCheckpoint(ctx, time.Now(), func(digest model.Hash) ([]byte, error) {
+		return ed25519.Sign(s.key, digest), nil
+	})
+}
+
+// RunCheckpoints публикует точку раз в interval и блокируется до отмены ctx.
+// Digest и подпись пишутся и в лог, чтобы копия точки хранилась вне БД
+func (s *ChainService) RunCheckpoints(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cp, err := s.Checkpoint(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("chain checkpoint: %v", err)
+		case cp != nil:
+			log.Printf("chain checkpoint %d: %d heads, digest %x, signature %x", cp.ID, len(cp.Heads), []byte(cp.Digest), cp.Signature)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// VerifyCheckpoints проверяет все точки по порядку: связь с предыдущей точкой,
+// digest по сохраненным головам и подпись ключом publicKey. Возвращает число
+// проверенных точек и ErrCheckpointInvalid на первой неверной
+func (s *ChainService) VerifyCheckpoints(ctx context.Context, publicKey ed25519.PublicKey) (int, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return 0, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(publicKey))
+	}
+
+	prev := model.GenesisHash
+	var afterID int64
+	checked := 0
+	for {
+		checkpoints, err := s.repo.ListCheckpoints(ctx, afterID, checkpointPageSize)
+		if err != nil {
+			return checked, err
+		}
+		if len(checkpoints) == 0 {
+			return checked, nil
+		}
+
+		for _, cp := range checkpoints {
+			if err := verifyCheckpoint(cp, prev, publicKey); err != nil {
+				return checked, err
+			}
+			checked++
+			prev = cp.Digest
+			afterID = cp.ID
+		}
+	}
+}
+
+func verifyCheckpoint(cp model.ChainCheckpoint, prev model.Hash, publicKey ed25519.PublicKey) error {
+	switch {
+	case !cp.PrevDigest.Equal(prev):
+		return fmt.Errorf("%w: checkpoint %d does not follow the previous checkpoint", ErrCheckpointInvalid, cp.ID)
+	case !model.CheckpointDigest(cp.PrevDigest, cp.CreatedAt, cp.Heads).Equal(cp.Digest):
+		return fmt.Errorf("%w: checkpoint %d digest does not match its heads", ErrCheckpointInvalid, cp.ID)
+	case !ed25519.Verify(publicKey, cp.Digest, cp.Signature):
+		return fmt.Errorf("%w: checkpoint %d signature is invalid", ErrCheckpointInvalid, cp.ID)
+	}
+	return nil
+}
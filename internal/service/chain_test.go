@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChainRepository хранит одну цепочку и точки в памяти
+type fakeChainRepository struct {
+	walletID    uuid.UUID
+	links       []model.ChainLink
+	unchained   *model.Operation
+	checkpoints []model.ChainCheckpoint
+}
+
+// newFakeChain строит цепочку из n пополнений кошелька так же, как appendChain
+func newFakeChain(n int) *fakeChainRepository {
+	repo := &fakeChainRepository{walletID: uuid.New()}
+	prev := model.GenesisHash
+	balance := decimal.Zero
+	for i := 1; i <= n; i++ {
+		balance = balance.Add(decimal.NewFromInt(100))
+		op := model.Operation{
+			ID:            uuid.New(),
+			WalletID:      repo.walletID,
+			OperationType: model.OperationTypeDeposit,
+			Amount:        decimal.NewFromInt(100),
+			BalanceAfter:  balance,
+			WalletVersion: i,
+			CreatedAt:     time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC),
+		}
+		link := model.ChainLink{Seq: int64(i), Format: model.ChainFormat, PrevHash: prev, Operation: op, TenantID: "acme"}
+		link.Hash = link.ExpectedHash(prev)
+		repo.links = append(repo.links, link)
+		prev = link.Hash
+	}
+	return repo
+}
+
+// rehash пересчитывает хеши звеньев начиная с i, как сделал бы тот, кто переписал цепочку в БД
+func (f *fakeChainRepository) rehash(i int) {
+	for ; i < len(f.links); i++ {
+		if i > 0 {
+			f.links[i].PrevHash = f.links[i-1].Hash
+		}
+		f.links[i].Hash = f.links[i].ExpectedHash(f.links[i].PrevHash)
+	}
+}
+
+func (f *fakeChainRepository) WalkChain(ctx context.Context, walletID uuid.UUID, fn func(model.ChainLink) error) error {
+	for _, link := range f.links {
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeChainRepository) FirstUnchained(ctx context.Context, walletID uuid.UUID) (*model.Operation, error) {
+	return f.unchained, nil
+}
+
+func (f *fakeChainRepository) ChainWallets(ctx context.Context) ([]uuid.UUID, error) {
+	return []uuid.UUID{f.walletID}, nil
+}
+
+func (f *fakeChainRepository) CheckpointHead(ctx context.Context, walletID uuid.UUID) (*model.ChainHead, error) {
+	for i := len(f.checkpoints) - 1; i >= 0; i-- {
+		for _, head := range f.checkpoints[i].Heads {
+			if head.WalletID == walletID {
+				return &head, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeChainRepository) LatestCheckpoint(ctx context.Context) (model.ChainCheckpoint, error) {
+	if len(f.checkpoints) == 0 {
+		return model.ChainCheckpoint{}, errors.New("no checkpoints")
+	}
+	return f.checkpoints[len(f.checkpoints)-1], nil
+}
+
+func (f *fakeChainRepository) ListCheckpoints(ctx context.Context, afterID int64, limit int) ([]model.ChainCheckpoint, error) {
+	var page []model.ChainCheckpoint
+	for _, cp := range f.checkpoints {
+		if cp.ID > afterID && len(page) < limit {
+			page = append(page, cp)
+		}
+	}
+	return page, nil
+}
+
+// CreateCheckpoint фиксирует текущую голову единственной цепочки
+func (f *fakeChainRepository) CreateCheckpoint(ctx context.Context, createdAt time.Time, sign func(digest model.Hash) ([]byte, error)) (*model.ChainCheckpoint, error) {
+	last := f.links[len(f.links)-1]
+	cp := model.ChainCheckpoint{
+		ID:         int64(len(f.checkpoints) + 1),
+		CreatedAt:  createdAt.UTC().Truncate(time.Microsecond),
+		PrevDigest: model.GenesisHash,
+		Heads:      []model.ChainHead{{WalletID: f.walletID, Seq: last.Seq, Hash: last.Hash}},
+	}
+	if len(f.checkpoints) > 0 {
+		cp.PrevDigest = f.checkpoints[len(f.checkpoints)-1].Digest
+	}
+	cp.Digest = model.CheckpointDigest(cp.PrevDigest, cp.CreatedAt, cp.Heads)
+	signature, err := sign(cp.Digest)
+	if err != nil {
+		return nil, err
+	}
+	cp.Signature = signature
+	f.checkpoints = append(f.checkpoints, cp)
+	return &cp, nil
+}
+
+func TestChainService_VerifyValid(t *testing.T) {
+	repo := newFakeChain(3)
+
+	result, err := NewChainService(repo, nil).Verify(context.Background(), repo.walletID)
+
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(3), result.Checked)
+	assert.Equal(t, int64(3), result.Head.Seq)
+	assert.Nil(t, result.BrokenAt)
+}
+
+func TestChainService_VerifyReportsFirstBrokenLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(repo *fakeChainRepository)
+		seq    int64
+		reason string
+	}{
+		{
+			name:   "edited amount",
+			tamper: func(repo *fakeChainRepository) { repo.links[1].Operation.Amount = decimal.NewFromInt(1) },
+			seq:    2,
+			reason: "hash does not match the operation",
+		},
+		{
+			name: "rewritten link",
+			tamper: func(repo *fakeChainRepository) {
+				repo.links[1].Operation.Amount = decimal.NewFromInt(1)
+				repo.links[1].Hash = repo.links[1].ExpectedHash(repo.links[1].PrevHash)
+			},
+			seq:    3,
+			reason: "previous hash does not match the previous link",
+		},
+		{
+			name:   "deleted link",
+			tamper: func(repo *fakeChainRepository) { repo.links = append(repo.links[:1], repo.links[2:]...) },
+			seq:    2,
+			reason: "link is missing",
+		},
+		{
+			name:   "operation without link",
+			tamper: func(repo *fakeChainRepository) { repo.unchained = &model.Operation{ID: uuid.New()} },
+			reason: "operation is not in the chain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeChain(4)
+			tt.tamper(repo)
+
+			result, err := NewChainService(repo, nil).Verify(context.Background(), repo.walletID)
+
+			require.NoError(t, err)
+			assert.False(t, result.Valid)
+			require.NotNil(t, result.BrokenAt)
+			assert.Equal(t, tt.seq, result.BrokenAt.Seq)
+			assert.Equal(t, tt.reason, result.BrokenAt.Reason)
+		})
+	}
+}
+
+func TestChainService_VerifyDetectsEveryHashedColumn(t *testing.T) {
+	feeID, feeWallet := uuid.New(), uuid.New()
+	tests := []struct {
+		name   string
+		tamper func(link *model.ChainLink)
+	}{
+		{"tenant", func(link *model.ChainLink) { link.TenantID = "globex" }},
+		{"wallet", func(link *model.ChainLink) { link.Operation.WalletID = uuid.New() }},
+		{"operation type", func(link *model.ChainLink) { link.Operation.OperationType = model.OperationTypeWithdraw }},
+		{"amount", func(link *model.ChainLink) { link.Operation.Amount = decimal.NewFromInt(1) }},
+		{"balance after", func(link *model.ChainLink) { link.Operation.BalanceAfter = decimal.NewFromInt(1) }},
+		{"wallet version", func(link *model.ChainLink) { link.Operation.WalletVersion = 9 }},
+		{"reversal of", func(link *model.ChainLink) { id := uuid.New(); link.Operation.ReversalOf = &id }},
+		{"adjustment", func(link *model.ChainLink) { id := uuid.New(); link.Operation.AdjustmentID = &id }},
+		{"created at", func(link *model.ChainLink) { link.Operation.CreatedAt = link.Operation.CreatedAt.Add(time.Microsecond) }},
+		{"fee fixed", func(link *model.ChainLink) { link.Operation.Fee.Fixed = decimal.NewFromInt(2) }},
+		{"fee variable", func(link *model.ChainLink) { link.Operation.Fee.Variable = decimal.NewFromInt(4) }},
+		{"fee total", func(link *model.ChainLink) { link.Operation.Fee.Total = decimal.NewFromInt(6) }},
+		{"fee wallet", func(link *model.ChainLink) { link.Operation.Fee.WalletID = uuid.New() }},
+		{"fee operation", func(link *model.ChainLink) { link.Operation.Fee.OperationID = uuid.New() }},
+		{"fee removed", func(link *model.ChainLink) { link.Operation.Fee = nil }},
+		{"downgraded to v1", func(link *model.ChainLink) { link.Format = model.ChainFormatV1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeChain(3)
+			// Комиссия 1 + 3 = 4; перенос между частями итог не меняет
+			repo.links[1].Operation.Fee = &model.FeeBreakdown{
+				Fixed: decimal.NewFromInt(1), Variable: decimal.NewFromInt(3), Total: decimal.NewFromInt(4),
+				WalletID: feeWallet, OperationID: feeID,
+			}
+			repo.rehash(1)
+			tt.tamper(&repo.links[1])
+
+			result, err := NewChainService(repo, nil).Verify(context.Background(), repo.walletID)
+
+			require.NoError(t, err)
+			assert.False(t, result.Valid)
+			require.NotNil(t, result.BrokenAt)
+			assert.Equal(t, int64(2), result.BrokenAt.Seq)
+		})
+	}
+}
+
+func TestChainService_VerifyLegacyLinks(t *testing.T) {
+	repo := newFakeChain(4)
+	// Звенья до v2 записаны в v1, после - в v2
+	repo.links[0].Format = model.ChainFormatV1
+	repo.links[1].Format = model.ChainFormatV1
+	repo.rehash(0)
+
+	result, err := NewChainService(repo, nil).Verify(context.Background(), repo.walletID)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(4), result.Checked)
+
+	// v1 не покрывает тенанта: перенос операции прежнего звена не виден
+	repo.links[0].TenantID = "globex"
+	result, err = NewChainService(repo, nil).Verify(context.Background(), repo.walletID)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+
+	repo.links[2].TenantID = "globex"
+	result, err = NewChainService(repo, nil).Verify(context.Background(), repo.walletID)
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(3), result.BrokenAt.Seq)
+}
+
+func TestChainService_VerifyAgainstCheckpoint(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	repo := newFakeChain(4)
+	chainService := NewChainService(repo, key)
+	_, err = chainService.Checkpoint(context.Background())
+	require.NoError(t, err)
+
+	// Последнее звено удалено вместе с операцией - внутри цепочка цела, но короче точки
+	repo.links = repo.links[:3]
+	result, err := chainService.Verify(context.Background(), repo.walletID)
+
+	require.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(4), result.BrokenAt.Seq)
+	assert.Equal(t, int64(4), result.Checkpoint.Seq)
+}
+
+func TestChainService_VerifyCheckpoints(t *testing.T) {
+	public, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	repo := newFakeChain(2)
+	chainService := NewChainService(repo, key)
+	for i := 0; i < 3; i++ {
+		_, err := chainService.Checkpoint(context.Background())
+		require.NoError(t, err)
+	}
+
+	checked, err := chainService.VerifyCheckpoints(context.Background(), public)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, checked)
+
+	otherPublic, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	_, err = chainService.VerifyCheckpoints(context.Background(), otherPublic)
+	assert.ErrorIs(t, err, ErrCheckpointInvalid)
+
+	// Подмена головы в сохраненной точке ломает ее digest
+	repo.checkpoints[1].Heads[0].Seq = 1
+	checked, err = chainService.VerifyCheckpoints(context.Background(), public)
+	assert.ErrorIs(t, err, ErrCheckpointInvalid)
+	assert.Equal(t, 1, checked)
+}
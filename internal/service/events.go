@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"wallet-service/internal/events"
+	"wallet-service/internal/model"
+	"wallet-service/internal/repository"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+)
+
+// MaxReplayEvents - сколько пропущенных событий отдается при возобновлении потока.
+// Если пропущено больше, вместо них клиент получает снимок текущего состояния
+const MaxReplayEvents = 1000
+
+const (
+	// DefaultWaitTimeout и MaxWaitTimeout - сколько ждет запрос баланса с waitForVersionAfter
+	DefaultWaitTimeout = 30 * time.Second
+	MaxWaitTimeout     = 60 * time.Second
+)
+
+// Stream - поток событий кошелька: сначала Backlog, затем события подписки.
+// События подписки могут повторять Backlog - потребитель пропускает версии,
+// которые уже отдал
+type Stream struct {
+	*events.Subscription
+	Backlog []model.BalanceEvent
+}
+
+// EventService подписывает клиентов на изменения балансов их кошельков
+type EventService struct {
+	wallets repository.WalletRepository
+	repo    repository.EventRepository
+	hub     *events.Hub
+}
+
+func NewEventService(wallets repository.WalletRepository, repo repository.EventRepository, hub *events.Hub) *EventService {
+	return &EventService{
+		wallets: wallets,
+		repo:    repo,
+		hub:     hub,
+	}
+}
+
+// Subscribe открывает поток событий кошелька тенанта запроса. lastEventID - версия
+// последнего полученного клиентом события; nil - поток начинается со снимка.
+// Кошелек другого тенанта не найден, как и несуществующий
+func (s *EventService) Subscribe(ctx context.Context, walletID uuid.UUID, lastEventID *int) (*Stream, error) {
+	// Подписка раньше чтения: изменение между чтением и подпиской не потеряется
+	sub := s.hub.Subscribe(tenant.FromContext(ctx), walletID)
+
+	wallet, err := s.wallets.GetWallet(ctx, walletID)
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+
+	stream := &Stream{Subscription: sub}
+	if lastEventID == nil || *lastEventID > wallet.Version {
+		stream.Backlog = []model.BalanceEvent{model.SnapshotEvent(wallet, time.Now().UTC())}
+		return stream, nil
+	}
+	if *lastEventID == wallet.Version {
+		return stream, nil
+	}
+
+	backlog, err := s.repo.EventsSince(ctx, walletID, *lastEventID, MaxReplayEvents+1)
+	if err != nil {
+		sub.Close()
+		return nil, err
+	}
+	if len(backlog) > MaxReplayEvents {
+		backlog = []model.BalanceEvent{model.SnapshotEvent(wallet, time.Now().UTC())}
+	}
+	stream.Backlog = backlog
+	return stream, nil
+}
+
+// WaitForVersion ждет, пока версия кошелька превысит after, но не дольше timeout, и
+// возвращает кошелек. Ожидание будят события Hub, поэтому ждущие запросы не читают
+// базу; по таймауту возвращается текущее состояние кошелька
+func (s *EventService) WaitForVersion(ctx context.Context, walletID uuid.UUID, after int, timeout time.Duration) (model.Wallet, error) {
+	sub := s.hub.Subscribe(tenant.FromContext(ctx), walletID)
+	defer sub.Close()
+
+	wallet, err := s.wallets.GetWallet(ctx, walletID)
+	if err != nil || wallet.Version > after {
+		return wallet, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return model.Wallet{}, ctx.Err()
+		case <-timer.C:
+			return s.wallets.GetWallet(ctx, walletID)
+		case event, ok := <-sub.Events():
+			// Закрытая подписка могла пропустить событие - проверяем по базе
+			if !ok || event.Version > after {
+				return s.wallets.GetWallet(ctx, walletID)
+			}
+		}
+	}
+}
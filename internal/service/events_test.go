@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/events"
+	"wallet-service/internal/model"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockEventRepository) EventsSince(ctx context.Context, walletID uuid.UUID, afterVersion, limit int) ([]model.BalanceEvent, error) {
+	args := m.Called(ctx, walletID, afterVersion, limit)
+	return args.Get(0).([]model.BalanceEvent), args.Error(1)
+}
+
+func TestEventService_Subscribe(t *testing.T) {
+	walletID := uuid.New()
+	wallet := model.Wallet{ID: walletID, Balance: decimal.NewFromInt(300), Version: 5}
+	wallets := new(MockWalletRepository)
+	wallets.On("GetWallet", mock.Anything, walletID).Return(wallet, nil)
+	wallets.On("GetWallet", mock.Anything, mock.Anything).Return(model.Wallet{}, apperror.ErrWalletNotFound)
+
+	replay := []model.BalanceEvent{{Type: model.EventOperation, WalletID: walletID, Version: 4}, {Type: model.EventOperation, WalletID: walletID, Version: 5}}
+	repo := new(MockEventRepository)
+	repo.On("EventsSince", mock.Anything, walletID, 3, MaxReplayEvents+1).Return(replay, nil)
+	repo.On("EventsSince", mock.Anything, walletID, 0, MaxReplayEvents+1).Return(make([]model.BalanceEvent, MaxReplayEvents+1), nil)
+
+	hub := events.NewHub()
+	service := NewEventService(wallets, repo, hub)
+	version := func(v int) *int { return &v }
+
+	// Без lastEventId поток начинается со снимка
+	stream, err := service.Subscribe(context.Background(), walletID, nil)
+	require.NoError(t, err)
+	require.Len(t, stream.Backlog, 1)
+	assert.Equal(t, model.EventSnapshot, stream.Backlog[0].Type)
+	assert.True(t, stream.Backlog[0].Balance.Equal(wallet.Balance))
+	stream.Close()
+
+	stream, err = service.Subscribe(context.Background(), walletID, version(3))
+	require.NoError(t, err)
+	assert.Equal(t, replay, stream.Backlog)
+	stream.Close()
+
+	// Клиент уже видел последнюю версию - дочитывать нечего
+	stream, err = service.Subscribe(context.Background(), walletID, version(5))
+	require.NoError(t, err)
+	assert.Empty(t, stream.Backlog)
+	stream.Close()
+
+	// Пропущено слишком много - снимок вместо событий
+	stream, err = service.Subscribe(context.Background(), walletID, version(0))
+	require.NoError(t, err)
+	require.Len(t, stream.Backlog, 1)
+	assert.Equal(t, model.EventSnapshot, stream.Backlog[0].Type)
+	stream.Close()
+
+	_, err = service.Subscribe(context.Background(), uuid.New(), nil)
+	assert.ErrorIs(t, err, apperror.ErrWalletNotFound)
+}
+
+func TestEventService_WaitForVersion(t *testing.T) {
+	walletID := uuid.New()
+	wallets := new(MockWalletRepository)
+	wallets.On("GetWallet", mock.Anything, walletID).Return(model.Wallet{ID: walletID, Version: 5}, nil).Twice()
+	wallets.On("GetWallet", mock.Anything, walletID).Return(model.Wallet{ID: walletID, Version: 6}, nil)
+	hub := events.NewHub()
+	service := NewEventService(wallets, new(MockEventRepository), hub)
+
+	// Версия уже больше ожидаемой - ответ сразу
+	wallet, err := service.WaitForVersion(context.Background(), walletID, 4, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 5, wallet.Version)
+
+	done := make(chan model.Wallet)
+	go func() {
+		wallet, _ := service.WaitForVersion(context.Background(), walletID, 5, time.Minute)
+		done <- wallet
+	}()
+
+	// Событие своей же версии не будит ожидание, следующей - будит
+	deadline := time.After(time.Second)
+	for version := 5; ; version = 6 {
+		hub.Publish(tenant.DefaultID, model.BalanceEvent{WalletID: walletID, Version: version})
+		select {
+		case wallet := <-done:
+			assert.Equal(t, 6, wallet.Version)
+			return
+		case <-deadline:
+			t.Fatal("wait was not woken by the event")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestEventService_WaitForVersion_Timeout(t *testing.T) {
+	walletID := uuid.New()
+	wallets := new(MockWalletRepository)
+	wallets.On("GetWallet", mock.Anything, walletID).Return(model.Wallet{ID: walletID, Version: 5}, nil)
+	service := NewEventService(wallets, new(MockEventRepository), events.NewHub())
+
+	wallet, err := service.WaitForVersion(context.Background(), walletID, 5, 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 5, wallet.Version)
+	wallets.AssertNumberOfCalls(t, "GetWallet", 2)
+}
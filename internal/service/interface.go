@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"crypto/ed25519"
+	"time"
 
 	"wallet-service/internal/model"
 	"github.com/google/uuid"
@@ -11,5 +13,70 @@ import (
 // WalletServiceInterface определяет контракт сервиса для использования в хендлерах
 type WalletServiceInterface interface {
 	GetBalance(ctx context.Context, id uuid.UUID) (decimal.Decimal, error)
-	ProcessOperation(ctx context.Context, op model.WalletOperation) error
-}
\ No newline at end of file
+	GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error)
+	ProcessOperation(ctx context.Context, op model.WalletOperation) (model.Operation, error)
+	GetOperation(ctx context.Context, id uuid.UUID) (model.Operation, error)
+	ReverseOperation(ctx context.Context, rev model.Reversal) (model.Operation, error)
+	ListWallets(ctx context.Context, filter model.WalletFilter) (model.WalletPage, error)
+	UpdateMetadata(ctx context.Context, id uuid.UUID, meta model.WalletMetadata) (model.Wallet, error)
+}
+
+// LimitsServiceInterface - контракт админского API лимитов
+type LimitsServiceInterface interface {
+	GetLimits(ctx context.Context, walletID uuid.UUID) (model.WalletLimits, error)
+	SetLimits(ctx context.Context, walletID uuid.UUID, limits model.Limits) (model.WalletLimits, error)
+	SetCreditLimit(ctx context.Context, walletID uuid.UUID, limit decimal.Decimal) (model.Wallet, error)
+	SetTier(ctx context.Context, walletID uuid.UUID, tier string) (model.Wallet, error)
+	SetStatus(ctx context.Context, walletID uuid.UUID, status model.WalletStatus) (model.Wallet, error)
+}
+
+// LedgerServiceInterface - контракт отчетов двойной записи
+type LedgerServiceInterface interface {
+	GetEntry(ctx context.Context, id uuid.UUID) (model.JournalEntry, error)
+	TrialBalance(ctx context.Context) (model.TrialBalance, error)
+	BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (decimal.Decimal, error)
+	BalancesAsOf(ctx context.Context, walletIDs []uuid.UUID, asOf time.Time) (map[uuid.UUID]decimal.Decimal, error)
+}
+
+// ChainServiceInterface - контракт админского API цепочки операций
+type ChainServiceInterface interface {
+	Verify(ctx context.Context, walletID uuid.UUID) (model.ChainVerification, error)
+	LatestCheckpoint(ctx context.Context) (model.ChainCheckpoint, error)
+	PublicKey() ed25519.PublicKey
+}
+
+// AdjustmentServiceInterface - контракт админского API корректировок
+type AdjustmentServiceInterface interface {
+	Propose(ctx context.Context, operator string, req model.AdjustmentRequest) (model.Adjustment, error)
+	Get(ctx context.Context, id uuid.UUID) (model.Adjustment, error)
+	List(ctx context.Context, filter model.AdjustmentFilter) ([]model.Adjustment, error)
+	Approve(ctx context.Context, review model.AdjustmentReview) (model.Adjustment, error)
+	Reject(ctx context.Context, review model.AdjustmentReview) (model.Adjustment, error)
+}
+
+// AuditRecorder - куда сервисы пишут аудит изменений вне HTTP-запросов (планировщик)
+type AuditRecorder interface {
+	Record(ctx context.Context, rec model.AuditRecord) error
+}
+
+// AuditServiceInterface - контракт журнала аудита
+type AuditServiceInterface interface {
+	AuditRecorder
+	List(ctx context.Context, filter model.AuditFilter) (model.AuditPage, error)
+	Export(ctx context.Context, filter model.AuditFilter, fn func(model.AuditRecord) error) error
+}
+
+// ScheduleServiceInterface - контракт API отложенных операций
+type ScheduleServiceInterface interface {
+	Create(ctx context.Context, req model.ScheduleOperationRequest) (model.ScheduledOperation, error)
+	Get(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error)
+	List(ctx context.Context, walletID uuid.UUID) ([]model.ScheduledOperation, error)
+	Cancel(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error)
+	Runs(ctx context.Context, id uuid.UUID) ([]model.ScheduledRun, error)
+}
+
+// EventServiceInterface - контракт потоков событий балансов
+type EventServiceInterface interface {
+	Subscribe(ctx context.Context, walletID uuid.UUID, lastEventID *int) (*Stream, error)
+	WaitForVersion(ctx context.Context, walletID uuid.UUID, after int, timeout time.Duration) (model.Wallet, error)
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"wallet-service/internal/model"
+	"wallet-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// LedgerService - отчеты по двойной записи и балансы на момент времени
+type LedgerService struct {
+	repo repository.LedgerRepository
+}
+
+func NewLedgerService(repo repository.LedgerRepository) *LedgerService {
+	return &LedgerService{
+		repo: repo,
+	}
+}
+
+func (s *LedgerService) GetEntry(ctx context.Context, id uuid.UUID) (model.JournalEntry, error) {
+	return s.repo.GetEntry(ctx, id)
+}
+
+func (s *LedgerService) TrialBalance(ctx context.Context) (model.TrialBalance, error) {
+	return s.repo.TrialBalance(ctx)
+}
+
+// BalanceAsOf - баланс кошелька на момент asOf
+func (s *LedgerService) BalanceAsOf(ctx context.Context, walletID uuid.UUID, asOf time.Time) (decimal.Decimal, error) {
+	balances, err := s.repo.BalancesAsOf(ctx, []uuid.UUID{walletID}, asOf)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	balance, ok := balances[walletID]
+	if !ok {
+		return decimal.Zero, ErrWalletNotFound
+	}
+	return balance, nil
+}
+
+// BalancesAsOf - балансы кошельков на момент asOf; несуществующих кошельков в результате нет
+func (s *LedgerService) BalancesAsOf(ctx context.Context, walletIDs []uuid.UUID, asOf time.Time) (map[uuid.UUID]decimal.Decimal, error) {
+	return s.repo.BalancesAsOf(ctx, walletIDs, asOf)
+}
+
+// RunSnapshots снимает остатки счетов раз в interval и блокируется до отмены ctx.
+// Снимок берется на момент now - lag, чтобы незакоммиченные операции не выпали из истории
+func (s *LedgerService) RunSnapshots(ctx context.Context, interval, lag time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.repo.TakeSnapshots(ctx, time.Now().Add(-lag)); err != nil && ctx.Err() == nil {
+			log.Printf("balance snapshots: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+
+	"wallet-service/internal/model"
+	"wallet-service/internal/repository"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// LimitsService - просмотр и изменение лимитов кошельков (админский API).
+// Сами лимиты проверяются репозиторием в транзакции операции
+type LimitsService struct {
+	repo repository.LimitsRepository
+	// tenants - лимиты по умолчанию каждого тенанта
+	tenants *tenant.Registry
+}
+
+func NewLimitsService(repo repository.LimitsRepository, tenants *tenant.Registry) *LimitsService {
+	return &LimitsService{
+		repo:    repo,
+		tenants: tenants,
+	}
+}
+
+func (s *LimitsService) GetLimits(ctx context.Context, walletID uuid.UUID) (model.WalletLimits, error) {
+	overrides, err := s.repo.GetLimits(ctx, walletID)
+	if err != nil {
+		return model.WalletLimits{}, err
+	}
+
+	usage, err := s.repo.GetUsage(ctx, walletID)
+	if err != nil {
+		return model.WalletLimits{}, err
+	}
+
+	return model.WalletLimits{
+		WalletID:  walletID,
+		Effective: overrides.Merge(s.tenants.Current(ctx).Limits),
+		Overrides: overrides,
+		Usage:     usage,
+	}, nil
+}
+
+// SetLimits заменяет лимиты кошелька целиком; nil-поля возвращают значение тенанта
+func (s *LimitsService) SetLimits(ctx context.Context, walletID uuid.UUID, limits model.Limits) (model.WalletLimits, error) {
+	if err := s.repo.SetLimits(ctx, walletID, limits); err != nil {
+		return model.WalletLimits{}, err
+	}
+	return s.GetLimits(ctx, walletID)
+}
+
+func (s *LimitsService) SetCreditLimit(ctx context.Context, walletID uuid.UUID, limit decimal.Decimal) (model.Wallet, error) {
+	return s.repo.SetCreditLimit(ctx, walletID, limit)
+}
+
+func (s *LimitsService) SetTier(ctx context.Context, walletID uuid.UUID, tier string) (model.Wallet, error) {
+	return s.repo.SetTier(ctx, walletID, tier)
+}
+
+func (s *LimitsService) SetStatus(ctx context.Context, walletID uuid.UUID, status model.WalletStatus) (model.Wallet, error) {
+	return s.repo.SetStatus(ctx, walletID, status)
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"wallet-service/internal/model"
+	"wallet-service/internal/repository"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLimitsRepository struct {
+	mock.Mock
+}
+
+func (m *MockLimitsRepository) GetLimits(ctx context.Context, walletID uuid.UUID) (model.Limits, error) {
+	args := m.Called(ctx, walletID)
+	return args.Get(0).(model.Limits), args.Error(1)
+}
+
+func (m *MockLimitsRepository) SetLimits(ctx context.Context, walletID uuid.UUID, limits model.Limits) error {
+	args := m.Called(ctx, walletID, limits)
+	return args.Error(0)
+}
+
+func (m *MockLimitsRepository) GetUsage(ctx context.Context, walletID uuid.UUID) (model.LimitUsage, error) {
+	args := m.Called(ctx, walletID)
+	return args.Get(0).(model.LimitUsage), args.Error(1)
+}
+
+func (m *MockLimitsRepository) SetCreditLimit(ctx context.Context, walletID uuid.UUID, limit decimal.Decimal) (model.Wallet, error) {
+	args := m.Called(ctx, walletID, limit)
+	return args.Get(0).(model.Wallet), args.Error(1)
+}
+
+func (m *MockLimitsRepository) SetTier(ctx context.Context, walletID uuid.UUID, tier string) (model.Wallet, error) {
+	args := m.Called(ctx, walletID, tier)
+	return args.Get(0).(model.Wallet), args.Error(1)
+}
+
+func (m *MockLimitsRepository) SetStatus(ctx context.Context, walletID uuid.UUID, status model.WalletStatus) (model.Wallet, error) {
+	args := m.Called(ctx, walletID, status)
+	return args.Get(0).(model.Wallet), args.Error(1)
+}
+
+func TestLimitsService_GetLimits_MergesDefaults(t *testing.T) {
+	mockRepo := new(MockLimitsRepository)
+	globalDaily := decimal.NewFromInt(1000)
+	globalCount := 10
+	service := NewLimitsService(mockRepo, tenant.NewRegistry(model.Tenant{ID: tenant.DefaultID, Limits: model.Limits{DailyWithdrawalAmount: &globalDaily, DailyWithdrawalCount: &globalCount}}))
+
+	walletID := uuid.New()
+	walletDaily := decimal.NewFromInt(50000)
+	overrides := model.Limits{DailyWithdrawalAmount: &walletDaily}
+	usage := model.LimitUsage{DailyWithdrawalAmount: decimal.NewFromInt(700), DailyWithdrawalCount: 2}
+
+	mockRepo.On("GetLimits", mock.Anything, walletID).Return(overrides, nil)
+	mockRepo.On("GetUsage", mock.Anything, walletID).Return(usage, nil)
+
+	limits, err := service.GetLimits(context.Background(), walletID)
+
+	assert.NoError(t, err)
+	// Лимит кошелька переопределяет глобальный, незаданный - наследуется
+	assert.True(t, walletDaily.Equal(*limits.Effective.DailyWithdrawalAmount))
+	assert.Equal(t, 10, *limits.Effective.DailyWithdrawalCount)
+	assert.Nil(t, limits.Overrides.DailyWithdrawalCount)
+	assert.Equal(t, usage, limits.Usage)
+}
+
+func TestLimitsService_SetLimits_WalletNotFound(t *testing.T) {
+	mockRepo := new(MockLimitsRepository)
+	service := NewLimitsService(mockRepo, tenant.NewRegistry())
+
+	walletID := uuid.New()
+	mockRepo.On("SetLimits", mock.Anything, walletID, model.Limits{}).Return(repository.ErrWalletNotFound)
+
+	_, err := service.SetLimits(context.Background(), walletID, model.Limits{})
+
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+	mockRepo.AssertNotCalled(t, "GetLimits", mock.Anything, walletID)
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"wallet-service/internal/metrics"
+	"wallet-service/internal/repository"
+)
+
+// RetryPolicy описывает, сколько раз и с какими паузами повторять операцию,
+// упавшую на конфликте конкурентного доступа
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Retryable решает, можно ли повторить операцию после ошибки. По умолчанию - IsRetryable
+	Retryable func(error) bool
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   10 * time.Millisecond,
+		MaxDelay:    200 * time.Millisecond,
+		Retryable:   IsRetryable,
+	}
+}
+
+// IsRetryable возвращает true для конфликтов, которые могут разрешиться при повторе:
+// оптимистичная блокировка, serialization failure и deadlock в Postgres
+func IsRetryable(err error) bool {
+	return errors.Is(err, repository.ErrOptimisticLock) ||
+		errors.Is(err, repository.ErrSerializationFailure) ||
+		errors.Is(err, repository.ErrDeadlock)
+}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable == nil {
+		return IsRetryable(err)
+	}
+	return p.Retryable(err)
+}
+
+// Backoff возвращает паузу перед повтором после attempt-й неудачной попытки (с 1).
+// Используется full jitter: случайное значение в [0, min(MaxDelay, BaseDelay*2^(attempt-1))]
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+
+	ceiling := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		ceiling *= 2
+		if p.MaxDelay > 0 && ceiling >= p.MaxDelay {
+			ceiling = p.MaxDelay
+			break
+		}
+	}
+	if p.MaxDelay > 0 && ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// wait ждет delay, но не дольше, чем позволяет контекст. Если пауза не укладывается
+// в дедлайн контекста, ждать бессмысленно - возвращаем ошибку сразу
+func wait(ctx context.Context, delay time.Duration) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return context.DeadlineExceeded
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// withRetry повторяет изменение баланса при конфликтах транзакций
+func withRetry[T any](ctx context.Context, policy RetryPolicy, fn func() (T, error)) (T, error) {
+	var result T
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		result, err = fn()
+		if err == nil || !policy.retryable(err) || attempt >= policy.attempts() {
+			break
+		}
+		// Контекст отменен или дедлайн не позволяет ждать - отдаем конфликт клиенту
+		if wait(ctx, policy.Backoff(attempt)) != nil {
+			break
+		}
+		metrics.OperationRetries.Add(1)
+	}
+	metrics.OperationAttempts.Add(strconv.Itoa(attempt), 1)
+
+	if err != nil && policy.retryable(err) {
+		metrics.OperationRetriesExhausted.Add(1)
+		var zero T
+		return zero, ErrOptimisticLock
+	}
+
+	// Конфликт версии, заданной клиентом (ErrVersionMismatch), сюда тоже попадает
+	// без повторов - повтор ничего не изменит
+	return result, err
+}
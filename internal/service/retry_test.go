@@ -0,0 +1,37 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   10 * time.Millisecond,
+		MaxDelay:    40 * time.Millisecond,
+	}
+
+	ceilings := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		40 * time.Millisecond,
+	}
+
+	for i, ceiling := range ceilings {
+		for j := 0; j < 100; j++ {
+			delay := policy.Backoff(i + 1)
+			assert.GreaterOrEqual(t, delay, time.Duration(0))
+			assert.LessOrEqual(t, delay, ceiling)
+		}
+	}
+}
+
+func TestRetryPolicy_BackoffWithoutBaseDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+
+	assert.Equal(t, time.Duration(0), policy.Backoff(2))
+}
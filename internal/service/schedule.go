@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/model"
+	"wallet-service/internal/repository"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+// ScheduleService - отложенные и повторяющиеся операции. Запуски выполняет Run
+// в фоне; операции проходят через WalletService.ProcessOperation со всеми его проверками
+type ScheduleService struct {
+	repo    repository.ScheduleRepository
+	wallets WalletServiceInterface
+	// audit - журнал аудита запусков; nil - запуски не аудируются
+	audit AuditRecorder
+	now   func() time.Time
+}
+
+func NewScheduleService(repo repository.ScheduleRepository, wallets WalletServiceInterface, audit AuditRecorder) *ScheduleService {
+	return &ScheduleService{
+		repo:    repo,
+		wallets: wallets,
+		audit:   audit,
+		now:     time.Now,
+	}
+}
+
+// ParseSchedule разбирает cron-выражение из 5 полей (минуты, часы, день месяца, месяц,
+// день недели) или дескриптор вроде @daily. Время - UTC
+func ParseSchedule(expr string) (cron.Schedule, error) {
+	return cron.ParseStandard(expr)
+}
+
+func (s *ScheduleService) Create(ctx context.Context, req model.ScheduleOperationRequest) (model.ScheduledOperation, error) {
+	audit.SetWallet(ctx, req.WalletID)
+	now := s.now().UTC()
+	job := model.ScheduledOperation{
+		ID:            uuid.New(),
+		WalletID:      req.WalletID,
+		OperationType: req.OperationType,
+		Amount:        req.Amount,
+		Schedule:      req.Schedule,
+		Status:        model.ScheduleStatusActive,
+	}
+
+	if req.Schedule == "" {
+		if req.RunAt == nil {
+			return model.ScheduledOperation{}, apperror.Validation(apperror.FieldError{Field: "runAt", Message: "runAt or schedule is required"})
+		}
+		runAt := req.RunAt.UTC()
+		job.NextRunAt = &runAt
+		return s.repo.CreateScheduled(ctx, job)
+	}
+
+	schedule, err := ParseSchedule(req.Schedule)
+	if err != nil {
+		return model.ScheduledOperation{}, apperror.Validation(apperror.FieldError{Field: "schedule", Message: err.Error()})
+	}
+
+	// Первый запуск - ближайшее время по расписанию, но не раньше runAt
+	from := now
+	if req.RunAt != nil && req.RunAt.After(now) {
+		from = req.RunAt.UTC().Add(-time.Second)
+	}
+	next := schedule.Next(from)
+	if next.IsZero() {
+		return model.ScheduledOperation{}, apperror.Validation(apperror.FieldError{Field: "schedule", Message: "never fires"})
+	}
+	job.NextRunAt = &next
+
+	return s.repo.CreateScheduled(ctx, job)
+}
+
+func (s *ScheduleService) Get(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error) {
+	return s.repo.GetScheduled(ctx, id)
+}
+
+func (s *ScheduleService) List(ctx context.Context, walletID uuid.UUID) ([]model.ScheduledOperation, error) {
+	return s.repo.ListScheduled(ctx, walletID)
+}
+
+func (s *ScheduleService) Cancel(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error) {
+	job, err := s.repo.CancelScheduled(ctx, id)
+	if err == nil {
+		audit.SetWallet(ctx, job.WalletID)
+	}
+	return job, err
+}
+
+func (s *ScheduleService) Runs(ctx context.Context, id uuid.UUID) ([]model.ScheduledRun, error) {
+	return s.repo.ListRuns(ctx, id)
+}
+
+// Run запускает планировщик и блокируется до отмены ctx
+func (s *ScheduleService) Run(ctx context.Context, interval time.Duration, batchSize int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Забираем пачки, пока наступившие задания не кончатся
+		for {
+			n, err := s.RunDue(ctx, batchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("scheduler: %v", err)
+				}
+				break
+			}
+			if n < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue выполняет одну пачку наступивших заданий и возвращает их число
+func (s *ScheduleService) RunDue(ctx context.Context, batchSize int) (int, error) {
+	return s.repo.ClaimDue(ctx, s.now(), batchSize, func(job model.ScheduledOperation) (model.ScheduledRun, *time.Time) {
+		return s.execute(ctx, job)
+	})
+}
+
+func (s *ScheduleService) execute(ctx context.Context, job model.ScheduledOperation) (model.ScheduledRun, *time.Time) {
+	scheduledAt := *job.NextRunAt
+	run := model.ScheduledRun{
+		ScheduledOperationID: job.ID,
+		ScheduledAt:          scheduledAt,
+		Status:               model.RunStatusSucceeded,
+	}
+
+	// Ключ привязан к плановому времени: если реплика упадет после операции, но до записи
+	// результата, следующий запуск вернет уже выполненную операцию, а не спишет еще раз
+	key := fmt.Sprintf("scheduled:%s:%d", job.ID, scheduledAt.Unix())
+	started := s.now()
+	// Операция выполняется от имени тенанта, создавшего задание
+	ctx = tenant.NewContext(ctx, job.TenantID)
+	rec := &model.AuditRecord{
+		RequestID: key,
+		TenantID:  job.TenantID,
+		Actor:     "scheduler",
+		Method:    "RUN",
+		Endpoint:  "/api/v1/scheduled-operations/{scheduleId}",
+		Path:      "/api/v1/scheduled-operations/" + job.ID.String(),
+		Summary:   map[string]any{"operationType": job.OperationType, "amount": job.Amount.String()},
+	}
+	op, err := s.wallets.ProcessOperation(audit.NewContext(ctx, rec), model.WalletOperation{
+		WalletID:       job.WalletID,
+		OperationType:  job.OperationType,
+		Amount:         job.Amount,
+		IdempotencyKey: key,
+	})
+	run.ExecutedAt = s.now()
+	s.recordRun(ctx, rec, err, run.ExecutedAt.Sub(started))
+	if err != nil {
+		appErr := apperror.From(err)
+		run.Status = model.RunStatusFailed
+		run.ErrorCode = string(appErr.Code)
+		run.Error = err.Error()
+	} else {
+		run.OperationID = &op.ID
+	}
+
+	if job.Schedule == "" {
+		return run, nil
+	}
+
+	schedule, err := ParseSchedule(job.Schedule)
+	if err != nil {
+		// Выражение проверено при создании; сюда попадаем, только если его испортили в БД
+		log.Printf("scheduler: invalid schedule %q for %s: %v", job.Schedule, job.ID, err)
+		return run, nil
+	}
+
+	// Пропущенные запуски (сервис был остановлен) не догоняются: следующий - после текущего момента
+	from := scheduledAt
+	if now := s.now(); now.After(from) {
+		from = now
+	}
+	next := schedule.Next(from.UTC())
+	return run, &next
+}
+
+// recordRun пишет запуск в журнал аудита. Ошибка аудита не отменяет уже
+// выполненную операцию, поэтому только логируется
+func (s *ScheduleService) recordRun(ctx context.Context, rec *model.AuditRecord, err error, latency time.Duration) {
+	if s.audit == nil {
+		return
+	}
+
+	rec.Status = http.StatusOK
+	rec.Outcome = model.AuditSuccess
+	if err != nil {
+		appErr := apperror.From(err)
+		rec.Status = appErr.Status
+		rec.Outcome = model.AuditFailure
+		rec.ErrorCode = string(appErr.Code)
+	}
+	rec.LatencyMs = float64(latency.Microseconds()) / 1000
+
+	if err := s.audit.Record(ctx, *rec); err != nil {
+		log.Printf("scheduler: failed to write audit record for %s: %v", rec.RequestID, err)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"wallet-service/internal/repository"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeScheduleRepository хранит задания в памяти; ClaimDue отдает все наступившие
+type fakeScheduleRepository struct {
+	jobs []model.ScheduledOperation
+	runs []model.ScheduledRun
+	next map[uuid.UUID]*time.Time
+}
+
+func (f *fakeScheduleRepository) CreateScheduled(ctx context.Context, job model.ScheduledOperation) (model.ScheduledOperation, error) {
+	f.jobs = append(f.jobs, job)
+	return job, nil
+}
+
+func (f *fakeScheduleRepository) GetScheduled(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error) {
+	for _, job := range f.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return model.ScheduledOperation{}, repository.ErrScheduleNotFound
+}
+
+func (f *fakeScheduleRepository) ListScheduled(ctx context.Context, walletID uuid.UUID) ([]model.ScheduledOperation, error) {
+	return f.jobs, nil
+}
+
+func (f *fakeScheduleRepository) CancelScheduled(ctx context.Context, id uuid.UUID) (model.ScheduledOperation, error) {
+	return f.GetScheduled(ctx, id)
+}
+
+func (f *fakeScheduleRepository) ListRuns(ctx context.Context, id uuid.UUID) ([]model.ScheduledRun, error) {
+	return f.runs, nil
+}
+
+func (f *fakeScheduleRepository) ClaimDue(ctx context.Context, now time.Time, limit int, run repository.RunFunc) (int, error) {
+	if f.next == nil {
+		f.next = map[uuid.UUID]*time.Time{}
+	}
+	n := 0
+	for _, job := range f.jobs {
+		if job.NextRunAt == nil || job.NextRunAt.After(now) || n == limit {
+			continue
+		}
+		result, next := run(job)
+		f.runs = append(f.runs, result)
+		f.next[job.ID] = next
+		n++
+	}
+	return n, nil
+}
+
+func newTestScheduleService(repo repository.ScheduleRepository, wallets repository.WalletRepository, now time.Time) *ScheduleService {
+	service := NewScheduleService(repo, NewWalletService(wallets, DefaultRetryPolicy(), tenant.NewRegistry()), nil)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestScheduleService_Create(t *testing.T) {
+	now := time.Date(2024, 3, 10, 14, 30, 0, 0, time.UTC)
+	runAt := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		req      model.ScheduleOperationRequest
+		expected time.Time
+	}{
+		{
+			name:     "one-off",
+			req:      model.ScheduleOperationRequest{RunAt: &runAt},
+			expected: runAt,
+		},
+		{
+			name:     "recurring",
+			req:      model.ScheduleOperationRequest{Schedule: "0 9 * * *"},
+			expected: time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "recurring from runAt",
+			req:      model.ScheduleOperationRequest{Schedule: "@monthly", RunAt: &runAt},
+			expected: runAt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestScheduleService(&fakeScheduleRepository{}, new(MockWalletRepository), now)
+			tt.req.WalletID = uuid.New()
+			tt.req.OperationType = model.OperationTypeDeposit
+			tt.req.Amount = decimal.NewFromInt(100)
+
+			job, err := service.Create(context.Background(), tt.req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, model.ScheduleStatusActive, job.Status)
+			assert.Equal(t, tt.expected, *job.NextRunAt)
+		})
+	}
+}
+
+func TestScheduleService_Create_InvalidSchedule(t *testing.T) {
+	service := newTestScheduleService(&fakeScheduleRepository{}, new(MockWalletRepository), time.Now())
+
+	_, err := service.Create(context.Background(), model.ScheduleOperationRequest{
+		WalletID:      uuid.New(),
+		OperationType: model.OperationTypeDeposit,
+		Amount:        decimal.NewFromInt(100),
+		Schedule:      "every day",
+	})
+
+	assert.ErrorIs(t, err, apperror.ErrValidationFailed)
+}
+
+func TestScheduleService_RunDue(t *testing.T) {
+	now := time.Date(2024, 3, 10, 9, 0, 30, 0, time.UTC)
+	due := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+
+	oneOff := model.ScheduledOperation{ID: uuid.New(), WalletID: uuid.New(), OperationType: model.OperationTypeDeposit,
+		Amount: decimal.NewFromInt(100), NextRunAt: &due, Status: model.ScheduleStatusActive}
+	recurring := model.ScheduledOperation{ID: uuid.New(), WalletID: uuid.New(), OperationType: model.OperationTypeWithdraw,
+		Amount: decimal.NewFromInt(50), Schedule: "0 9 * * *", NextRunAt: &due, Status: model.ScheduleStatusActive}
+	notDue := model.ScheduledOperation{ID: uuid.New(), WalletID: uuid.New(), OperationType: model.OperationTypeDeposit,
+		Amount: decimal.NewFromInt(1), NextRunAt: &later, Status: model.ScheduleStatusActive}
+	repo := &fakeScheduleRepository{jobs: []model.ScheduledOperation{oneOff, recurring, notDue}}
+
+	operationID := uuid.New()
+	wallets := new(MockWalletRepository)
+	wallets.On("UpdateBalance", mock.Anything, mock.MatchedBy(func(op model.WalletOperation) bool {
+		return op.WalletID == oneOff.WalletID && op.IdempotencyKey == fmt.Sprintf("scheduled:%s:%d", oneOff.ID, due.Unix())
+	})).Return(model.Operation{ID: operationID}, nil)
+	wallets.On("UpdateBalance", mock.Anything, mock.MatchedBy(func(op model.WalletOperation) bool {
+		return op.WalletID == recurring.WalletID
+	})).Return(model.Operation{}, repository.ErrInsufficientFunds)
+
+	service := newTestScheduleService(repo, wallets, now)
+	n, err := service.RunDue(context.Background(), 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	assert.Equal(t, model.RunStatusSucceeded, repo.runs[0].Status)
+	assert.Equal(t, &operationID, repo.runs[0].OperationID)
+	assert.Nil(t, repo.next[oneOff.ID])
+
+	// Неудачный запуск не останавливает повторяющуюся операцию
+	assert.Equal(t, model.RunStatusFailed, repo.runs[1].Status)
+	assert.Equal(t, string(apperror.CodeInsufficientFunds), repo.runs[1].ErrorCode)
+	assert.Equal(t, time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC), *repo.next[recurring.ID])
+}
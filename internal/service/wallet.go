@@ -3,57 +3,167 @@ package service
 import (
 	"context"
 	"errors"
-	"time"
+	"fmt"
+	"strings"
 
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/cache"
 	"wallet-service/internal/model"
 	"wallet-service/internal/repository"
+	"wallet-service/internal/tenant"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
-// Экспортируем ошибки для использования в хендлерах
+// Экспортируем ошибки для использования в хендлерах. Это те же доменные ошибки,
+// что возвращает репозиторий, поэтому их достаточно пробрасывать как есть
 var (
-	ErrWalletNotFound    = errors.New("wallet not found")
-	ErrInsufficientFunds = errors.New("insufficient funds")
-	ErrOptimisticLock    = errors.New("optimistic lock conflict")
+	ErrWalletNotFound    = apperror.ErrWalletNotFound
+	ErrInsufficientFunds = apperror.ErrInsufficientFunds
+	ErrOptimisticLock    = apperror.ErrConcurrencyConflict
+	ErrVersionMismatch   = apperror.ErrPreconditionFailed
+)
+
+const (
+	// DefaultWalletPageSize и MaxWalletPageSize - размер страницы списка кошельков
+	DefaultWalletPageSize = 50
+	MaxWalletPageSize     = 500
 )
 
 type WalletService struct {
-	repo    repository.WalletRepository
-	retries int
+	repo  repository.WalletRepository
+	retry RetryPolicy
+	// tenants - валюты и тарифные сетки тенантов
+	tenants *tenant.Registry
 }
 
-func NewWalletService(repo repository.WalletRepository, retries int) *WalletService {
+func NewWalletService(repo repository.WalletRepository, retry RetryPolicy, tenants *tenant.Registry) *WalletService {
 	return &WalletService{
 		repo:    repo,
-		retries: retries,
+		retry:   retry,
+		tenants: tenants,
 	}
 }
 
 func (s *WalletService) GetBalance(ctx context.Context, id uuid.UUID) (decimal.Decimal, error) {
-	balance, err := s.repo.GetBalance(ctx, id)
-	if errors.Is(err, repository.ErrWalletNotFound) {
-		return decimal.Zero, ErrWalletNotFound
+	return s.repo.GetBalance(ctx, id)
+}
+
+func (s *WalletService) GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error) {
+	return s.repo.GetWallet(ctx, id)
+}
+
+// ListWallets возвращает страницу кошельков тенанта; следующую страницу дает filter.After = Next
+func (s *WalletService) ListWallets(ctx context.Context, filter model.WalletFilter) (model.WalletPage, error) {
+	if filter.Sort == "" {
+		filter.Sort = model.SortCreatedAt
+	}
+	if filter.After != nil && filter.After.Sort != filter.Sort {
+		return model.WalletPage{}, apperror.Validation(apperror.FieldError{Field: "cursor", Message: "was issued for another sort order"})
+	}
+	if filter.Limit <= 0 || filter.Limit > MaxWalletPageSize {
+		filter.Limit = DefaultWalletPageSize
 	}
-	return balance, err
+
+	wallets, err := s.repo.ListWallets(ctx, filter)
+	if err != nil {
+		return model.WalletPage{}, err
+	}
+
+	page := model.WalletPage{Wallets: wallets}
+	if len(wallets) == filter.Limit {
+		last := wallets[len(wallets)-1]
+		page.Next = &model.WalletCursor{Sort: filter.Sort, Value: filter.Sort.CursorValue(last), ID: last.ID}
+	}
+	return page, nil
+}
+
+func (s *WalletService) UpdateMetadata(ctx context.Context, id uuid.UUID, meta model.WalletMetadata) (model.Wallet, error) {
+	return s.repo.UpdateMetadata(ctx, id, meta)
+}
+
+func (s *WalletService) GetOperation(ctx context.Context, id uuid.UUID) (model.Operation, error) {
+	return s.repo.GetOperation(ctx, id)
 }
 
-func (s *WalletService) ProcessOperation(ctx context.Context, op model.WalletOperation) error {
-	for i := 0; i < s.retries; i++ {
-		err := s.repo.UpdateBalance(ctx, op)
-		if errors.Is(err, repository.ErrOptimisticLock) {
-			time.Sleep(time.Duration(i*i) * time.Millisecond * 10)
-			continue
+func (s *WalletService) ProcessOperation(ctx context.Context, op model.WalletOperation) (model.Operation, error) {
+	audit.SetWallet(ctx, op.WalletID)
+
+	current := s.tenants.Current(ctx)
+	if op.Currency != "" && !current.AllowsCurrency(op.Currency) {
+		return model.Operation{}, apperror.Validation(apperror.FieldError{
+			Field:   "currency",
+			Message: fmt.Sprintf("must be one of %s", strings.Join(current.Currencies, ", ")),
+		})
+	}
+
+	// Комиссия пересчитывается в каждой попытке: репозиторий отклоняет операцию,
+	// если тариф кошелька сменили после расчета
+	result, err := withRetry(ctx, s.retry, func() (model.Operation, error) {
+		op, err := s.withFee(ctx, current.Fees, op)
+		if err != nil {
+			return model.Operation{}, err
 		}
-		
-		// Маппим ошибки репозитория на ошибки сервиса
-		if errors.Is(err, repository.ErrWalletNotFound) {
-			return ErrWalletNotFound
+		return s.repo.UpdateBalance(ctx, op)
+	})
+	if err == nil {
+		audit.SetOperation(ctx, result.ID)
+	}
+	return result, err
+}
+
+// withFee добавляет к операции комиссию по тарифной сетке тенанта и тариф, по которому
+// она посчитана. Кошелек комиссий комиссий не платит
+func (s *WalletService) withFee(ctx context.Context, fees model.FeeSchedule, op model.WalletOperation) (model.WalletOperation, error) {
+	if fees.WalletID == uuid.Nil || op.WalletID == fees.WalletID {
+		return op, nil
+	}
+
+	// Тариф читаем мимо кеша, чтобы не повторять операцию из-за устаревшей записи.
+	// Отставшая реплика обойдется лишним повтором. Нового кошелька еще нет - он
+	// откроется с тарифом по умолчанию
+	tier := ""
+	if fees.HasTierRules(op.OperationType) {
+		tier = model.DefaultTier
+		wallet, err := s.repo.GetWallet(cache.WithoutCache(ctx), op.WalletID)
+		if err != nil && !errors.Is(err, ErrWalletNotFound) {
+			return model.WalletOperation{}, err
 		}
-		if errors.Is(err, repository.ErrInsufficientFunds) {
-			return ErrInsufficientFunds
+		if err == nil {
+			tier = wallet.Tier
 		}
-		return err
 	}
-	return ErrOptimisticLock
-}
\ No newline at end of file
+	op.FeeTier = tier
+
+	rule, ok := fees.Rule(op.OperationType, tier)
+	if !ok {
+		return op, nil
+	}
+	fee := rule.Compute(op.Amount)
+	if !fee.Total.IsPositive() {
+		return op, nil
+	}
+	if op.OperationType == model.OperationTypeDeposit && !fee.Total.LessThan(op.Amount) {
+		return model.WalletOperation{}, apperror.Validation(apperror.FieldError{
+			Field:   "amount",
+			Message: fmt.Sprintf("must exceed the fee of %s", fee.Total.StringFixed(2)),
+		})
+	}
+	fee.WalletID = fees.WalletID
+	op.Fee = &fee
+	return op, nil
+}
+
+// ReverseOperation сторнирует операцию. Повторы безопасны: остаток к сторнированию
+// перечитывается в каждой попытке
+func (s *WalletService) ReverseOperation(ctx context.Context, rev model.Reversal) (model.Operation, error) {
+	result, err := withRetry(ctx, s.retry, func() (model.Operation, error) {
+		return s.repo.ReverseOperation(ctx, rev)
+	})
+	if err == nil {
+		audit.SetWallet(ctx, result.WalletID)
+		audit.SetOperation(ctx, result.ID)
+	}
+	return result, err
+}
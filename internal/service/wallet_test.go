@@ -2,10 +2,14 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
+	"wallet-service/internal/apperror"
 	"wallet-service/internal/model"
 	"wallet-service/internal/repository"
+	"wallet-service/internal/tenant"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
@@ -22,14 +26,39 @@ func (m *MockWalletRepository) GetBalance(ctx context.Context, id uuid.UUID) (de
 	return args.Get(0).(decimal.Decimal), args.Error(1)
 }
 
-func (m *MockWalletRepository) UpdateBalance(ctx context.Context, op model.WalletOperation) error {
+func (m *MockWalletRepository) GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(model.Wallet), args.Error(1)
+}
+
+func (m *MockWalletRepository) UpdateBalance(ctx context.Context, op model.WalletOperation) (model.Operation, error) {
 	args := m.Called(ctx, op)
-	return args.Error(0)
+	return args.Get(0).(model.Operation), args.Error(1)
+}
+
+func (m *MockWalletRepository) GetOperation(ctx context.Context, id uuid.UUID) (model.Operation, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(model.Operation), args.Error(1)
+}
+
+func (m *MockWalletRepository) ReverseOperation(ctx context.Context, rev model.Reversal) (model.Operation, error) {
+	args := m.Called(ctx, rev)
+	return args.Get(0).(model.Operation), args.Error(1)
+}
+
+func (m *MockWalletRepository) ListWallets(ctx context.Context, filter model.WalletFilter) ([]model.Wallet, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]model.Wallet), args.Error(1)
+}
+
+func (m *MockWalletRepository) UpdateMetadata(ctx context.Context, id uuid.UUID, meta model.WalletMetadata) (model.Wallet, error) {
+	args := m.Called(ctx, id, meta)
+	return args.Get(0).(model.Wallet), args.Error(1)
 }
 
 func TestWalletService_GetBalance(t *testing.T) {
 	mockRepo := new(MockWalletRepository)
-	service := NewWalletService(mockRepo, 3)
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry())
 
 	walletID := uuid.New()
 	expectedBalance := decimal.NewFromInt(1000)
@@ -48,7 +77,7 @@ func TestWalletService_GetBalance(t *testing.T) {
 
 func TestWalletService_GetBalance_WalletNotFound(t *testing.T) {
 	mockRepo := new(MockWalletRepository)
-	service := NewWalletService(mockRepo, 3)
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry())
 
 	walletID := uuid.New()
 
@@ -67,7 +96,7 @@ func TestWalletService_GetBalance_WalletNotFound(t *testing.T) {
 
 func TestWalletService_ProcessOperation_Deposit(t *testing.T) {
 	mockRepo := new(MockWalletRepository)
-	service := NewWalletService(mockRepo, 3)
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry())
 
 	walletID := uuid.New()
 	operation := model.WalletOperation{
@@ -77,10 +106,10 @@ func TestWalletService_ProcessOperation_Deposit(t *testing.T) {
 	}
 
 	// Настраиваем mock
-	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(nil)
+	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(model.Operation{}, nil)
 
 	// Вызываем метод
-	err := service.ProcessOperation(context.Background(), operation)
+	_, err := service.ProcessOperation(context.Background(), operation)
 
 	// Проверяем результат
 	assert.NoError(t, err)
@@ -89,7 +118,7 @@ func TestWalletService_ProcessOperation_Deposit(t *testing.T) {
 
 func TestWalletService_ProcessOperation_OptimisticLockRetry(t *testing.T) {
 	mockRepo := new(MockWalletRepository)
-	service := NewWalletService(mockRepo, 3)
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry())
 
 	walletID := uuid.New()
 	operation := model.WalletOperation{
@@ -99,14 +128,292 @@ func TestWalletService_ProcessOperation_OptimisticLockRetry(t *testing.T) {
 	}
 
 	// Настраиваем mock: первые 2 вызова - ошибка, третий - успех
-	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(repository.ErrOptimisticLock).Twice()
-	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(nil).Once()
+	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(model.Operation{}, repository.ErrOptimisticLock).Twice()
+	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(model.Operation{}, nil).Once()
 
 	// Вызываем метод
-	err := service.ProcessOperation(context.Background(), operation)
+	_, err := service.ProcessOperation(context.Background(), operation)
 
 	// Проверяем результат
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 	mockRepo.AssertNumberOfCalls(t, "UpdateBalance", 3)
-}
\ No newline at end of file
+}
+
+func TestWalletService_ProcessOperation_VersionMismatchNotRetried(t *testing.T) {
+	mockRepo := new(MockWalletRepository)
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry())
+
+	version := 2
+	operation := model.WalletOperation{
+		WalletID:        uuid.New(),
+		OperationType:   model.OperationTypeWithdraw,
+		Amount:          decimal.NewFromInt(100),
+		ExpectedVersion: &version,
+	}
+
+	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(model.Operation{}, repository.ErrVersionMismatch).Once()
+
+	_, err := service.ProcessOperation(context.Background(), operation)
+
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+	mockRepo.AssertNumberOfCalls(t, "UpdateBalance", 1)
+}
+
+func TestWalletService_ProcessOperation_RetriesSerializationFailure(t *testing.T) {
+	mockRepo := new(MockWalletRepository)
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry())
+
+	operation := model.WalletOperation{
+		WalletID:      uuid.New(),
+		OperationType: model.OperationTypeDeposit,
+		Amount:        decimal.NewFromInt(10),
+	}
+
+	// Ошибки Postgres приходят обернутыми - ретраим по errors.Is
+	serializationErr := fmt.Errorf("failed to commit transaction: %w", repository.ErrSerializationFailure)
+	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(model.Operation{}, serializationErr).Once()
+	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(model.Operation{}, repository.ErrDeadlock).Once()
+	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(model.Operation{}, nil).Once()
+
+	_, err := service.ProcessOperation(context.Background(), operation)
+
+	assert.NoError(t, err)
+	mockRepo.AssertNumberOfCalls(t, "UpdateBalance", 3)
+}
+
+func TestWalletService_ProcessOperation_RetriesExhausted(t *testing.T) {
+	mockRepo := new(MockWalletRepository)
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 4
+	service := NewWalletService(mockRepo, policy, tenant.NewRegistry())
+
+	operation := model.WalletOperation{
+		WalletID:      uuid.New(),
+		OperationType: model.OperationTypeDeposit,
+		Amount:        decimal.NewFromInt(10),
+	}
+
+	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(model.Operation{}, repository.ErrOptimisticLock)
+
+	_, err := service.ProcessOperation(context.Background(), operation)
+
+	assert.Equal(t, ErrOptimisticLock, err)
+	mockRepo.AssertNumberOfCalls(t, "UpdateBalance", 4)
+}
+
+func TestWalletService_ProcessOperation_StopsOnContextCancel(t *testing.T) {
+	mockRepo := new(MockWalletRepository)
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = 10
+	policy.BaseDelay = time.Second
+	policy.MaxDelay = time.Second
+	service := NewWalletService(mockRepo, policy, tenant.NewRegistry())
+
+	operation := model.WalletOperation{
+		WalletID:      uuid.New(),
+		OperationType: model.OperationTypeDeposit,
+		Amount:        decimal.NewFromInt(10),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mockRepo.On("UpdateBalance", mock.Anything, operation).Return(model.Operation{}, repository.ErrOptimisticLock).Run(func(mock.Arguments) {
+		cancel()
+	})
+
+	start := time.Now()
+	_, err := service.ProcessOperation(ctx, operation)
+
+	assert.Equal(t, ErrOptimisticLock, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	mockRepo.AssertNumberOfCalls(t, "UpdateBalance", 1)
+}
+
+func TestWalletService_ReverseOperation_RetriesConflict(t *testing.T) {
+	mockRepo := new(MockWalletRepository)
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry())
+
+	originalID := uuid.New()
+	rev := model.Reversal{OperationID: originalID, IdempotencyKey: "refund-1"}
+	reversal := model.Operation{ID: uuid.New(), OperationType: model.OperationTypeWithdraw, ReversalOf: &originalID}
+
+	mockRepo.On("ReverseOperation", mock.Anything, rev).Return(model.Operation{}, repository.ErrOptimisticLock).Once()
+	mockRepo.On("ReverseOperation", mock.Anything, rev).Return(reversal, nil).Once()
+
+	result, err := service.ReverseOperation(context.Background(), rev)
+
+	assert.NoError(t, err)
+	assert.Equal(t, reversal, result)
+	mockRepo.AssertNumberOfCalls(t, "ReverseOperation", 2)
+}
+
+func TestWalletService_ReverseOperation_AlreadyReversedNotRetried(t *testing.T) {
+	mockRepo := new(MockWalletRepository)
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry())
+
+	rev := model.Reversal{OperationID: uuid.New()}
+	mockRepo.On("ReverseOperation", mock.Anything, rev).Return(model.Operation{}, apperror.ErrAlreadyReversed).Once()
+
+	_, err := service.ReverseOperation(context.Background(), rev)
+
+	assert.ErrorIs(t, err, apperror.ErrAlreadyReversed)
+	mockRepo.AssertNumberOfCalls(t, "ReverseOperation", 1)
+}
+
+func TestWalletService_ProcessOperation_Fees(t *testing.T) {
+	feeWallet := uuid.New()
+	minFee := decimal.NewFromInt(2)
+	maxFee := decimal.NewFromInt(50)
+	fees := model.FeeSchedule{
+		WalletID: feeWallet,
+		Rules: []model.FeeRule{
+			{OperationType: model.OperationTypeWithdraw, Fixed: decimal.NewFromInt(1), Percent: decimal.RequireFromString("1.5"), Min: &minFee, Max: &maxFee},
+			{OperationType: model.OperationTypeWithdraw, Tier: "premium", Percent: decimal.RequireFromString("0.5")},
+			{OperationType: model.OperationTypeDeposit, Fixed: decimal.NewFromInt(5)},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		opType   model.OperationType
+		amount   int64
+		tier     string
+		expected string
+	}{
+		{"fixed plus percent", model.OperationTypeWithdraw, 1000, model.DefaultTier, "16"},
+		{"raised to min", model.OperationTypeWithdraw, 10, model.DefaultTier, "2"},
+		{"capped at max", model.OperationTypeWithdraw, 10000, model.DefaultTier, "50"},
+		{"tier rule", model.OperationTypeWithdraw, 1000, "premium", "5"},
+		{"deposit", model.OperationTypeDeposit, 1000, model.DefaultTier, "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockWalletRepository)
+			service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry(model.Tenant{ID: tenant.DefaultID, Fees: fees}))
+			walletID := uuid.New()
+
+			mockRepo.On("GetWallet", mock.Anything, walletID).Return(model.Wallet{ID: walletID, Tier: tt.tier}, nil).Maybe()
+			mockRepo.On("UpdateBalance", mock.Anything, mock.MatchedBy(func(op model.WalletOperation) bool {
+				return op.Fee != nil && op.Fee.Total.String() == tt.expected && op.Fee.WalletID == feeWallet
+			})).Return(model.Operation{}, nil)
+
+			_, err := service.ProcessOperation(context.Background(), model.WalletOperation{
+				WalletID:      walletID,
+				OperationType: tt.opType,
+				Amount:        decimal.NewFromInt(tt.amount),
+			})
+
+			assert.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWalletService_ProcessOperation_DepositBelowFee(t *testing.T) {
+	mockRepo := new(MockWalletRepository)
+	fees := model.FeeSchedule{
+		WalletID: uuid.New(),
+		Rules:    []model.FeeRule{{OperationType: model.OperationTypeDeposit, Fixed: decimal.NewFromInt(5)}},
+	}
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry(model.Tenant{ID: tenant.DefaultID, Fees: fees}))
+
+	_, err := service.ProcessOperation(context.Background(), model.WalletOperation{
+		WalletID:      uuid.New(),
+		OperationType: model.OperationTypeDeposit,
+		Amount:        decimal.NewFromInt(5),
+	})
+
+	assert.ErrorIs(t, err, apperror.ErrValidationFailed)
+	mockRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything)
+}
+
+func TestWalletService_ProcessOperation_FeeWalletPaysNoFees(t *testing.T) {
+	mockRepo := new(MockWalletRepository)
+	feeWallet := uuid.New()
+	fees := model.FeeSchedule{
+		WalletID: feeWallet,
+		Rules:    []model.FeeRule{{OperationType: model.OperationTypeWithdraw, Fixed: decimal.NewFromInt(1)}},
+	}
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry(model.Tenant{ID: tenant.DefaultID, Fees: fees}))
+
+	mockRepo.On("UpdateBalance", mock.Anything, mock.MatchedBy(func(op model.WalletOperation) bool {
+		return op.Fee == nil
+	})).Return(model.Operation{}, nil)
+
+	_, err := service.ProcessOperation(context.Background(), model.WalletOperation{
+		WalletID:      feeWallet,
+		OperationType: model.OperationTypeWithdraw,
+		Amount:        decimal.NewFromInt(100),
+	})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWalletService_ProcessOperation_FeeRecomputedAfterTierChange(t *testing.T) {
+	mockRepo := new(MockWalletRepository)
+	fees := model.FeeSchedule{
+		WalletID: uuid.New(),
+		Rules: []model.FeeRule{
+			{OperationType: model.OperationTypeWithdraw, Fixed: decimal.NewFromInt(10)},
+			{OperationType: model.OperationTypeWithdraw, Tier: "premium", Fixed: decimal.NewFromInt(1)},
+		},
+	}
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry(model.Tenant{ID: tenant.DefaultID, Fees: fees}))
+	walletID := uuid.New()
+
+	// Тариф сменили между расчетом комиссии и блокировкой кошелька
+	mockRepo.On("GetWallet", mock.Anything, walletID).Return(model.Wallet{ID: walletID, Tier: model.DefaultTier}, nil).Once()
+	mockRepo.On("GetWallet", mock.Anything, walletID).Return(model.Wallet{ID: walletID, Tier: "premium"}, nil).Once()
+	mockRepo.On("UpdateBalance", mock.Anything, mock.MatchedBy(func(op model.WalletOperation) bool {
+		return op.FeeTier == model.DefaultTier && op.Fee.Total.String() == "10"
+	})).Return(model.Operation{}, repository.ErrOptimisticLock).Once()
+	mockRepo.On("UpdateBalance", mock.Anything, mock.MatchedBy(func(op model.WalletOperation) bool {
+		return op.FeeTier == "premium" && op.Fee.Total.String() == "1"
+	})).Return(model.Operation{}, nil).Once()
+
+	_, err := service.ProcessOperation(context.Background(), model.WalletOperation{
+		WalletID:      walletID,
+		OperationType: model.OperationTypeWithdraw,
+		Amount:        decimal.NewFromInt(100),
+	})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWalletService_ProcessOperation_CurrencyNotAllowed(t *testing.T) {
+	mockRepo := new(MockWalletRepository)
+	tenants := tenant.NewRegistry(model.Tenant{ID: "acme", Currencies: []string{"EUR"}})
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenants)
+
+	_, err := service.ProcessOperation(tenant.NewContext(context.Background(), "acme"), model.WalletOperation{
+		WalletID:      uuid.New(),
+		OperationType: model.OperationTypeDeposit,
+		Amount:        decimal.NewFromInt(100),
+		Currency:      "USD",
+	})
+
+	assert.ErrorIs(t, err, apperror.ErrValidationFailed)
+	mockRepo.AssertNotCalled(t, "UpdateBalance", mock.Anything, mock.Anything)
+}
+
+func TestWalletService_ListWallets_Pagination(t *testing.T) {
+	mockRepo := new(MockWalletRepository)
+	service := NewWalletService(mockRepo, DefaultRetryPolicy(), tenant.NewRegistry())
+	last := model.Wallet{ID: uuid.New(), Balance: decimal.NewFromInt(25)}
+
+	mockRepo.On("ListWallets", mock.Anything, mock.MatchedBy(func(f model.WalletFilter) bool {
+		return f.Limit == 2 && f.Sort == model.SortBalanceDesc
+	})).Return([]model.Wallet{{ID: uuid.New()}, last}, nil)
+
+	page, err := service.ListWallets(context.Background(), model.WalletFilter{Sort: model.SortBalanceDesc, Limit: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &model.WalletCursor{Sort: model.SortBalanceDesc, Value: "25", ID: last.ID}, page.Next)
+
+	// Курсор другого порядка не подходит
+	_, err = service.ListWallets(context.Background(), model.WalletFilter{After: page.Next})
+	assert.ErrorIs(t, err, apperror.ErrValidationFailed)
+}
@@ -0,0 +1,96 @@
+// Package tenant передает тенанта запроса через контекст и хранит настройки
+// тенантов. Репозитории берут тенанта из контекста и видят только его данные
+package tenant
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"wallet-service/internal/model"
+)
+
+// DefaultID - тенант запросов без ключа и данных, созданных до появления тенантов
+const DefaultID = "default"
+
+type contextKey struct{}
+
+// NewContext возвращает контекст, в котором запрос выполняется от имени тенанта id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext возвращает тенанта запроса; DefaultID, если тенант не задан
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return DefaultID
+}
+
+// Registry - настройки тенантов и их API-ключи
+type Registry struct {
+	tenants map[string]model.Tenant
+	// keys - тенант по SHA-256 ключа: сами ключи в памяти не держим
+	keys map[[sha256.Size]byte]string
+}
+
+func NewRegistry(tenants ...model.Tenant) *Registry {
+	r := &Registry{
+		tenants: make(map[string]model.Tenant, len(tenants)),
+		keys:    make(map[[sha256.Size]byte]string),
+	}
+	for _, t := range tenants {
+		r.tenants[t.ID] = t
+		for _, key := range t.APIKeys {
+			r.keys[sha256.Sum256([]byte(key))] = t.ID
+		}
+	}
+	return r
+}
+
+// Get возвращает настройки тенанта; false - тенант не настроен
+func (r *Registry) Get(id string) (model.Tenant, bool) {
+	t, ok := r.tenants[id]
+	return t, ok
+}
+
+// All возвращает настройки всех тенантов в порядке id
+func (r *Registry) All() []model.Tenant {
+	tenants := make([]model.Tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		tenants = append(tenants, t)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants
+}
+
+// Current - настройки тенанта запроса. Для ненастроенного тенанта - пустые:
+// без ограничения валют, без лимитов и комиссий
+func (r *Registry) Current(ctx context.Context) model.Tenant {
+	id := FromContext(ctx)
+	if t, ok := r.tenants[id]; ok {
+		return t
+	}
+	return model.Tenant{ID: id}
+}
+
+// Authenticate находит тенанта по API-ключу
+func (r *Registry) Authenticate(apiKey string) (string, bool) {
+	id, ok := r.keys[sha256.Sum256([]byte(apiKey))]
+	return id, ok
+}
+
+// KeyFingerprint - короткий отпечаток API-ключа для журнала аудита: по нему видно,
+// каким из ключей тенанта выполнен запрос, но сам ключ не восстановить
+func KeyFingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// KeysRequired сообщает, нужен ли клиентам ключ. Пока ключей нет ни у одного
+// тенанта, все запросы выполняются от имени DefaultID, как до появления тенантов
+func (r *Registry) KeysRequired() bool {
+	return len(r.keys) > 0
+}
@@ -1,30 +1,61 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"wallet-service/internal/config"
+	"wallet-service/internal/database"
+	"wallet-service/internal/metrics"
+	"wallet-service/internal/model"
+	"wallet-service/internal/repository"
+	"wallet-service/internal/service"
+	"wallet-service/internal/tenant"
+	"wallet-service/pkg/client"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
 func main() {
-	baseURL := "http://localhost:8080"
+	baseURL := flag.String("url", "http://localhost:8080", "адрес сервиса для HTTP-теста")
+	concurrentRequests := flag.Int("requests", 1000, "число конкурентных операций")
+	compare := flag.Bool("compare-strategies", false, "сравнить стратегии блокировок напрямую через БД (DB_* из окружения)")
+	strategies := flag.String("strategies", strings.Join(repository.LockStrategies, ","), "стратегии для сравнения")
+	retries := flag.Int("retries", 10, "максимум попыток операции при сравнении стратегий")
+	flag.Parse()
+
+	if *compare {
+		compareStrategies(strings.Split(*strategies, ","), *concurrentRequests, *retries)
+		return
+	}
+
+	runHTTPLoadTest(*baseURL, *concurrentRequests)
+}
+
+func runHTTPLoadTest(baseURL string, concurrentRequests int) {
+	ctx := context.Background()
 	walletID := uuid.New()
-	concurrentRequests := 1000
+
+	// Повторы клиента отключены: нагрузочный тест должен видеть ошибки сервиса как есть
+	walletClient, err := client.New(baseURL, client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 1}))
+	if err != nil {
+		log.Fatalf("Failed to create client: %v", err)
+	}
+
 	var successCount int32
 	var errorCount int32
 
 	fmt.Printf("Creating wallet: %s\n", walletID)
 	
 	// Сначала создаем кошелек через DEPOSIT
-	if err := createWallet(baseURL, walletID); err != nil {
+	if _, err := walletClient.Deposit(ctx, walletID, decimal.NewFromInt(1)); err != nil {
 		log.Fatalf("Failed to create wallet: %v", err)
 	}
 
@@ -39,7 +70,7 @@ func main() {
 		go func(index int) {
 			defer wg.Done()
 			
-			if err := sendDepositRequest(baseURL, walletID, decimal.NewFromInt(1)); err != nil {
+			if _, err := walletClient.Deposit(ctx, walletID, decimal.NewFromInt(1)); err != nil {
 				atomic.AddInt32(&errorCount, 1)
 				fmt.Printf("Request %d failed: %v\n", index, err)
 			} else {
@@ -59,70 +90,117 @@ func main() {
 	fmt.Printf("RPS: %.2f\n", float64(concurrentRequests)/duration.Seconds())
 	
 	// Проверяем финальный баланс
-	balance, err := getBalance(baseURL, walletID)
+	wallet, err := walletClient.GetWallet(ctx, walletID)
 	if err != nil {
 		log.Printf("Failed to get final balance: %v", err)
 	} else {
 		expected := concurrentRequests + 1
-		fmt.Printf("Final balance: %s (expected: %d)\n", balance.String(), expected)
-		fmt.Printf("Balance correct: %t\n", balance.Equal(decimal.NewFromInt(int64(expected))))
+		fmt.Printf("Final balance: %s (expected: %d)\n", wallet.Balance.String(), expected)
+		fmt.Printf("Balance correct: %t\n", wallet.Balance.Equal(decimal.NewFromInt(int64(expected))))
 	}
 }
 
-func createWallet(baseURL string, walletID uuid.UUID) error {
-	return sendDepositRequest(baseURL, walletID, decimal.NewFromInt(1))
+type strategyResult struct {
+	name      string
+	success   int32
+	conflicts int32
+	errors    int32
+	retries   int64
+	duration  time.Duration
+	balanceOK bool
 }
 
-func sendDepositRequest(baseURL string, walletID uuid.UUID, amount decimal.Decimal) error {
-	reqBody := struct {
-		WalletID      string `json:"walletId"`
-		OperationType string `json:"operationType"`
-		Amount        string `json:"amount"`
-	}{
-		WalletID:      walletID.String(),
-		OperationType: "DEPOSIT",
-		Amount:        amount.String(),
+// compareStrategies прогоняет одинаковую нагрузку на один кошелек для каждой
+// стратегии блокировок, минуя HTTP, и печатает сводную таблицу
+func compareStrategies(names []string, concurrentRequests, retries int) {
+	// Флаги нагрузочного теста не относятся к конфигурации сервиса - только файл и окружение
+	cfg, err := config.LoadArgs(nil)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	body, err := json.Marshal(reqBody)
+	cluster, err := database.Connect(cfg)
 	if err != nil {
-		return fmt.Errorf("marshal error: %w", err)
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
+	defer cluster.Close()
 
-	fmt.Printf("Sending request: %s\n", string(body))
-
-	resp, err := http.Post(baseURL+"/api/v1/wallet", "application/json", bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("http error: %w", err)
+	if err := database.RunMigrations(cluster.DB); err != nil {
+		log.Fatalf("Failed to run migrations: %v", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		var errorBody map[string]interface{}
-		json.NewDecoder(resp.Body).Decode(&errorBody)
-		return fmt.Errorf("unexpected status: %d, body: %v", resp.StatusCode, errorBody)
+	policy := service.DefaultRetryPolicy()
+	policy.MaxAttempts = retries
+
+	var results []strategyResult
+	for _, name := range names {
+		strategy, err := repository.ParseLockStrategy(strings.TrimSpace(name))
+		if err != nil {
+			log.Fatalf("Invalid strategy: %v", err)
+		}
+
+		// Лимиты и комиссии не задаем: сравниваем только стоимость блокировок
+		tenants := tenant.NewRegistry()
+		walletService := service.NewWalletService(repository.NewWalletRepository(cluster.Primary, strategy, tenants), policy, tenants)
+		results = append(results, runStrategy(strategy.Name(), walletService, concurrentRequests))
 	}
 
-	return nil
+	fmt.Printf("\n=== LOCK STRATEGY COMPARISON (%d concurrent deposits, %d attempts max) ===\n", concurrentRequests, retries)
+	fmt.Printf("%-14s %8s %10s %8s %8s %12s %10s %8s\n", "strategy", "success", "conflicts", "errors", "retries", "duration", "RPS", "balance")
+	for _, r := range results {
+		fmt.Printf("%-14s %8d %10d %8d %8d %12v %10.2f %8t\n",
+			r.name, r.success, r.conflicts, r.errors, r.retries, r.duration.Round(time.Millisecond),
+			float64(concurrentRequests)/r.duration.Seconds(), r.balanceOK)
+	}
+	// Сколько раз операции ждали свободного соединения - пул мог ограничить RPS раньше блокировок
+	pool := cluster.PrimaryStats()
+	fmt.Printf("pool: max %d, opened %d, acquires %d, waited for a connection %d\n", pool.Max, pool.NewConns, pool.Acquires, pool.EmptyAcquires)
 }
 
-func getBalance(baseURL string, walletID uuid.UUID) (decimal.Decimal, error) {
-	resp, err := http.Get(baseURL + "/api/v1/wallets/" + walletID.String())
-	if err != nil {
-		return decimal.Zero, err
-	}
-	defer resp.Body.Close()
+func runStrategy(name string, walletService *service.WalletService, concurrentRequests int) strategyResult {
+	ctx := context.Background()
+	walletID := uuid.New()
+	result := strategyResult{name: name}
 
-	if resp.StatusCode != http.StatusOK {
-		return decimal.Zero, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	deposit := model.WalletOperation{
+		WalletID:      walletID,
+		OperationType: model.OperationTypeDeposit,
+		Amount:        decimal.NewFromInt(1),
 	}
+	if _, err := walletService.ProcessOperation(ctx, deposit); err != nil {
+		log.Fatalf("Failed to create wallet for %s: %v", name, err)
+	}
+
+	retriesBefore := metrics.OperationRetries.Value()
 
-	var balanceResp struct {
-		Balance decimal.Decimal `json:"balance"`
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrentRequests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := walletService.ProcessOperation(ctx, deposit)
+			switch {
+			case err == nil:
+				atomic.AddInt32(&result.success, 1)
+			case errors.Is(err, service.ErrOptimisticLock):
+				atomic.AddInt32(&result.conflicts, 1)
+			default:
+				atomic.AddInt32(&result.errors, 1)
+			}
+		}()
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&balanceResp); err != nil {
-		return decimal.Zero, err
+	wg.Wait()
+	result.duration = time.Since(start)
+	result.retries = metrics.OperationRetries.Value() - retriesBefore
+
+	balance, err := walletService.GetBalance(ctx, walletID)
+	if err != nil {
+		log.Printf("Failed to get final balance for %s: %v", name, err)
+		return result
 	}
+	result.balanceOK = balance.Equal(decimal.NewFromInt(int64(result.success) + 1))
 
-	return balanceResp.Balance, nil
-}
\ No newline at end of file
+	return result
+}
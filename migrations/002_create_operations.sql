@@ -0,0 +1,19 @@
+-- Журнал примененных операций. Каждая операция получает id, на который
+-- может ссылаться сторнирование (reversal_of)
+CREATE TABLE IF NOT EXISTS operations (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallets(id),
+    operation_type VARCHAR(16) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    balance_after DECIMAL(15,2) NOT NULL,
+    wallet_version INTEGER NOT NULL,
+    idempotency_key VARCHAR(255),
+    reversal_of UUID REFERENCES operations(id),
+    reversed_amount DECIMAL(15,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT operations_reversed_amount_check CHECK (reversed_amount >= 0 AND reversed_amount <= amount)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_operations_idempotency_key ON operations(idempotency_key) WHERE idempotency_key IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_operations_wallet_created ON operations(wallet_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_operations_reversal_of ON operations(reversal_of) WHERE reversal_of IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Лимиты, заданные для отдельных кошельков. NULL - действует глобальное значение
+-- из конфигурации (LIMIT_*)
+CREATE TABLE IF NOT EXISTS wallet_limits (
+    wallet_id UUID PRIMARY KEY REFERENCES wallets(id),
+    max_operation_amount DECIMAL(15,2) CHECK (max_operation_amount >= 0),
+    daily_withdrawal_amount DECIMAL(15,2) CHECK (daily_withdrawal_amount >= 0),
+    daily_withdrawal_count INTEGER CHECK (daily_withdrawal_count >= 0),
+    monthly_withdrawal_amount DECIMAL(15,2) CHECK (monthly_withdrawal_amount >= 0),
+    monthly_withdrawal_count INTEGER CHECK (monthly_withdrawal_count >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
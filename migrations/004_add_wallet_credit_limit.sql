@@ -0,0 +1,6 @@
+-- Овердрафт: баланс может уходить в минус до -credit_limit. Ограничение на уровне БД
+-- страхует от ошибки в коде и от гонки со снижением лимита
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS credit_limit DECIMAL(15,2) NOT NULL DEFAULT 0;
+
+ALTER TABLE wallets ADD CONSTRAINT wallets_credit_limit_check CHECK (credit_limit >= 0);
+ALTER TABLE wallets ADD CONSTRAINT wallets_balance_check CHECK (balance >= -credit_limit);
@@ -0,0 +1,29 @@
+-- Отложенные и повторяющиеся операции. wallet_id без внешнего ключа:
+-- запланированное пополнение может создать кошелек при первом запуске
+CREATE TABLE IF NOT EXISTS scheduled_operations (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL,
+    operation_type VARCHAR(16) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    schedule VARCHAR(255),
+    next_run_at TIMESTAMPTZ,
+    status VARCHAR(16) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Планировщик выбирает наступившие задания: WHERE status = 'ACTIVE' AND next_run_at <= now()
+CREATE INDEX IF NOT EXISTS idx_scheduled_operations_due ON scheduled_operations(next_run_at) WHERE status = 'ACTIVE';
+CREATE INDEX IF NOT EXISTS idx_scheduled_operations_wallet ON scheduled_operations(wallet_id, created_at);
+
+-- История запусков. Уникальность (задание, плановое время) не дает записать запуск дважды
+CREATE TABLE IF NOT EXISTS scheduled_operation_runs (
+    scheduled_operation_id UUID NOT NULL REFERENCES scheduled_operations(id),
+    scheduled_at TIMESTAMPTZ NOT NULL,
+    executed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    status VARCHAR(16) NOT NULL,
+    operation_id UUID REFERENCES operations(id),
+    error_code VARCHAR(64),
+    error TEXT,
+    PRIMARY KEY (scheduled_operation_id, scheduled_at)
+);
@@ -0,0 +1,7 @@
+-- Комиссии: тариф кошелька выбирает правило из тарифной сетки, а удержанная
+-- комиссия хранится в операции вместе с расшифровкой
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS tier VARCHAR(32) NOT NULL DEFAULT 'standard';
+
+-- {"fixed", "variable", "total", "walletId", "operationId"}; NULL - операция без комиссии.
+-- Зачисление на кошелек комиссий - отдельная операция с типом FEE
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS fee JSONB;
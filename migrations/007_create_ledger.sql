@@ -0,0 +1,52 @@
+-- Двойная запись: каждая операция - проводка (journal entry) из нескольких записей
+-- по счетам, сумма которых равна нулю. Счета - кошельки (wallet:<id>) и системные
+-- счета (system:cash-in, system:cash-out, system:opening-balance).
+-- Положительная сумма увеличивает остаток счета, отрицательная - уменьшает.
+-- id проводки операции совпадает с id операции
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS postings (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id UUID NOT NULL REFERENCES journal_entries(id),
+    account VARCHAR(64) NOT NULL,
+    amount DECIMAL(15,2) NOT NULL CHECK (amount <> 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_postings_entry ON postings(entry_id);
+CREATE INDEX IF NOT EXISTS idx_postings_account ON postings(account);
+
+-- Инвариант проверяется при коммите: несбалансированная проводка откатывает всю транзакцию
+CREATE OR REPLACE FUNCTION check_entry_balanced() RETURNS trigger AS $$
+DECLARE
+    total DECIMAL;
+BEGIN
+    SELECT SUM(amount) INTO total FROM postings WHERE entry_id = NEW.entry_id;
+    IF total <> 0 THEN
+        RAISE EXCEPTION 'journal entry % is not balanced: postings sum to %', NEW.entry_id, total
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS postings_balanced ON postings;
+CREATE CONSTRAINT TRIGGER postings_balanced
+    AFTER INSERT ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_entry_balanced();
+
+-- Входящие остатки: текущий баланс каждого кошелька против system:opening-balance,
+-- чтобы остатки счетов кошельков сходились с wallets.balance
+CREATE TEMPORARY TABLE opening_balances ON COMMIT DROP AS
+    SELECT gen_random_uuid() AS entry_id, id AS wallet_id, balance
+    FROM wallets WHERE balance <> 0;
+
+INSERT INTO journal_entries (id) SELECT entry_id FROM opening_balances;
+
+INSERT INTO postings (entry_id, account, amount)
+    SELECT entry_id, 'wallet:' || wallet_id, balance FROM opening_balances
+    UNION ALL
+    SELECT entry_id, 'system:opening-balance', -balance FROM opening_balances;
@@ -0,0 +1,30 @@
+-- Остатки на момент времени: остаток счета на T = последний снимок не позже T +
+-- записи после снимка до T. Время записи - время ее проводки
+ALTER TABLE postings ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+
+UPDATE postings p SET created_at = e.created_at
+    FROM journal_entries e WHERE e.id = p.entry_id AND p.created_at IS NULL;
+
+ALTER TABLE postings ALTER COLUMN created_at SET DEFAULT now();
+ALTER TABLE postings ALTER COLUMN created_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_postings_account_created ON postings(account, created_at);
+-- Снимок обрабатывает записи между предыдущим и новым моментом снимка
+CREATE INDEX IF NOT EXISTS idx_postings_created ON postings(created_at);
+-- Покрывается idx_postings_account_created
+DROP INDEX IF EXISTS idx_postings_account;
+
+-- Моменты снимков. Все записи не позже последнего момента учтены в снимках
+CREATE TABLE IF NOT EXISTS balance_snapshot_runs (
+    taken_at TIMESTAMPTZ PRIMARY KEY,
+    accounts INTEGER NOT NULL
+);
+
+-- Снимки остатков. Снимок берется только для счетов, по которым были записи
+-- после предыдущего снимка
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    account VARCHAR(64) NOT NULL,
+    taken_at TIMESTAMPTZ NOT NULL,
+    balance DECIMAL(15,2) NOT NULL,
+    PRIMARY KEY (account, taken_at)
+);
@@ -0,0 +1,27 @@
+-- Ручные корректировки с двойным контролем: один оператор предлагает, другой
+-- одобряет или отклоняет. Строка хранит обоих операторов, время и комментарии
+CREATE TABLE IF NOT EXISTS adjustments (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallets(id),
+    direction VARCHAR(8) NOT NULL CHECK (direction IN ('CREDIT', 'DEBIT')),
+    amount DECIMAL(15,2) NOT NULL CHECK (amount > 0),
+    reason_code VARCHAR(32) NOT NULL,
+    comment TEXT NOT NULL CHECK (comment <> ''),
+    status VARCHAR(16) NOT NULL DEFAULT 'PENDING',
+    proposed_by VARCHAR(64) NOT NULL,
+    proposed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    reviewed_by VARCHAR(64),
+    reviewed_at TIMESTAMPTZ,
+    review_comment TEXT,
+    operation_id UUID REFERENCES operations(id),
+    -- Одобрить свою же корректировку нельзя; отклонить (отозвать) - можно
+    CONSTRAINT adjustments_maker_checker CHECK (status <> 'APPROVED' OR reviewed_by <> proposed_by),
+    CONSTRAINT adjustments_applied CHECK ((status = 'APPROVED') = (operation_id IS NOT NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_adjustments_status ON adjustments(status, proposed_at);
+CREATE INDEX IF NOT EXISTS idx_adjustments_wallet ON adjustments(wallet_id, proposed_at);
+
+-- Операция, которой применена корректировка. Уникальность не дает применить ее дважды
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS adjustment_id UUID REFERENCES adjustments(id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_operations_adjustment ON operations(adjustment_id) WHERE adjustment_id IS NOT NULL;
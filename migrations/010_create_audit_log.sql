@@ -0,0 +1,40 @@
+-- Журнал аудита изменяющих запросов. Только добавление: изменить или удалить
+-- записи не дают триггеры, в том числе прямыми запросами к БД
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    request_id VARCHAR(128) NOT NULL,
+    actor VARCHAR(64) NOT NULL,
+    client_ip VARCHAR(64),
+    method VARCHAR(16) NOT NULL,
+    endpoint VARCHAR(255) NOT NULL,
+    path VARCHAR(1024) NOT NULL,
+    wallet_id UUID,
+    operation_id UUID,
+    summary JSONB,
+    status INTEGER NOT NULL,
+    outcome VARCHAR(16) NOT NULL,
+    error_code VARCHAR(64),
+    latency_ms DOUBLE PRECISION NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_wallet ON audit_log(wallet_id, id) WHERE wallet_id IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only: % is not allowed', TG_OP
+        USING ERRCODE = 'insufficient_privilege';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+CREATE TRIGGER audit_log_no_update
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,33 @@
+-- Цепочка хешей операций кошелька: звено seq заверяет операцию и хеш звена seq-1.
+-- Звено пишется в транзакции операции. Операции до этой миграции в цепочку не входят:
+-- цепочка кошелька начинается с первой операции после нее
+CREATE TABLE IF NOT EXISTS operation_chain (
+    wallet_id UUID NOT NULL,
+    seq BIGINT NOT NULL CHECK (seq > 0),
+    operation_id UUID NOT NULL UNIQUE REFERENCES operations(id),
+    prev_hash BYTEA NOT NULL,
+    hash BYTEA NOT NULL,
+    PRIMARY KEY (wallet_id, seq)
+);
+
+-- Подписанные контрольные точки. digest покрывает digest предыдущей точки,
+-- поэтому удаление или подмена точки тоже обнаруживается
+CREATE TABLE IF NOT EXISTS chain_checkpoints (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    prev_digest BYTEA NOT NULL,
+    digest BYTEA NOT NULL,
+    signature BYTEA NOT NULL
+);
+
+-- Головы цепочек в точке: только кошельки, цепочка которых выросла с предыдущей точки
+CREATE TABLE IF NOT EXISTS chain_checkpoint_heads (
+    checkpoint_id BIGINT NOT NULL REFERENCES chain_checkpoints(id),
+    wallet_id UUID NOT NULL,
+    seq BIGINT NOT NULL,
+    hash BYTEA NOT NULL,
+    PRIMARY KEY (checkpoint_id, wallet_id)
+);
+
+-- Последняя точка кошелька при проверке цепочки
+CREATE INDEX IF NOT EXISTS idx_chain_checkpoint_heads_wallet ON chain_checkpoint_heads(wallet_id, checkpoint_id);
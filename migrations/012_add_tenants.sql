@@ -0,0 +1,65 @@
+-- Тенанты: у каждого кошелька есть владелец, и все связанные данные несут его id.
+-- Запросы сервиса фильтруют по tenant_id, поэтому кошелек одного тенанта не виден
+-- другому. Данные до этой миграции принадлежат тенанту 'default'
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+-- До тенантов сервис работал в одной валюте
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE wallets ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE wallets ALTER COLUMN currency DROP DEFAULT;
+
+-- id кошелька остается глобально уникальным; пара нужна для составных внешних ключей,
+-- которые не дают сослаться на кошелек чужого тенанта
+ALTER TABLE wallets ADD CONSTRAINT wallets_tenant_id_key UNIQUE (tenant_id, id);
+
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE operations ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_wallet_id_fkey;
+ALTER TABLE operations ADD CONSTRAINT operations_wallet_fkey
+    FOREIGN KEY (tenant_id, wallet_id) REFERENCES wallets(tenant_id, id);
+
+-- Ключ идемпотентности уникален в пределах тенанта: клиенты разных тенантов
+-- генерируют ключи независимо
+DROP INDEX IF EXISTS idx_operations_idempotency_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_operations_tenant_idempotency_key
+    ON operations(tenant_id, idempotency_key) WHERE idempotency_key IS NOT NULL;
+
+ALTER TABLE wallet_limits ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE wallet_limits ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE wallet_limits DROP CONSTRAINT IF EXISTS wallet_limits_wallet_id_fkey;
+ALTER TABLE wallet_limits ADD CONSTRAINT wallet_limits_wallet_fkey
+    FOREIGN KEY (tenant_id, wallet_id) REFERENCES wallets(tenant_id, id);
+
+ALTER TABLE adjustments ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE adjustments ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE adjustments DROP CONSTRAINT IF EXISTS adjustments_wallet_id_fkey;
+ALTER TABLE adjustments ADD CONSTRAINT adjustments_wallet_fkey
+    FOREIGN KEY (tenant_id, wallet_id) REFERENCES wallets(tenant_id, id);
+DROP INDEX IF EXISTS idx_adjustments_status;
+CREATE INDEX IF NOT EXISTS idx_adjustments_tenant_status ON adjustments(tenant_id, status, proposed_at);
+
+-- Кошелька может еще не быть, поэтому без внешнего ключа, как и wallet_id
+ALTER TABLE scheduled_operations ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE scheduled_operations ALTER COLUMN tenant_id DROP DEFAULT;
+
+ALTER TABLE operation_chain ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE operation_chain ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- Системные счета (system:cash-in и другие) у каждого тенанта свои, поэтому
+-- остаток счета - сумма записей с тем же tenant_id
+ALTER TABLE journal_entries ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE journal_entries ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE postings ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE postings ALTER COLUMN tenant_id DROP DEFAULT;
+DROP INDEX IF EXISTS idx_postings_account_created;
+CREATE INDEX IF NOT EXISTS idx_postings_tenant_account_created ON postings(tenant_id, account, created_at);
+
+ALTER TABLE balance_snapshots ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE balance_snapshots ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE balance_snapshots DROP CONSTRAINT IF EXISTS balance_snapshots_pkey;
+ALTER TABLE balance_snapshots ADD PRIMARY KEY (tenant_id, account, taken_at);
+
+-- NULL - запрос отклонен до того, как тенант стал известен (неверный ключ).
+-- ADD COLUMN не вызывает триггеры audit_log, записи остаются неизменяемыми
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) DEFAULT 'default';
+ALTER TABLE audit_log ALTER COLUMN tenant_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_audit_log_tenant ON audit_log(tenant_id, id);
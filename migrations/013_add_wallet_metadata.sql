@@ -0,0 +1,29 @@
+-- Метаданные кошелька: ссылка на владельца во внешней системе, имя для интерфейса,
+-- метки ключ-значение, статус и время создания и последнего изменения
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS owner_ref VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS display_name VARCHAR(256) NOT NULL DEFAULT '';
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}'
+    CONSTRAINT wallets_labels_check CHECK (jsonb_typeof(labels) = 'object');
+-- FROZEN: операции по кошельку запрещены, пока администратор его не разморозит
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'ACTIVE'
+    CONSTRAINT wallets_status_check CHECK (status IN ('ACTIVE', 'FROZEN'));
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- Существующим кошелькам - время первой и последней операции
+UPDATE wallets w SET created_at = o.first_at, updated_at = o.last_at
+FROM (
+    SELECT wallet_id, MIN(created_at) AS first_at, MAX(created_at) AS last_at
+    FROM operations GROUP BY wallet_id
+) o
+WHERE o.wallet_id = w.id;
+
+-- Список кошельков: сортировка по (колонка, id) в пределах тенанта дает курсор без OFFSET
+CREATE INDEX IF NOT EXISTS idx_wallets_tenant_created ON wallets(tenant_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_wallets_tenant_updated ON wallets(tenant_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_wallets_tenant_balance ON wallets(tenant_id, balance, id);
+CREATE INDEX IF NOT EXISTS idx_wallets_tenant_owner ON wallets(tenant_id, owner_ref) WHERE owner_ref <> '';
+-- Замороженных кошельков мало: фильтру по ACTIVE индекс не поможет, по FROZEN - частичный
+CREATE INDEX IF NOT EXISTS idx_wallets_tenant_frozen ON wallets(tenant_id) WHERE status = 'FROZEN';
+-- Фильтр по меткам - labels @> '{"key": "value"}'
+CREATE INDEX IF NOT EXISTS idx_wallets_labels ON wallets USING GIN (labels jsonb_path_ops);
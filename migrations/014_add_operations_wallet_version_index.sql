@@ -0,0 +1,2 @@
+-- Возобновление потока событий: операции кошелька после версии из Last-Event-ID
+CREATE INDEX IF NOT EXISTS idx_operations_wallet_version ON operations(wallet_id, wallet_version);
@@ -0,0 +1,30 @@
+-- Денежные колонки: DECIMAL(15,2) ограничивал суммы 10^13. NUMERIC(20,2) дает 18 цифр
+-- целой части. Масштаб остается 2 - на нем построены хеш цепочки и округление комиссий;
+-- суммы с большим числом знаков сервис отклоняет сам (model.ValidAmount), а не отдает
+-- Postgres на округление. Рост точности при том же масштабе не переписывает таблицы
+ALTER TABLE wallets
+    ALTER COLUMN balance TYPE NUMERIC(20,2),
+    ALTER COLUMN credit_limit TYPE NUMERIC(20,2);
+
+ALTER TABLE operations
+    ALTER COLUMN amount TYPE NUMERIC(20,2),
+    ALTER COLUMN balance_after TYPE NUMERIC(20,2),
+    ALTER COLUMN reversed_amount TYPE NUMERIC(20,2);
+
+ALTER TABLE wallet_limits
+    ALTER COLUMN max_operation_amount TYPE NUMERIC(20,2),
+    ALTER COLUMN daily_withdrawal_amount TYPE NUMERIC(20,2),
+    ALTER COLUMN monthly_withdrawal_amount TYPE NUMERIC(20,2);
+
+ALTER TABLE scheduled_operations ALTER COLUMN amount TYPE NUMERIC(20,2);
+ALTER TABLE postings ALTER COLUMN amount TYPE NUMERIC(20,2);
+ALTER TABLE balance_snapshots ALTER COLUMN balance TYPE NUMERIC(20,2);
+ALTER TABLE adjustments ALTER COLUMN amount TYPE NUMERIC(20,2);
+
+-- Баланс не ниже -credit_limit проверяет wallets_balance_check из 004.
+-- Версия кошелька растет с 1: ожидаемая версия в запросах не бывает меньше
+ALTER TABLE wallets ADD CONSTRAINT wallets_version_check CHECK (version >= 1);
+ALTER TABLE operations ADD CONSTRAINT operations_wallet_version_check CHECK (wallet_version >= 1);
+
+-- Первичный ключ уже индексирует id: отдельный индекс только замедлял запись
+DROP INDEX IF EXISTS idx_wallets_id;
@@ -0,0 +1,4 @@
+-- Актор клиентского запроса - id тенанта (до 64 символов), двоеточие и отпечаток
+-- API-ключа (16 символов). Удлинение VARCHAR не переписывает таблицу и не задевает
+-- триггеры, запрещающие UPDATE журнала
+ALTER TABLE audit_log ALTER COLUMN actor TYPE VARCHAR(96);
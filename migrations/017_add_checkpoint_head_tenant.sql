@@ -0,0 +1,15 @@
+-- Головы контрольных точек принадлежат тенантам: админский API отдает тенанту только
+-- его головы. Точка остается общей - digest и подпись покрывают головы всех тенантов.
+-- id кошельков уникальны для всех тенантов, поэтому тенант головы - тенант цепочки.
+-- Голова, цепочку которой удалили прямо в БД, сохраняется: ее расхождение найдет проверка
+ALTER TABLE chain_checkpoint_heads ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64);
+UPDATE chain_checkpoint_heads h SET tenant_id = COALESCE(
+        (SELECT c.tenant_id FROM operation_chain c WHERE c.wallet_id = h.wallet_id LIMIT 1),
+        (SELECT w.tenant_id FROM wallets w WHERE w.id = h.wallet_id),
+        'default')
+    WHERE h.tenant_id IS NULL;
+ALTER TABLE chain_checkpoint_heads ALTER COLUMN tenant_id SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_chain_checkpoint_heads_wallet;
+CREATE INDEX IF NOT EXISTS idx_chain_checkpoint_heads_tenant_wallet
+    ON chain_checkpoint_heads(tenant_id, wallet_id, checkpoint_id);
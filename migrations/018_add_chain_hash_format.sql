@@ -0,0 +1,4 @@
+-- Формат хеша звена. v1 не покрывал тенанта операции и поля комиссии, кроме итога
+-- и кошелька; новые звенья пишутся в v2, а существующие проверяются по v1
+ALTER TABLE operation_chain ADD COLUMN IF NOT EXISTS format SMALLINT NOT NULL DEFAULT 1;
+ALTER TABLE operation_chain ALTER COLUMN format DROP DEFAULT;
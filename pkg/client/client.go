@@ -0,0 +1,462 @@
+// Package client - Go-клиент HTTP API wallet-service.
+//
+// Клиент сам генерирует Idempotency-Key для изменяющих запросов и повторяет
+// запросы при конфликте транзакций (409 CONCURRENCY_CONFLICT), сетевых ошибках
+// и ошибках сервера (5xx) с тем же ключом. Изменяющие запросы без ключа при сетевых
+// ошибках и 5xx не повторяются: они могли успеть выполниться.
+//
+//	c, err := client.New("http://localhost:8080")
+//	op, err := c.Deposit(ctx, walletID, decimal.NewFromInt(100))
+//	refund, err := c.Reverse(ctx, op.ID, nil)
+//	wallet, err := c.GetWallet(ctx, walletID)
+//	if errors.Is(err, client.ErrWalletNotFound) { ... }
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Типы API. Объявлены алиасами, чтобы пакет можно было использовать вне модуля
+type (
+	OperationType    = model.OperationType
+	OperationRequest = model.WalletOperationRequest
+	Operation        = model.Operation
+	Balance          = model.BalanceResponse
+	WalletList       = model.WalletListResponse
+	MetadataRequest  = model.WalletMetadataRequest
+	WalletStatus     = model.WalletStatus
+	WalletSort       = model.WalletSort
+	HealthResponse   = model.HealthResponse
+	Problem          = apperror.Problem
+	FieldError       = apperror.FieldError
+)
+
+const (
+	OperationTypeDeposit  = model.OperationTypeDeposit
+	OperationTypeWithdraw = model.OperationTypeWithdraw
+)
+
+// IdempotencyKeyHeader - заголовок, по которому сервер распознает повтор операции
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy - повторы запросов на стороне клиента (full jitter, как на сервере)
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    time.Second,
+	}
+}
+
+type Client struct {
+	baseURL        *url.URL
+	httpClient     *http.Client
+	retry          RetryPolicy
+	idempotencyKey func() string
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// WithIdempotencyKeyGenerator подменяет генератор ключей (по умолчанию - UUID v4)
+func WithIdempotencyKeyGenerator(generate func() string) Option {
+	return func(c *Client) { c.idempotencyKey = generate }
+}
+
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme and host are required", baseURL)
+	}
+
+	c := &Client{
+		baseURL:        u,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+		retry:          DefaultRetryPolicy(),
+		idempotencyKey: func() string { return uuid.NewString() },
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// CallOption настраивает отдельный вызов операции
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	idempotencyKey  string
+	expectedVersion *int
+}
+
+// WithIdempotencyKey задает ключ явно - например, чтобы повторить операцию после рестарта клиента
+func WithIdempotencyKey(key string) CallOption {
+	return func(o *callOptions) { o.idempotencyKey = key }
+}
+
+// IfMatch выполняет операцию, только если версия кошелька не изменилась (иначе ErrPreconditionFailed)
+func IfMatch(version int) CallOption {
+	return func(o *callOptions) { o.expectedVersion = &version }
+}
+
+func (c *Client) Deposit(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, opts ...CallOption) (*Operation, error) {
+	return c.ProcessOperation(ctx, OperationRequest{
+		WalletID:      walletID,
+		OperationType: OperationTypeDeposit,
+		Amount:        amount,
+	}, opts...)
+}
+
+func (c *Client) Withdraw(ctx context.Context, walletID uuid.UUID, amount decimal.Decimal, opts ...CallOption) (*Operation, error) {
+	return c.ProcessOperation(ctx, OperationRequest{
+		WalletID:      walletID,
+		OperationType: OperationTypeWithdraw,
+		Amount:        amount,
+	}, opts...)
+}
+
+// ProcessOperation - POST /api/v1/wallet
+func (c *Client) ProcessOperation(ctx context.Context, req OperationRequest, opts ...CallOption) (*Operation, error) {
+	o := c.callOptions(opts)
+	if o.expectedVersion != nil {
+		req.ExpectedVersion = o.expectedVersion
+	}
+	return c.postOperation(ctx, "/api/v1/wallet", req, o.idempotencyKey)
+}
+
+// Reverse - POST /api/v1/operations/{operationId}/reverse. amount == nil сторнирует весь остаток
+func (c *Client) Reverse(ctx context.Context, operationID uuid.UUID, amount *decimal.Decimal, opts ...CallOption) (*Operation, error) {
+	o := c.callOptions(opts)
+	req := model.ReverseOperationRequest{Amount: amount}
+	return c.postOperation(ctx, "/api/v1/operations/"+operationID.String()+"/reverse", req, o.idempotencyKey)
+}
+
+// GetOperation - GET /api/v1/operations/{operationId}
+func (c *Client) GetOperation(ctx context.Context, operationID uuid.UUID) (*Operation, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/operations/"+operationID.String(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var op Operation
+	if err := json.NewDecoder(resp.Body).Decode(&op); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &op, nil
+}
+
+func (c *Client) callOptions(opts []CallOption) callOptions {
+	o := callOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.idempotencyKey == "" {
+		o.idempotencyKey = c.idempotencyKey()
+	}
+	return o
+}
+
+func (c *Client) postOperation(ctx context.Context, path string, req any, idempotencyKey string) (*Operation, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(IdempotencyKeyHeader, idempotencyKey)
+
+	resp, err := c.do(ctx, http.MethodPost, path, header, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result model.OperationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result.Operation, nil
+}
+
+// GetWallet - GET /api/v1/wallets/{walletId}
+func (c *Client) GetWallet(ctx context.Context, walletID uuid.UUID) (*Balance, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/wallets/"+walletID.String(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var balance Balance
+	if err := json.NewDecoder(resp.Body).Decode(&balance); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &balance, nil
+}
+
+// GetWalletIfModified возвращает ErrNotModified, если версия кошелька по-прежнему равна version
+func (c *Client) GetWalletIfModified(ctx context.Context, walletID uuid.UUID, version int) (*Balance, error) {
+	header := http.Header{}
+	header.Set("If-None-Match", `"`+strconv.Itoa(version)+`"`)
+
+	resp, err := c.do(ctx, http.MethodGet, "/api/v1/wallets/"+walletID.String(), header, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
+
+	var balance Balance
+	if err := json.NewDecoder(resp.Body).Decode(&balance); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &balance, nil
+}
+
+// WalletQuery - фильтр и порядок списка кошельков; пустые поля не ограничивают выборку
+type WalletQuery struct {
+	Owner      string
+	Labels     map[string]string
+	Status     WalletStatus
+	MinBalance *decimal.Decimal
+	MaxBalance *decimal.Decimal
+	Sort       WalletSort
+	// Cursor - NextCursor предыдущей страницы
+	Cursor string
+	Limit  int
+}
+
+func (q WalletQuery) values() url.Values {
+	v := url.Values{}
+	set := func(name, value string) {
+		if value != "" {
+			v.Set(name, value)
+		}
+	}
+	set("owner", q.Owner)
+	for key, value := range q.Labels {
+		v.Add("label", key+":"+value)
+	}
+	set("status", string(q.Status))
+	if q.MinBalance != nil {
+		set("minBalance", q.MinBalance.String())
+	}
+	if q.MaxBalance != nil {
+		set("maxBalance", q.MaxBalance.String())
+	}
+	set("sort", string(q.Sort))
+	set("cursor", q.Cursor)
+	if q.Limit > 0 {
+		set("limit", strconv.Itoa(q.Limit))
+	}
+	return v
+}
+
+// ListWallets - GET /api/v1/wallets. Следующая страница - с Cursor = NextCursor
+func (c *Client) ListWallets(ctx context.Context, q WalletQuery) (*WalletList, error) {
+	path := "/api/v1/wallets"
+	if v := q.values(); len(v) > 0 {
+		path += "?" + v.Encode()
+	}
+	resp, err := c.do(ctx, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var list WalletList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &list, nil
+}
+
+// UpdateMetadata - PATCH /api/v1/wallets/{walletId}: меняет только переданные поля
+func (c *Client) UpdateMetadata(ctx context.Context, walletID uuid.UUID, req MetadataRequest) (*Balance, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(ctx, http.MethodPatch, "/api/v1/wallets/"+walletID.String(), header, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var balance Balance
+	if err := json.NewDecoder(resp.Body).Decode(&balance); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &balance, nil
+}
+
+// Ready - GET /readyz. Неготовый сервис возвращает *APIError со статусом 503 и деталями проверок
+func (c *Client) Ready(ctx context.Context) (*HealthResponse, error) {
+	resp, err := c.do(ctx, http.MethodGet, "/readyz", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var health HealthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &health, nil
+}
+
+// do выполняет запрос с повторами. Успешными считаются 2xx и 304;
+// остальные ответы превращаются в *APIError
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body []byte) (*http.Response, error) {
+	attempts := c.retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	safe := safeToRetry(method, header)
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			if err := sleep(ctx, c.backoff(attempt-1)); err != nil {
+				return nil, lastErr
+			}
+		}
+
+		resp, err := c.send(ctx, method, path, header, body)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil || !safe {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified {
+			return resp, nil
+		}
+
+		apiErr := decodeAPIError(resp)
+		resp.Body.Close()
+		lastErr = apiErr
+		if !retryable(apiErr, safe) {
+			return nil, lastErr
+		}
+	}
+	return nil, lastErr
+}
+
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.String()+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json, "+apperror.ProblemContentType)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+// retryable: конфликт транзакций означает, что операция не применилась, - ее можно
+// повторить. Остальные 409 (например, операция уже сторнирована) повтором не исправить.
+// 5xx повторяется, только если повтор безопасен (safeToRetry)
+func retryable(err *APIError, safe bool) bool {
+	if err.StatusCode == http.StatusConflict {
+		return err.Problem.Code == apperror.CodeConcurrencyConflict
+	}
+	return safe && err.StatusCode >= http.StatusInternalServerError
+}
+
+// safeToRetry: GET ничего не меняет, а запрос с Idempotency-Key сервер не применит дважды
+func safeToRetry(method string, header http.Header) bool {
+	return method == http.MethodGet || header.Get(IdempotencyKeyHeader) != ""
+}
+
+func (c *Client) backoff(retry int) time.Duration {
+	if c.retry.BaseDelay <= 0 {
+		return 0
+	}
+	ceiling := c.retry.BaseDelay
+	for i := 1; i < retry && (c.retry.MaxDelay <= 0 || ceiling < c.retry.MaxDelay); i++ {
+		ceiling *= 2
+	}
+	if c.retry.MaxDelay > 0 && ceiling > c.retry.MaxDelay {
+		ceiling = c.retry.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func decodeAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err := json.Unmarshal(data, &apiErr.Problem); err != nil || apiErr.Problem.Code == "" {
+		// Ответ не от сервиса (прокси, балансировщик) - сохраняем, что есть
+		apiErr.Problem = Problem{
+			Status: resp.StatusCode,
+			Title:  http.StatusText(resp.StatusCode),
+			Detail: strings.TrimSpace(string(data)),
+		}
+	}
+	return apiErr
+}
@@ -0,0 +1,475 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"wallet-service/internal/apperror"
+	"wallet-service/internal/handler"
+	"wallet-service/internal/model"
+	"wallet-service/internal/repository"
+	"wallet-service/internal/service"
+	"wallet-service/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository - репозиторий в памяти, чтобы гонять настоящий роутер и сервис без Postgres
+type memoryRepository struct {
+	mu         sync.Mutex
+	wallets    map[uuid.UUID]model.Wallet
+	operations map[uuid.UUID]model.Operation
+	keys       map[string]uuid.UUID
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		wallets:    map[uuid.UUID]model.Wallet{},
+		operations: map[uuid.UUID]model.Operation{},
+		keys:       map[string]uuid.UUID{},
+	}
+}
+
+func (r *memoryRepository) GetBalance(ctx context.Context, id uuid.UUID) (decimal.Decimal, error) {
+	wallet, err := r.GetWallet(ctx, id)
+	return wallet.Balance, err
+}
+
+func (r *memoryRepository) GetWallet(ctx context.Context, id uuid.UUID) (model.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wallet, ok := r.wallets[id]
+	if !ok {
+		return model.Wallet{}, repository.ErrWalletNotFound
+	}
+	return wallet, nil
+}
+
+func (r *memoryRepository) UpdateBalance(ctx context.Context, op model.WalletOperation) (model.Operation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id, ok := r.keys[op.IdempotencyKey]; ok && op.IdempotencyKey != "" {
+		return r.operations[id], nil
+	}
+	return r.apply(op, nil)
+}
+
+func (r *memoryRepository) GetOperation(ctx context.Context, id uuid.UUID) (model.Operation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	op, ok := r.operations[id]
+	if !ok {
+		return model.Operation{}, apperror.ErrOperationNotFound
+	}
+	return op, nil
+}
+
+func (r *memoryRepository) ReverseOperation(ctx context.Context, rev model.Reversal) (model.Operation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id, ok := r.keys[rev.IdempotencyKey]; ok && rev.IdempotencyKey != "" {
+		return r.operations[id], nil
+	}
+
+	original, ok := r.operations[rev.OperationID]
+	if !ok {
+		return model.Operation{}, apperror.ErrOperationNotFound
+	}
+	remaining := original.Amount.Sub(original.ReversedAmount)
+	if !remaining.IsPositive() {
+		return model.Operation{}, apperror.ErrAlreadyReversed
+	}
+	amount := remaining
+	if rev.Amount != nil {
+		if rev.Amount.GreaterThan(remaining) {
+			return model.Operation{}, apperror.ErrReversalExceeded
+		}
+		amount = *rev.Amount
+	}
+
+	reversal, err := r.apply(model.WalletOperation{
+		WalletID:       original.WalletID,
+		OperationType:  original.OperationType.Opposite(),
+		Amount:         amount,
+		IdempotencyKey: rev.IdempotencyKey,
+	}, &original.ID)
+	if err != nil {
+		return model.Operation{}, err
+	}
+	original.ReversedAmount = original.ReversedAmount.Add(amount)
+	r.operations[original.ID] = original
+	return reversal, nil
+}
+
+// ListWallets поддерживает только отбор по владельцу и порядок по id
+func (r *memoryRepository) ListWallets(ctx context.Context, filter model.WalletFilter) ([]model.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wallets := []model.Wallet{}
+	for _, wallet := range r.wallets {
+		if filter.OwnerRef != "" && wallet.OwnerRef != filter.OwnerRef {
+			continue
+		}
+		if filter.After != nil && wallet.ID.String() <= filter.After.ID.String() {
+			continue
+		}
+		wallets = append(wallets, wallet)
+	}
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].ID.String() < wallets[j].ID.String() })
+	if len(wallets) > filter.Limit {
+		wallets = wallets[:filter.Limit]
+	}
+	return wallets, nil
+}
+
+func (r *memoryRepository) UpdateMetadata(ctx context.Context, id uuid.UUID, meta model.WalletMetadata) (model.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	wallet, ok := r.wallets[id]
+	if !ok {
+		return model.Wallet{}, repository.ErrWalletNotFound
+	}
+	if meta.OwnerRef != nil {
+		wallet.OwnerRef = *meta.OwnerRef
+	}
+	if meta.DisplayName != nil {
+		wallet.DisplayName = *meta.DisplayName
+	}
+	if meta.Labels != nil {
+		wallet.Labels = meta.Labels
+	}
+	wallet.Version++
+	r.wallets[id] = wallet
+	return wallet, nil
+}
+
+func (r *memoryRepository) apply(op model.WalletOperation, reversalOf *uuid.UUID) (model.Operation, error) {
+	wallet, ok := r.wallets[op.WalletID]
+	if op.ExpectedVersion != nil && (!ok || wallet.Version != *op.ExpectedVersion) {
+		return model.Operation{}, repository.ErrVersionMismatch
+	}
+	if !ok {
+		if op.OperationType != model.OperationTypeDeposit {
+			return model.Operation{}, repository.ErrWalletNotFound
+		}
+		now := time.Now()
+		wallet = model.Wallet{ID: op.WalletID, Status: model.WalletActive, Labels: map[string]string{}, CreatedAt: now, UpdatedAt: now}
+	}
+
+	if op.OperationType == model.OperationTypeWithdraw {
+		if wallet.Available().LessThan(op.Amount) {
+			return model.Operation{}, repository.ErrInsufficientFunds
+		}
+		wallet.Balance = wallet.Balance.Sub(op.Amount)
+	} else {
+		wallet.Balance = wallet.Balance.Add(op.Amount)
+	}
+	wallet.Version++
+	r.wallets[op.WalletID] = wallet
+
+	result := model.Operation{
+		ID:            uuid.New(),
+		WalletID:      op.WalletID,
+		OperationType: op.OperationType,
+		Amount:        op.Amount,
+		BalanceAfter:  wallet.Balance,
+		WalletVersion: wallet.Version,
+		ReversalOf:    reversalOf,
+		CreatedAt:     time.Now(),
+	}
+	r.operations[result.ID] = result
+	if op.IdempotencyKey != "" {
+		r.keys[op.IdempotencyKey] = result.ID
+	}
+	return result, nil
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	walletService := service.NewWalletService(newMemoryRepository(), service.DefaultRetryPolicy(), tenant.NewRegistry())
+	server := httptest.NewServer(handler.NewRouter(walletService, nil, handler.RouterOptions{}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestClient(t *testing.T, baseURL string, opts ...Option) *Client {
+	opts = append([]Option{WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond})}, opts...)
+	c, err := New(baseURL, opts...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_DepositWithdrawAndGet(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server.URL)
+	ctx := context.Background()
+	walletID := uuid.New()
+
+	deposit, err := c.Deposit(ctx, walletID, decimal.NewFromInt(100))
+	require.NoError(t, err)
+	assert.Equal(t, 1, deposit.WalletVersion)
+
+	_, err = c.Withdraw(ctx, walletID, decimal.NewFromInt(30))
+	require.NoError(t, err)
+
+	wallet, err := c.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, walletID, wallet.WalletID)
+	assert.True(t, decimal.NewFromInt(70).Equal(wallet.Balance))
+	assert.Equal(t, 2, wallet.Version)
+
+	_, err = c.GetWalletIfModified(ctx, walletID, wallet.Version)
+	assert.ErrorIs(t, err, ErrNotModified)
+}
+
+func TestClient_TypedErrors(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server.URL)
+	ctx := context.Background()
+	walletID := uuid.New()
+
+	_, err := c.GetWallet(ctx, walletID)
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+
+	_, err = c.Deposit(ctx, walletID, decimal.NewFromInt(10))
+	require.NoError(t, err)
+
+	_, err = c.Withdraw(ctx, walletID, decimal.NewFromInt(11))
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = c.Deposit(ctx, walletID, decimal.NewFromInt(1), IfMatch(5))
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+	_, err = c.Deposit(ctx, walletID, decimal.NewFromInt(-1))
+	assert.ErrorIs(t, err, ErrValidation)
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "amount", apiErr.Problem.Errors[0].Field)
+}
+
+func TestClient_RetriesWithSameIdempotencyKey(t *testing.T) {
+	server := newTestServer(t)
+
+	var calls int32
+	var keys sync.Map
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys.Store(r.Header.Get(IdempotencyKeyHeader), true)
+		// Первые два ответа - конфликты, на которых клиент должен повторить запрос
+		switch atomic.AddInt32(&calls, 1) {
+		case 1, 2:
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"status":409,"code":"CONCURRENCY_CONFLICT"}`))
+		default:
+			proxy, _ := http.NewRequest(r.Method, server.URL+r.URL.Path, r.Body)
+			proxy.Header = r.Header
+			resp, err := http.DefaultClient.Do(proxy)
+			if err != nil {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			defer resp.Body.Close()
+			w.WriteHeader(resp.StatusCode)
+			io.Copy(w, resp.Body)
+		}
+	}))
+	defer flaky.Close()
+
+	c := newTestClient(t, flaky.URL)
+	_, err := c.Deposit(context.Background(), uuid.New(), decimal.NewFromInt(5))
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	count := 0
+	keys.Range(func(key, _ any) bool {
+		assert.NotEmpty(t, key)
+		count++
+		return true
+	})
+	assert.Equal(t, 1, count)
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		w.Write([]byte(`{"status":412,"code":"PRECONDITION_FAILED"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server.URL)
+	_, err := c.Withdraw(context.Background(), uuid.New(), decimal.NewFromInt(1))
+
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestClient_RetriesServerErrorsOnlyWhenSafe(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server.URL)
+
+	// Изменяющий запрос без ключа мог выполниться до ошибки - повтор применил бы его дважды
+	_, err := c.do(context.Background(), http.MethodPost, "/api/v1/wallet", nil, []byte(`{}`))
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Операцию с Idempotency-Key сервер не применит дважды
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.Withdraw(context.Background(), uuid.New(), decimal.NewFromInt(1))
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.GetWallet(context.Background(), uuid.New())
+	assert.Error(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestClient_ContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server.URL, WithRetryPolicy(RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Second}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.GetWallet(ctx, uuid.New())
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestClient_IdempotentReplay(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server.URL)
+	ctx := context.Background()
+	walletID := uuid.New()
+
+	first, err := c.Deposit(ctx, walletID, decimal.NewFromInt(50), WithIdempotencyKey("order-42"))
+	require.NoError(t, err)
+	second, err := c.Deposit(ctx, walletID, decimal.NewFromInt(50), WithIdempotencyKey("order-42"))
+	require.NoError(t, err)
+
+	assert.Equal(t, first.ID, second.ID)
+
+	wallet, err := c.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(50).Equal(wallet.Balance))
+}
+
+func TestClient_Reverse(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server.URL)
+	ctx := context.Background()
+	walletID := uuid.New()
+
+	deposit, err := c.Deposit(ctx, walletID, decimal.NewFromInt(100))
+	require.NoError(t, err)
+
+	partial := decimal.NewFromInt(30)
+	reversal, err := c.Reverse(ctx, deposit.ID, &partial)
+	require.NoError(t, err)
+	assert.Equal(t, OperationTypeWithdraw, reversal.OperationType)
+	assert.Equal(t, &deposit.ID, reversal.ReversalOf)
+
+	tooMuch := decimal.NewFromInt(71)
+	_, err = c.Reverse(ctx, deposit.ID, &tooMuch)
+	assert.ErrorIs(t, err, ErrReversalExceeded)
+
+	_, err = c.Reverse(ctx, deposit.ID, nil)
+	require.NoError(t, err)
+
+	original, err := c.GetOperation(ctx, deposit.ID)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(100).Equal(original.ReversedAmount))
+
+	_, err = c.Reverse(ctx, deposit.ID, nil)
+	assert.ErrorIs(t, err, ErrAlreadyReversed)
+
+	wallet, err := c.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.True(t, wallet.Balance.IsZero())
+}
+
+func TestClient_DoesNotRetryAlreadyReversed(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"status":409,"code":"OPERATION_ALREADY_REVERSED"}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, server.URL)
+	_, err := c.Reverse(context.Background(), uuid.New(), nil)
+
+	assert.ErrorIs(t, err, ErrAlreadyReversed)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestClient_ListWallets(t *testing.T) {
+	server := newTestServer(t)
+	c := newTestClient(t, server.URL)
+	ctx := context.Background()
+
+	owner := "customer-7"
+	for i := 0; i < 3; i++ {
+		walletID := uuid.New()
+		_, err := c.Deposit(ctx, walletID, decimal.NewFromInt(10))
+		require.NoError(t, err)
+		if i == 2 {
+			continue
+		}
+		wallet, err := c.UpdateMetadata(ctx, walletID, MetadataRequest{OwnerRef: &owner, Labels: map[string]string{"segment": "vip"}})
+		require.NoError(t, err)
+		assert.Equal(t, owner, wallet.OwnerRef)
+		assert.Equal(t, "vip", wallet.Labels["segment"])
+		assert.Equal(t, 2, wallet.Version)
+	}
+
+	var seen []uuid.UUID
+	q := WalletQuery{Owner: owner, Limit: 1}
+	for {
+		page, err := c.ListWallets(ctx, q)
+		require.NoError(t, err)
+		for _, w := range page.Wallets {
+			seen = append(seen, w.WalletID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	assert.Len(t, seen, 2)
+
+	_, err := c.ListWallets(ctx, WalletQuery{Sort: "name"})
+	assert.ErrorIs(t, err, ErrValidation)
+}
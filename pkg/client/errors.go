@@ -0,0 +1,68 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"wallet-service/internal/apperror"
+)
+
+// Ошибки, соответствующие кодам сервиса (docs/errors.md). Проверять через errors.Is:
+//
+//	if errors.Is(err, client.ErrInsufficientFunds) { ... }
+var (
+	ErrValidation           = errors.New("validation failed")
+	ErrWalletNotFound       = errors.New("wallet not found")
+	ErrInsufficientFunds    = errors.New("insufficient funds")
+	ErrConflict             = errors.New("operation conflict")
+	ErrPreconditionFailed   = errors.New("wallet has been modified")
+	ErrNotModified          = errors.New("wallet not modified")
+	ErrOperationNotFound    = errors.New("operation not found")
+	ErrAlreadyReversed      = errors.New("operation already reversed")
+	ErrReversalExceeded     = errors.New("reversal amount exceeded")
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused")
+	ErrLimitExceeded        = errors.New("wallet limit exceeded")
+	ErrWalletFrozen         = errors.New("wallet is frozen")
+)
+
+var errorsByCode = map[apperror.Code]error{
+	apperror.CodeValidationFailed:     ErrValidation,
+	apperror.CodeWalletNotFound:       ErrWalletNotFound,
+	apperror.CodeInsufficientFunds:    ErrInsufficientFunds,
+	apperror.CodeConcurrencyConflict:  ErrConflict,
+	apperror.CodePreconditionFailed:   ErrPreconditionFailed,
+	apperror.CodeOperationNotFound:    ErrOperationNotFound,
+	apperror.CodeAlreadyReversed:      ErrAlreadyReversed,
+	apperror.CodeReversalExceeded:     ErrReversalExceeded,
+	apperror.CodeIdempotencyKeyReused: ErrIdempotencyKeyReused,
+	apperror.CodeLimitExceeded:        ErrLimitExceeded,
+	apperror.CodeWalletFrozen:         ErrWalletFrozen,
+}
+
+// APIError - ответ сервиса с ошибкой (application/problem+json)
+type APIError struct {
+	StatusCode int
+	Problem    Problem
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("wallet-service: %d", e.StatusCode)
+	if e.Problem.Code != "" {
+		msg += " " + string(e.Problem.Code)
+	}
+	if e.Problem.Title != "" {
+		msg += ": " + e.Problem.Title
+	}
+	if e.Problem.Detail != "" {
+		msg += ": " + e.Problem.Detail
+	}
+	for _, f := range e.Problem.Errors {
+		msg += fmt.Sprintf("; %s: %s", f.Field, f.Message)
+	}
+	return msg
+}
+
+func (e *APIError) Is(target error) bool {
+	sentinel, ok := errorsByCode[e.Problem.Code]
+	return ok && sentinel == target
+}